@@ -1,18 +1,39 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"log"
 	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/archive"
 	"github.com/FelipePn10/crispaybackend/internal/database"
 	"github.com/FelipePn10/crispaybackend/internal/didit"
 	"github.com/FelipePn10/crispaybackend/internal/email/service"
+	"github.com/FelipePn10/crispaybackend/internal/events"
 	"github.com/FelipePn10/crispaybackend/internal/handlers"
+	"github.com/FelipePn10/crispaybackend/internal/kyb"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/links"
+	"github.com/FelipePn10/crispaybackend/internal/media"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/monitoring"
+	"github.com/FelipePn10/crispaybackend/internal/outbound"
+	"github.com/FelipePn10/crispaybackend/internal/privacy"
+	"github.com/FelipePn10/crispaybackend/internal/ratelimit"
 	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/retention"
+	"github.com/FelipePn10/crispaybackend/internal/risk"
+	"github.com/FelipePn10/crispaybackend/internal/sanctions"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/FelipePn10/crispaybackend/internal/webhooks"
+	"github.com/FelipePn10/crispaybackend/pkg/kycgate"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,6 +43,34 @@ type application struct {
 	logger       *slog.Logger
 	db           *database.DB
 	emailService *service.EmailService
+	repo         *repository.VerificationRepository
+	tenants      *repository.TenantRepository
+	processor    *webhooks.Processor
+	queue        *webhooks.Queue
+	subRepo      *repository.SubscriptionRepository
+	dispatcher   *outbound.Dispatcher
+	broker       *events.Broker
+	audit        *repository.AuditRepository
+	levels       *kyc.Service
+	starter      *kyc.Starter
+	links        *links.Signer
+	media        *media.Service
+	providers    *kyc.Router
+	kyc          *kycgate.Gate
+	privacy      *privacy.Service
+	retention    *repository.RetentionRepository
+	purger       *retention.Purger
+	archiver     *archive.Manager
+	monitor      *monitoring.Scheduler
+	screener     *sanctions.Screener
+	screening    *repository.ScreeningRepository
+	identity     *repository.IdentityRepository
+	scorer       *risk.Engine
+	risks        *repository.RiskRepository
+	companies    *repository.CompanyRepository
+	kyb          *kyb.Service
+	limiter      *ratelimit.Limiter
+	startLimits  []ratelimit.Policy
 }
 
 func (app *application) traceMiddleware() gin.HandlerFunc {
@@ -41,6 +90,127 @@ func (app *application) traceMiddleware() gin.HandlerFunc {
 	}
 }
 
+// adminMiddleware guards the operational endpoints with the ADMIN_API_KEY
+// shared secret. The admin API is disabled when no key is configured.
+func (app *application) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if app.config.AdminAPIKey == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "admin API disabled"})
+			return
+		}
+		key := c.GetHeader("X-Admin-Key")
+		if subtle.ConstantTimeCompare([]byte(key), []byte(app.config.AdminAPIKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin key"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// tenantMiddleware resolves the tenant of client requests from the X-API-Key
+// header. Requests without a key act for the default tenant.
+func (app *application) tenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(tenant.APIKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		t, err := app.tenants.GetTenantByAPIKey(c.Request.Context(), key)
+		if err != nil {
+			log.Printf("Failed to resolve tenant API key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve tenant"})
+			return
+		}
+		if t == nil || !t.Active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+			return
+		}
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), t))
+		c.Next()
+	}
+}
+
+// userMiddleware authenticates the end user of client routes that act for
+// one. The tenant's backend names the user in X-User-ID, and the header is
+// only trusted from a backend that presented its API key; the user id is
+// stored under kycgate.UserIDKey. It runs after tenantMiddleware, which
+// rejects invalid keys.
+func (app *application) userMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(tenant.APIKeyHeader) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+			return
+		}
+		userID := c.GetHeader(tenant.UserHeader)
+		if userID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "X-User-ID required"})
+			return
+		}
+		c.Set(kycgate.UserIDKey, userID)
+		c.Next()
+	}
+}
+
+// adminTenantMiddleware lets admin calls act for the tenant whose slug is in
+// the X-Tenant header, the default tenant when it is absent.
+func (app *application) adminTenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slug := c.GetHeader(tenant.AdminHeader)
+		if slug == "" {
+			c.Next()
+			return
+		}
+
+		t, err := app.tenants.GetTenantBySlug(c.Request.Context(), slug)
+		if err != nil {
+			log.Printf("Failed to load tenant %s: %v", slug, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tenant"})
+			return
+		}
+		if t == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Unknown tenant"})
+			return
+		}
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), t))
+		c.Next()
+	}
+}
+
+// auditMiddleware appends an audit log entry for every successful admin call
+// that changes state, unless the handler already wrote a specific one.
+func (app *application) auditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Request.Method == http.MethodGet || c.Writer.Status() >= 400 || handlers.IsAudited(c) {
+			return
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+		_, err := app.audit.Record(c.Request.Context(), models.AuditEvent{
+			Action:        models.AuditAdminRequest,
+			SubjectUserID: c.Param("userId"),
+			Actor:         handlers.Actor(c),
+			EntityType:    "admin",
+			EntityID:      c.Param("id"),
+			Details: map[string]any{
+				"method": c.Request.Method,
+				"route":  c.FullPath(),
+				"params": params,
+				"status": c.Writer.Status(),
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to audit admin request %s %s: %v", c.Request.Method, c.FullPath(), err)
+		}
+	}
+}
+
 // // Middleware to validate Didit webhook
 // func (app *application) diditMiddleware() gin.HandlerFunc {
 // 	return func(c *gin.Context) {
@@ -61,9 +231,19 @@ func (app *application) mount() *gin.Engine {
 	// Health check global
 	r.GET("/health", app.healthHandler)
 
+	// Links assinados entregues aos usuários
+	linkHandler := handlers.NewLinkHandler(app.links, app.repo, app.processor, app.tenants, app.config.VerificationReturnURL)
+	r.GET("/v/:token", linkHandler.OpenVerification)
+	r.GET("/api/verification/callback", linkHandler.Callback)
+	mediaHandler := handlers.NewMediaHandler(app.media, app.repo, app.providers)
+	r.GET("/media/:token", mediaHandler.ServeMedia)
+
 	api := r.Group("/api")
 	{
 		app.diditRoutes(api)
+		app.kybRoutes(api)
+		app.adminRoutes(api)
+		app.privacyRoutes(api)
 	}
 
 	return r
@@ -75,23 +255,243 @@ func (app *application) healthHandler(c *gin.Context) {
 		"timestamp": time.Now().UTC(),
 		"service":   "core-api",
 	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+	if err := app.db.Ping(ctx); err != nil {
+		log.Printf("Health check failed to reach the database: %v", err)
+		resp["status"] = "unavailable"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
 	c.JSON(http.StatusOK, resp)
 }
 
+// databaseStatsHandler reports the connection pool, which stays off the
+// unauthenticated health check.
+func (app *application) databaseStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, app.db.Stats())
+}
+
 func (app *application) diditRoutes(rg *gin.RouterGroup) {
-	repo := repository.NewVerificationRepository(app.db.Queries())
+	webhookHandler := handlers.NewWebhookHandler(app.providers, app.repo, app.queue, app.levels, app.starter, app.tenants)
+	streamHandler := handlers.NewStreamHandler(app.repo, app.broker, app.config.StreamHeartbeat)
+
+	// Rotas Didit e demais provedores
+	rg.POST("/webhooks/:provider", webhookHandler.HandleVerificationWebhook)
+	rg.POST("/webhooks/:provider/:tenant", webhookHandler.HandleVerificationWebhook)
+
+	verification := rg.Group("/verification", app.tenantMiddleware())
+	verification.POST("/start", app.limiter.Middleware(app.startLimits...), webhookHandler.StartVerification)
+	verification.GET("/status/:sessionId", webhookHandler.GetVerificationStatus)
+	verification.GET("/status/:sessionId/stream", streamHandler.StreamVerificationStatus)
+	verification.GET("/user/:userId", webhookHandler.GetUserVerifications)
+	verification.GET("/user/:userId/status", webhookHandler.GetUserVerificationStatus)
+	verification.GET("/user/:userId/level", webhookHandler.GetUserKYCLevel)
+
+	// The KYC gate as an endpoint, for services that do not embed
+	// pkg/kycgate, e.g. behind a proxy's forward auth.
+	gate := verification.Group("/gate", app.userMiddleware())
+	for _, level := range kyc.Levels {
+		gate.GET("/"+string(level), app.kyc.RequireKYC(level), app.kycGateHandler)
+	}
+}
+
+// kycGateHandler answers the requests let through by the KYC gate with the
+// decision that granted them.
+func (app *application) kycGateHandler(c *gin.Context) {
+	decision, _ := c.Get("kyc")
+	c.JSON(http.StatusOK, decision)
+}
+
+// kybRoutes serve company owners, authenticated like the users of the KYC
+// gate; an owner only reaches their own companies. KYB is only offered to
+// the default tenant.
+func (app *application) kybRoutes(rg *gin.RouterGroup) {
+	kybHandler := handlers.NewKYBHandler(app.kyb, app.companies)
+
+	business := rg.Group("/kyb", app.tenantMiddleware(), app.userMiddleware(), app.defaultTenantMiddleware())
+	business.POST("/companies", kybHandler.RegisterCompany)
+	business.GET("/companies", kybHandler.ListCompanies)
+	business.GET("/companies/:companyId", kybHandler.GetCompany)
+	business.POST("/companies/:companyId/people/:personId/verification", kybHandler.StartPersonVerification)
+}
+
+// defaultTenantMiddleware rejects requests of tenants other than the default
+// one, on routes only offered to it.
+func (app *application) defaultTenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenant.ID(c.Request.Context()) != tenant.DefaultID {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not available for this tenant"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (app *application) adminRoutes(rg *gin.RouterGroup) {
+	adminHandler := handlers.NewAdminHandler(app.repo, app.processor, app.queue)
+
+	admin := rg.Group("/admin", app.adminMiddleware(), app.adminTenantMiddleware(), app.auditMiddleware())
+	admin.POST("/webhooks/replay", adminHandler.ReplayWebhooks)
+	admin.GET("/webhooks/queue", adminHandler.GetWebhookQueueStats)
+	admin.GET("/webhooks/dead-letter", adminHandler.ListDeadWebhookEvents)
+	admin.POST("/webhooks/:id/retry", adminHandler.RetryDeadWebhookEvent)
+	admin.POST("/sessions/:sessionId/review", adminHandler.ReviewSession)
+
+	providerHandler := handlers.NewProviderHandler(app.repo, app.processor, app.providers)
+
+	admin.GET("/providers", providerHandler.GetProviders)
+	admin.GET("/database", app.databaseStatsHandler)
+	admin.POST("/sessions/:sessionId/reconcile", providerHandler.ReconcileSession)
+	admin.POST("/reconcile", providerHandler.ReconcilePending)
+
+	mediaHandler := handlers.NewMediaHandler(app.media, app.repo, app.providers)
+
+	admin.GET("/sessions/:sessionId/media", mediaHandler.ListSessionMedia)
+	admin.POST("/sessions/:sessionId/media/capture", mediaHandler.CaptureSessionMedia)
+
+	screeningHandler := handlers.NewScreeningHandler(app.screening, app.screener)
+
+	admin.GET("/sessions/:sessionId/screening", screeningHandler.ListSessionMatches)
+	admin.GET("/screening/lists", screeningHandler.GetLists)
+	admin.POST("/screening/lists/reload", screeningHandler.ReloadLists)
+
+	identityHandler := handlers.NewIdentityHandler(app.identity)
+
+	admin.GET("/identity-links", identityHandler.ListLinks)
+	admin.GET("/users/:userId/identity-links", identityHandler.ListUserLinks)
+	admin.POST("/identity-links/:id/resolve", identityHandler.ResolveLink)
+
+	riskHandler := handlers.NewRiskHandler(app.risks, app.scorer)
+
+	admin.GET("/sessions/:sessionId/risk", riskHandler.ListSessionScores)
+	admin.GET("/risk/scores", riskHandler.ListScores)
+	admin.GET("/risk/rules", riskHandler.GetRules)
+	admin.POST("/risk/rules/reload", riskHandler.ReloadRules)
+
+	tenantHandler := handlers.NewTenantHandler(app.tenants)
+
+	admin.POST("/tenants", tenantHandler.CreateTenant)
+	admin.GET("/tenants", tenantHandler.ListTenants)
+	admin.GET("/tenants/:id", tenantHandler.GetTenant)
+	admin.PATCH("/tenants/:id", tenantHandler.UpdateTenant)
+	admin.POST("/tenants/:id/api-key", tenantHandler.RotateAPIKey)
+
+	auditHandler := handlers.NewAuditHandler(app.audit)
+
+	admin.GET("/audit", auditHandler.ListAuditLog)
+
+	subscriptionHandler := handlers.NewSubscriptionHandler(app.subRepo, app.dispatcher)
+
+	admin.POST("/subscriptions", subscriptionHandler.CreateSubscription)
+	admin.GET("/subscriptions", subscriptionHandler.ListSubscriptions)
+	admin.GET("/subscriptions/:id", subscriptionHandler.GetSubscription)
+	admin.DELETE("/subscriptions/:id", subscriptionHandler.DeleteSubscription)
+	admin.POST("/subscriptions/:id/enable", subscriptionHandler.EnableSubscription)
+	admin.GET("/subscriptions/:id/deliveries", subscriptionHandler.ListDeliveries)
+	admin.GET("/deliveries/:id", subscriptionHandler.GetDelivery)
+	admin.POST("/deliveries/:id/redeliver", subscriptionHandler.Redeliver)
+
+	retentionHandler := handlers.NewRetentionHandler(app.retention, app.purger)
+
+	admin.POST("/retention/purge", retentionHandler.Purge)
+	admin.GET("/retention/runs", retentionHandler.ListPurgeRuns)
+	admin.POST("/legal-holds", retentionHandler.CreateLegalHold)
+	admin.GET("/legal-holds", retentionHandler.ListLegalHolds)
+	admin.DELETE("/legal-holds/:userId", retentionHandler.DeleteLegalHold)
+}
+
+// privacyRoutes serves the LGPD/GDPR data subject rights. They expose and
+// destroy PII, so they sit behind the admin key.
+func (app *application) privacyRoutes(rg *gin.RouterGroup) {
+	privacyHandler := handlers.NewPrivacyHandler(app.privacy)
+
+	priv := rg.Group("/privacy", app.adminMiddleware(), app.adminTenantMiddleware())
+	priv.GET("/users/:userId/export", privacyHandler.ExportUserData)
+	priv.DELETE("/users/:userId", privacyHandler.EraseUserData)
+}
+
+// newKYCGate gates routes on the decisions stored by this service.
+func (app *application) newKYCGate() *kycgate.Gate {
 	diditClient := didit.NewClient(app.config)
 
-	webhookHandler := handlers.NewWebhookHandler(diditClient, app.config, repo, app.emailService)
+	return kycgate.New(app.levels, kycgate.Options{
+		CacheTTL: app.config.KYCCacheTTL,
+		Levels:   kyc.Levels,
+		Scope: func(ctx context.Context) string {
+			return tenant.ID(ctx).String()
+		},
+		VerificationURL: func(userID string, level kycgate.Level) string {
+			verificationURL, err := diditClient.GetWorkflowURL(string(level), false, userID, "", "", "")
+			if err != nil {
+				return ""
+			}
+			return verificationURL
+		},
+	})
+}
 
-	// Rotas Didit
-	rg.POST("/webhooks/didit", webhookHandler.HandleVerificationWebhook)
-	rg.POST("/verification/start", webhookHandler.StartVerification)
-	rg.GET("/verification/status/:sessionId", webhookHandler.GetVerificationStatus)
-	rg.GET("/verification/user/:userId", webhookHandler.GetUserVerifications)
+// invalidateKYC drops cached KYC decisions whenever a session changes status,
+// on any replica. The subscription misses no change, so a revoked approval
+// is never served from the cache.
+func (app *application) invalidateKYC(ctx context.Context) {
+	changes, unsubscribe := app.broker.SubscribeAll()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			app.kyc.Invalidate(change.TenantID.String(), change.UserID)
+		}
+	}
 }
 
-func (app *application) run(h *gin.Engine) error {
+// refreshCompanies recomputes the KYB status of the companies a user belongs
+// to whenever one of their sessions changes status. Every replica receives
+// the change; the status update only applies once. KYB is only offered to the
+// default tenant, so changes of other tenants are ignored. The subscription
+// misses no change, but notifications sent while the listener reconnects are
+// lost, so every company is also recomputed each KYB_REFRESH_INTERVAL.
+func (app *application) refreshCompanies(ctx context.Context) {
+	changes, unsubscribe := app.broker.SubscribeAll()
+	defer unsubscribe()
+
+	var sweep <-chan time.Time
+	if app.config.KYBRefreshInterval > 0 {
+		ticker := time.NewTicker(app.config.KYBRefreshInterval)
+		defer ticker.Stop()
+		sweep = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sweep:
+			if err := app.kyb.RefreshAll(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to refresh companies: %v", err)
+			}
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			if change.TenantID != tenant.DefaultID {
+				continue
+			}
+			if err := app.kyb.Refresh(ctx, change.UserID); err != nil {
+				log.Printf("Failed to refresh companies of user %s: %v", change.UserID, err)
+			}
+		}
+	}
+}
+
+func (app *application) run(ctx context.Context, h *gin.Engine) error {
 	addr := app.config.ServerPort
 	if addr == "" {
 		addr = "6000"
@@ -108,6 +508,68 @@ func (app *application) run(h *gin.Engine) error {
 		IdleTimeout:  time.Minute,
 	}
 
+	var workers sync.WaitGroup
+	workers.Add(11)
+	go func() {
+		defer workers.Done()
+		app.queue.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.broker.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.dispatcher.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.limiter.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.purger.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.archiver.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.invalidateKYC(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.refreshCompanies(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.monitor.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.screener.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		app.processor.RunReconciler(ctx, app.config.ReconcileInterval, app.config.ReconcileAfter)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			app.logger.Error("server shutdown failed", "error", err)
+		}
+	}()
+
 	log.Printf("Starting server on %s", addr)
-	return srv.ListenAndServe()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	workers.Wait()
+	app.emailService.Wait()
+	return nil
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/FelipePn10/crispaybackend/internal/webhooks"
+)
+
+const usage = `usage: crispay [command]
+
+Without a command the HTTP server is started.
+
+Commands:
+  webhooks replay --session ID | --since RFC3339 | --unprocessed [--tenant SLUG] [--dry-run]
+  webhooks partitions
+  webhooks restore --archive KEY [--table NAME]
+  retention purge [--dry-run]
+  crypto reencrypt [--batch N]
+  audit verify [--batch N]
+  monitoring run
+  sessions reconcile [--older-than DURATION]
+`
+
+// runCommand dispatches the operational subcommands of the crispay binary.
+func (app *application) runCommand(args []string) error {
+	switch args[0] {
+	case "webhooks":
+		return app.webhooksCommand(args[1:])
+	case "retention":
+		return app.retentionCommand(args[1:])
+	case "crypto":
+		return app.cryptoCommand(args[1:])
+	case "audit":
+		return app.auditCommand(args[1:])
+	case "monitoring":
+		return app.monitoringCommand(args[1:])
+	case "sessions":
+		return app.sessionsCommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func (app *application) webhooksCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown webhooks subcommand")
+	}
+	switch args[0] {
+	case "replay":
+		return app.webhooksReplay(args[1:])
+	case "partitions":
+		return app.webhooksPartitions(args[1:])
+	case "restore":
+		return app.webhooksRestore(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown webhooks subcommand")
+	}
+}
+
+func (app *application) webhooksReplay(args []string) error {
+	fs := flag.NewFlagSet("webhooks replay", flag.ContinueOnError)
+	sessionID := fs.String("session", "", "replay the events of a Didit session ID")
+	since := fs.String("since", "", "replay events received at or after this RFC3339 time")
+	unprocessed := fs.Bool("unprocessed", false, "replay events that were never processed")
+	tenantSlug := fs.String("tenant", "", "replay the events of this tenant instead of the default one")
+	dryRun := fs.Bool("dry-run", false, "show transitions and emails without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if *tenantSlug != "" {
+		t, err := app.tenants.GetTenantBySlug(ctx, *tenantSlug)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			return fmt.Errorf("unknown tenant %q", *tenantSlug)
+		}
+		ctx = tenant.NewContext(ctx, t)
+	}
+
+	req := webhooks.ReplayRequest{
+		SessionID:   *sessionID,
+		Unprocessed: *unprocessed,
+		DryRun:      *dryRun,
+	}
+	if *since != "" {
+		t, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		req.Since = t
+	}
+
+	report, err := app.processor.Replay(ctx, req)
+	if err != nil {
+		return err
+	}
+	app.emailService.Wait()
+
+	return printJSON(report)
+}
+
+// webhooksPartitions runs the webhook_events partition maintenance now:
+// creating the coming partitions and archiving the old ones.
+func (app *application) webhooksPartitions(args []string) error {
+	fs := flag.NewFlagSet("webhooks partitions", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := app.archiver.Maintain(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return printJSON(report)
+}
+
+// webhooksRestore loads an archived partition into a standalone table for an
+// investigation.
+func (app *application) webhooksRestore(args []string) error {
+	fs := flag.NewFlagSet("webhooks restore", flag.ContinueOnError)
+	key := fs.String("archive", "", "archive key, e.g. webhook_events/webhook_events_y2025m01.ndjson.gz, or the partition name")
+	table := fs.String("table", "", "table to load into, by default the partition name with a _restored suffix")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" {
+		return fmt.Errorf("--archive is required")
+	}
+
+	report, err := app.archiver.Restore(context.Background(), *key, *table)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(report)
+}
+
+func (app *application) retentionCommand(args []string) error {
+	if len(args) == 0 || args[0] != "purge" {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown retention subcommand")
+	}
+
+	fs := flag.NewFlagSet("retention purge", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show how many rows each policy would affect")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	report, err := app.purger.Purge(context.Background(), *dryRun)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(report)
+}
+
+// cryptoCommand re-encrypts stored PII under the current key. Run it after
+// adding a new key as the first FIELD_ENCRYPTION_KEYS entry, then drop the
+// old key once it finishes.
+func (app *application) cryptoCommand(args []string) error {
+	if len(args) == 0 || args[0] != "reencrypt" {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown crypto subcommand")
+	}
+
+	fs := flag.NewFlagSet("crypto reencrypt", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "rows read per query")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *batch < 1 {
+		return fmt.Errorf("--batch must be positive")
+	}
+
+	report, err := app.repo.Reencrypt(context.Background(), *batch)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(report)
+}
+
+// auditCommand checks the audit log hash chain. It fails when an entry was
+// altered, removed or inserted out of band.
+func (app *application) auditCommand(args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown audit subcommand")
+	}
+
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	batch := fs.Int("batch", 1000, "entries read per query")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *batch < 1 {
+		return fmt.Errorf("--batch must be positive")
+	}
+
+	report, err := app.audit.VerifyChain(context.Background(), *batch)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(report); err != nil {
+		return err
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("audit log chain broken at seq %d: %s", report.BrokenSeq, report.Reason)
+	}
+	return nil
+}
+
+// monitoringCommand runs the KYC monitoring once, e.g. from an external
+// scheduler with KYC_MONITOR_INTERVAL=0.
+func (app *application) monitoringCommand(args []string) error {
+	if len(args) == 0 || args[0] != "run" {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown monitoring subcommand")
+	}
+
+	report, err := app.monitor.RunOnce(context.Background())
+	if err != nil {
+		return err
+	}
+	app.emailService.Wait()
+
+	return printJSON(report)
+}
+
+// sessionsCommand fetches the decisions of pending sessions from their
+// providers, for webhooks that never arrived.
+func (app *application) sessionsCommand(args []string) error {
+	if len(args) == 0 || args[0] != "reconcile" {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown sessions subcommand")
+	}
+
+	fs := flag.NewFlagSet("sessions reconcile", flag.ContinueOnError)
+	olderThan := fs.Duration("older-than", app.config.ReconcileAfter, "only sessions opened at least this long ago")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	report, err := app.processor.ReconcilePending(context.Background(), *olderThan)
+	if err != nil {
+		return err
+	}
+	app.emailService.Wait()
+
+	return printJSON(report)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -1,13 +1,36 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/archive"
 	"github.com/FelipePn10/crispaybackend/internal/database"
+	"github.com/FelipePn10/crispaybackend/internal/didit"
 	"github.com/FelipePn10/crispaybackend/internal/email"
 	"github.com/FelipePn10/crispaybackend/internal/email/service"
+	"github.com/FelipePn10/crispaybackend/internal/events"
+	"github.com/FelipePn10/crispaybackend/internal/fieldcrypt"
+	"github.com/FelipePn10/crispaybackend/internal/kyb"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/links"
+	"github.com/FelipePn10/crispaybackend/internal/media"
+	"github.com/FelipePn10/crispaybackend/internal/monitoring"
+	"github.com/FelipePn10/crispaybackend/internal/outbound"
+	"github.com/FelipePn10/crispaybackend/internal/privacy"
+	"github.com/FelipePn10/crispaybackend/internal/ratelimit"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/retention"
+	"github.com/FelipePn10/crispaybackend/internal/risk"
+	"github.com/FelipePn10/crispaybackend/internal/sanctions"
+	"github.com/FelipePn10/crispaybackend/internal/webhooks"
 )
 
 func main() {
@@ -25,15 +48,214 @@ func main() {
 	emailConfig := email.LoadConfigFromEnv()
 	emailService := service.NewEmailService(service.EmailConfig(emailConfig))
 
+	crypt, err := fieldcrypt.FromConfig(cfg)
+	if err != nil {
+		slog.Error("invalid field encryption configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if cfg.WebhookAllowUnsigned {
+		slog.Warn("WEBHOOK_ALLOW_UNSIGNED is set, webhooks of providers without a secret are accepted unchecked")
+	} else if cfg.DiditWebhookSecret == "" {
+		slog.Warn("DIDIT_WEBHOOK_SECRET_KEY not set, Didit webhooks of tenants without their own secret are rejected")
+	}
+
+	repo := repository.NewVerificationRepository(db.Pool, db.Queries(), crypt)
+	tenantRepo := repository.NewTenantRepository(db.Queries(), crypt)
+	subRepo := repository.NewSubscriptionRepository(db.Queries())
+	dispatcher := outbound.NewDispatcher(cfg, subRepo)
+	auditRepo := repository.NewAuditRepository(db.Queries())
+	screener := sanctions.NewScreener(cfg)
+	if screener.Enabled() {
+		if err := screener.Load(); err != nil {
+			slog.Error("failed to load sanctions lists", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("sanctions lists loaded", "entries", screener.Counts())
+	}
+	screeningRepo := repository.NewScreeningRepository(db.Queries())
+	identityRepo := repository.NewIdentityRepository(db.Queries())
+	scorer := risk.NewEngine(cfg)
+	if scorer.Enabled() {
+		if err := scorer.Load(); err != nil {
+			slog.Error("failed to load risk rules", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("risk rules loaded", "version", scorer.Rules().Version, "enforced", scorer.Enforced())
+	}
+	riskRepo := repository.NewRiskRepository(db.Queries())
+	diditClient := didit.NewClient(cfg)
+	providers, err := newProviderRouter(cfg, diditClient)
+	if err != nil {
+		slog.Error("invalid KYC routing", "error", err)
+		os.Exit(1)
+	}
+	signer, err := newLinkSigner(cfg)
+	if err != nil {
+		slog.Error("invalid verification link configuration", "error", err)
+		os.Exit(1)
+	}
+	mediaStore, err := media.StoreFromConfig(cfg)
+	if err != nil {
+		slog.Error("invalid media store configuration", "error", err)
+		os.Exit(1)
+	}
+	if mediaStore != nil && !crypt.Enabled() {
+		slog.Error("MEDIA_STORE requires FIELD_ENCRYPTION_PROVIDER")
+		os.Exit(1)
+	}
+	archiveStore, err := archive.StoreFromConfig(cfg, mediaStore)
+	if err != nil {
+		slog.Error("invalid webhook archive configuration", "error", err)
+		os.Exit(1)
+	}
+	archiver, err := archive.NewManager(cfg, db.Pool, archiveStore)
+	if err != nil {
+		slog.Error("invalid webhook archive configuration", "error", err)
+		os.Exit(1)
+	}
+	mediaService := media.NewService(cfg, mediaStore, repository.NewMediaRepository(db.Queries()), auditRepo, crypt, signer)
+	processor := webhooks.NewProcessor(cfg, repo, emailService, dispatcher, auditRepo, screener, screeningRepo, identityRepo, scorer, riskRepo, providers, tenantRepo, mediaService)
+	queue := webhooks.NewQueue(cfg, repo, processor)
+	retentionRepo := repository.NewRetentionRepository(db.Queries())
+
+	limiter, startPolicies, err := newStartLimiter(cfg, db)
+	if err != nil {
+		slog.Error("invalid rate limit configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if _, err := kyc.ParseLevel(cfg.KYBPersonLevel); err != nil {
+		slog.Error("invalid KYB_PERSON_LEVEL", "error", err)
+		os.Exit(1)
+	}
+	levels := kyc.NewService(repo)
+	starter := kyc.NewStarter(cfg, repo, levels, providers, signer)
+	companyRepo := repository.NewCompanyRepository(db.Queries())
+
 	api := application{
 		config:       cfg,
 		logger:       logger,
 		db:           db,
 		emailService: emailService,
+		repo:         repo,
+		tenants:      tenantRepo,
+		processor:    processor,
+		queue:        queue,
+		subRepo:      subRepo,
+		dispatcher:   dispatcher,
+		broker:       events.NewBroker(cfg.DatabaseURL),
+		audit:        auditRepo,
+		levels:       levels,
+		starter:      starter,
+		links:        signer,
+		media:        mediaService,
+		providers:    providers,
+		privacy:      privacy.NewService(repo, subRepo, auditRepo, retentionRepo, mediaService),
+		retention:    retentionRepo,
+		purger:       retention.NewPurger(cfg, retentionRepo, mediaService),
+		archiver:     archiver,
+		monitor:      monitoring.NewScheduler(cfg, repo, processor, emailService, signer, providers, tenantRepo),
+		screener:     screener,
+		screening:    screeningRepo,
+		identity:     identityRepo,
+		scorer:       scorer,
+		risks:        riskRepo,
+		companies:    companyRepo,
+		kyb:          kyb.NewService(cfg, companyRepo, repo, auditRepo, levels, starter),
+		limiter:      limiter,
+		startLimits:  startPolicies,
+	}
+
+	api.kyc = api.newKYCGate()
+
+	if len(os.Args) > 1 {
+		if err := api.runCommand(os.Args[1:]); err != nil {
+			slog.Error("command failed", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	if err := api.run(api.mount()); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := api.run(ctx, api.mount()); err != nil {
 		slog.Error("application error", "error", err)
 		os.Exit(1)
 	}
 }
+
+// newProviderRouter registers Didit, and the mock provider when enabled, and
+// loads the routing rules.
+func newProviderRouter(cfg *config.Config, diditClient *didit.Client) (*kyc.Router, error) {
+	providers := []kyc.Provider{diditClient}
+	if cfg.MockProviderEnabled {
+		providers = append(providers, kyc.NewMockProvider("mock", cfg.MockProviderURL, cfg.MockProviderWebhookSecret, cfg.MockProviderDecision, cfg.WebhookAllowUnsigned))
+	}
+
+	router := kyc.NewRouter(providers...)
+	if err := router.Load(cfg.KYCRoutingFile); err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// newLinkSigner builds the signer of verification links. Without a secret
+// links are signed with a random key and stop working on restart, and links
+// issued by one replica fail on the others.
+func newLinkSigner(cfg *config.Config) (*links.Signer, error) {
+	for name, value := range map[string]string{"PUBLIC_BASE_URL": cfg.PublicBaseURL, "VERIFICATION_RETURN_URL": cfg.VerificationReturnURL} {
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("%s must be an absolute http(s) URL", name)
+		}
+	}
+
+	key := []byte(cfg.VerificationLinkSecret)
+	if len(key) == 0 {
+		slog.Warn("VERIFICATION_LINK_SECRET not set, verification links will not survive a restart")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return links.NewSigner(key, cfg.PublicBaseURL, cfg.VerificationLinkTTL, cfg.VerificationCallbackTTL), nil
+}
+
+// newStartLimiter builds the limiter and the per-user, per-IP and global
+// policies guarding verification start.
+func newStartLimiter(cfg *config.Config, db *database.DB) (*ratelimit.Limiter, []ratelimit.Policy, error) {
+	var store ratelimit.Store
+	switch cfg.RateLimitStore {
+	case "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		store = ratelimit.NewPostgresStore(db.Pool)
+	default:
+		return nil, nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", cfg.RateLimitStore)
+	}
+
+	perUser, err := ratelimit.ParseRate(cfg.RateLimitStartPerUser)
+	if err != nil {
+		return nil, nil, err
+	}
+	perIP, err := ratelimit.ParseRate(cfg.RateLimitStartPerIP)
+	if err != nil {
+		return nil, nil, err
+	}
+	global, err := ratelimit.ParseRate(cfg.RateLimitStartGlobal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policies := []ratelimit.Policy{
+		{Name: "start:global", Rate: global, Key: ratelimit.GlobalKey},
+		{Name: "start:ip", Rate: perIP, Key: ratelimit.ClientIPKey},
+		{Name: "start:user", Rate: perUser, Key: ratelimit.JSONFieldKey("user_id")},
+	}
+	return ratelimit.NewLimiter(store), policies, nil
+}
@@ -0,0 +1,322 @@
+// Package archive manages the monthly partitions of webhook_events and
+// exports old ones to gzip-compressed NDJSON archives, one JSON object per
+// event with the columns of the table, which can be loaded back later.
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// keyPrefix is where archives are kept in the store.
+const keyPrefix = "webhook_events/"
+
+// restoreBatchSize is how many events a single insert of Restore loads.
+const restoreBatchSize = 500
+
+var tableName = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// Archive is an exported partition.
+type Archive struct {
+	Partition string `json:"partition"`
+	Key       string `json:"key"`
+	Rows      int    `json:"rows"`
+	Bytes     int    `json:"bytes"`
+	SHA256    string `json:"sha256"`
+}
+
+// RestoreReport is the outcome of loading an archive back.
+type RestoreReport struct {
+	Key   string `json:"key"`
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+}
+
+// KeyOf returns the store key of the first archive of a partition. Archives
+// rewritten later get keys of their own, kept in webhook_event_archives.
+func KeyOf(partition string) string {
+	return keyPrefix + partition + ".ndjson.gz"
+}
+
+// digest hashes and counts the bytes written to it.
+type digest struct {
+	hash hash.Hash
+	size int
+}
+
+func newDigest() *digest {
+	return &digest{hash: sha256.New()}
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	d.hash.Write(p)
+	d.size += len(p)
+	return len(p), nil
+}
+
+func (d *digest) sum() string {
+	return hex.EncodeToString(d.hash.Sum(nil))
+}
+
+// put streams the NDJSON lines written by write, gzip-compressed, to the
+// store under key, and returns the digest of what was stored. Only a part of
+// the archive is held in memory at a time; the store checks what it
+// receives, so the archive is not read back. Nothing is stored when write
+// fails.
+func (m *Manager) put(ctx context.Context, key string, name string, write func(w io.Writer) error) (*digest, error) {
+	pr, pw := io.Pipe()
+	d := newDigest()
+
+	written := make(chan error, 1)
+	go func() {
+		zw := gzip.NewWriter(io.MultiWriter(pw, d))
+		zw.Name = name
+		err := write(zw)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	putErr := m.store.PutStream(ctx, key, pr, "application/gzip")
+	// Unblocks the writer when the store stopped reading early.
+	pr.Close()
+	writeErr := <-written
+
+	if writeErr != nil && !errors.Is(writeErr, io.ErrClosedPipe) {
+		return nil, writeErr
+	}
+	if putErr != nil {
+		return nil, putErr
+	}
+	return d, writeErr
+}
+
+// open streams the events of a stored archive to read, which is called with
+// the decompressed NDJSON. The archive must match its recorded checksum,
+// when there is one, or open fails once the whole archive was read.
+func (m *Manager) open(ctx context.Context, key string, sum string, read func(r io.Reader) error) error {
+	src, err := m.store.GetStream(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	d := newDigest()
+	tee := io.TeeReader(src, d)
+	zr, err := gzip.NewReader(tee)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer zr.Close()
+
+	if err := read(zr); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return fmt.Errorf("failed to read archive: %v", err)
+	}
+	if sum != "" && d.sum() != sum {
+		return fmt.Errorf("archive %s does not match its checksum", key)
+	}
+	return nil
+}
+
+// archivePartition exports a detached partition to the store and drops it,
+// recording the archive in webhook_event_archives.
+func (m *Manager) archivePartition(ctx context.Context, conn *pgx.Conn, name string) (*Archive, error) {
+	table := pgx.Identifier{name}.Sanitize()
+	archive := &Archive{
+		Partition: name,
+		Key:       KeyOf(name),
+	}
+
+	d, err := m.put(ctx, archive.Key, name+".ndjson", func(w io.Writer) error {
+		rows, err := conn.Query(ctx, `SELECT to_jsonb(e) FROM `+table+` e ORDER BY created_at, id`)
+		if err != nil {
+			return fmt.Errorf("failed to read partition: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var line []byte
+			if err := rows.Scan(&line); err != nil {
+				return fmt.Errorf("failed to read partition: %v", err)
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return fmt.Errorf("failed to compress partition: %v", err)
+			}
+			archive.Rows++
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read partition: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	archive.Bytes = d.size
+	archive.SHA256 = d.sum()
+
+	var count int
+	if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM `+table).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count partition: %v", err)
+	}
+	if count != archive.Rows {
+		return nil, fmt.Errorf("partition changed during export: %d rows exported, %d now", archive.Rows, count)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	err = sqlc.New(tx).MarkWebhookEventArchived(ctx, sqlc.MarkWebhookEventArchivedParams{
+		PartitionName: name,
+		ArchiveKey:    pgtype.Text{String: archive.Key, Valid: true},
+		EventCount:    pgtype.Int4{Int32: int32(archive.Rows), Valid: true},
+		SizeBytes:     pgtype.Int8{Int64: int64(archive.Bytes), Valid: true},
+		Sha256:        pgtype.Text{String: archive.SHA256, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record archive: %v", err)
+	}
+	if _, err := tx.Exec(ctx, `DROP TABLE `+table); err != nil {
+		return nil, fmt.Errorf("failed to drop archived partition: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit archive: %v", err)
+	}
+	return archive, nil
+}
+
+// Restore loads an archive, named by its key or its partition, into a new
+// table shaped like webhook_events, by default named after the partition
+// with a _restored suffix. The table is not attached: the events are there
+// to be queried during an investigation, not processed or served again.
+// Drop it when done.
+func (m *Manager) Restore(ctx context.Context, key string, table string) (*RestoreReport, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("no webhook archive store configured")
+	}
+
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	// Archives recorded by the manager are located and checked through
+	// their record; others are read as named.
+	queries := sqlc.New(conn)
+	var record sqlc.WebhookEventArchive
+	if strings.HasPrefix(key, keyPrefix) {
+		record, err = queries.GetWebhookEventArchiveByKey(ctx, pgtype.Text{String: key, Valid: true})
+	} else {
+		record, err = queries.GetWebhookEventArchive(ctx, strings.TrimSuffix(key, ".ndjson.gz"))
+	}
+	switch {
+	case err == nil && record.ArchiveKey.Valid:
+		key = record.ArchiveKey.String
+	case err == nil || errors.Is(err, pgx.ErrNoRows):
+		record = sqlc.WebhookEventArchive{PartitionName: strings.TrimSuffix(path.Base(key), ".ndjson.gz")}
+		if !strings.HasPrefix(key, keyPrefix) {
+			key = KeyOf(record.PartitionName)
+		}
+	default:
+		return nil, fmt.Errorf("failed to look up archive: %v", err)
+	}
+
+	if table == "" {
+		table = record.PartitionName + "_restored"
+	}
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+
+	exists, err := tableExists(ctx, conn.Conn(), table)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("table %s already exists", table)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	ident := pgx.Identifier{table}.Sanitize()
+	if _, err := tx.Exec(ctx, `CREATE TABLE `+ident+` (LIKE `+parent+` INCLUDING DEFAULTS)`); err != nil {
+		return nil, fmt.Errorf("failed to create table: %v", err)
+	}
+
+	report := &RestoreReport{Key: key, Table: table}
+	insert := func(batch []json.RawMessage) error {
+		payload, err := json.Marshal(batch)
+		if err != nil {
+			return fmt.Errorf("failed to encode events: %v", err)
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO `+ident+` SELECT * FROM jsonb_populate_recordset(NULL::`+ident+`, $1::jsonb)`, string(payload))
+		if err != nil {
+			return fmt.Errorf("failed to load events: %v", err)
+		}
+		report.Rows += len(batch)
+		return nil
+	}
+
+	// Each line is one JSON object, so the lines decode as a stream. A
+	// checksum mismatch found at the end rolls the whole load back.
+	err = m.open(ctx, key, record.Sha256.String, func(r io.Reader) error {
+		dec := json.NewDecoder(r)
+		batch := make([]json.RawMessage, 0, restoreBatchSize)
+		for {
+			var event json.RawMessage
+			err := dec.Decode(&event)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read archive line %d: %v", report.Rows+len(batch)+1, err)
+			}
+			batch = append(batch, event)
+			if len(batch) == restoreBatchSize {
+				if err := insert(batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		if len(batch) > 0 {
+			return insert(batch)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %v", err)
+	}
+	return report, nil
+}
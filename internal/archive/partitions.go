@@ -0,0 +1,477 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/media"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// parent is the partitioned table, and defaultPartition the partition
+	// holding rows outside every monthly one.
+	parent           = "webhook_events"
+	defaultPartition = "webhook_events_default"
+)
+
+// partitionName matches the monthly partitions, attached or detached, e.g.
+// webhook_events_y2025m03.
+var partitionName = regexp.MustCompile(`^webhook_events_y(\d{4})m(\d{2})$`)
+
+// StoreFromConfig builds the store selected by WEBHOOK_ARCHIVE_STORE, or nil
+// when partitions are never archived. The media store is reused as is.
+func StoreFromConfig(cfg *config.Config, mediaStore media.BlobStore) (media.BlobStore, error) {
+	switch cfg.WebhookArchiveStore {
+	case "", "none":
+		return nil, nil
+	case "media":
+		if mediaStore == nil {
+			return nil, fmt.Errorf("WEBHOOK_ARCHIVE_STORE=media requires MEDIA_STORE")
+		}
+		return mediaStore, nil
+	case "filesystem":
+		if cfg.WebhookArchiveDir == "" {
+			return nil, fmt.Errorf("WEBHOOK_ARCHIVE_DIR is required for the filesystem archive store")
+		}
+		return media.NewFilesystemStore(cfg.WebhookArchiveDir)
+	default:
+		return nil, fmt.Errorf("unknown WEBHOOK_ARCHIVE_STORE %q", cfg.WebhookArchiveStore)
+	}
+}
+
+// Manager keeps the monthly partitions of webhook_events. It creates the
+// partitions of the coming months and, with archiving enabled, detaches the
+// old ones, exports them to the archive store and drops them. Archives are
+// rewritten for the erasures, deleted decisions and payload retention that
+// reach them after the export.
+type Manager struct {
+	pool  *pgxpool.Pool
+	store media.BlobStore
+	// ahead is how many months after the current one get a partition.
+	ahead int
+	// archiveAfter is how many full months a partition is kept after the
+	// current one; zero keeps partitions forever.
+	archiveAfter int
+	// payloadRetention is RETENTION_WEBHOOK_PAYLOAD_DAYS, applied to the
+	// archived events too.
+	payloadRetention time.Duration
+	interval         time.Duration
+}
+
+func NewManager(cfg *config.Config, pool *pgxpool.Pool, store media.BlobStore) (*Manager, error) {
+	if cfg.WebhookArchiveAfterMonths > 0 && store == nil {
+		return nil, fmt.Errorf("WEBHOOK_ARCHIVE_AFTER_MONTHS requires WEBHOOK_ARCHIVE_STORE")
+	}
+	return &Manager{
+		pool:             pool,
+		store:            store,
+		ahead:            max(cfg.WebhookPartitionsAhead, 0),
+		archiveAfter:     max(cfg.WebhookArchiveAfterMonths, 0),
+		payloadRetention: time.Duration(max(cfg.RetentionWebhookPayloadDays, 0)) * 24 * time.Hour,
+		interval:         cfg.WebhookPartitionInterval,
+	}, nil
+}
+
+// Report is the outcome of a maintenance run.
+type Report struct {
+	StartedAt time.Time  `json:"started_at"`
+	Created   []string   `json:"created"`
+	Detached  []string   `json:"detached"`
+	Archived  []*Archive `json:"archived"`
+	// Rewritten lists the partitions whose archive changed.
+	Rewritten []string `json:"rewritten"`
+	// Skipped lists the partitions left alone, with the reason.
+	Skipped map[string]string `json:"skipped,omitempty"`
+	Errors  []string          `json:"errors,omitempty"`
+}
+
+func (r *Report) skip(partition string, reason string) {
+	if r.Skipped == nil {
+		r.Skipped = map[string]string{}
+	}
+	r.Skipped[partition] = reason
+}
+
+func (r *Report) fail(partition string, err error) {
+	r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", partition, err))
+}
+
+// Maintain creates the partitions of the current month, of the next ones and
+// of any month with rows in the default partition, moving those rows in.
+// Then, with archiving enabled, it detaches the partitions past the archive
+// age, archives the partitions it detached and rewrites the archives owing
+// purges. Replicas running at once take
+// turns through an advisory lock; the one that finds it taken does nothing.
+func (m *Manager) Maintain(ctx context.Context) (*Report, error) {
+	report := &Report{
+		StartedAt: time.Now().UTC(),
+		Created:   []string{},
+		Detached:  []string{},
+		Archived:  []*Archive{},
+		Rewritten: []string{},
+	}
+
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext('webhook_events_partitions'))`).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("failed to take partition lock: %v", err)
+	}
+	if !locked {
+		report.skip(parent, "maintenance running elsewhere")
+		return report, nil
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock(hashtext('webhook_events_partitions'))`)
+
+	months, err := m.monthsToCreate(ctx, conn.Conn(), report.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, month := range months {
+		created, err := createPartition(ctx, conn.Conn(), month)
+		if err != nil {
+			report.fail(nameOf(month), err)
+			continue
+		}
+		if created {
+			report.Created = append(report.Created, nameOf(month))
+		}
+	}
+
+	if m.store == nil {
+		return report, nil
+	}
+
+	// Archives of earlier runs are still exported and purged once archiving
+	// is turned off; only detaching stops.
+	if m.archiveAfter > 0 {
+		tables, err := partitions(ctx, conn.Conn())
+		if err != nil {
+			return nil, err
+		}
+		cutoff := monthStart(report.StartedAt).AddDate(0, -m.archiveAfter, 0)
+		for _, p := range tables {
+			if !p.attached || p.month.AddDate(0, 1, 0).After(cutoff) {
+				continue
+			}
+			detached, reason, err := detachPartition(ctx, conn.Conn(), p.name)
+			if err != nil {
+				report.fail(p.name, err)
+				continue
+			}
+			if !detached {
+				report.skip(p.name, reason)
+				continue
+			}
+			report.Detached = append(report.Detached, p.name)
+		}
+	}
+
+	// Only partitions detached here are archived, including those of an
+	// earlier run whose export failed. Tables detached by hand or restored
+	// under a partition's name are left alone.
+	detached, err := sqlc.New(conn).ListDetachedWebhookEventArchives(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list detached partitions: %v", err)
+	}
+	pending := false
+	for _, d := range detached {
+		archive, err := m.archivePartition(ctx, conn.Conn(), d.PartitionName)
+		if err != nil {
+			report.fail(d.PartitionName, err)
+			pending = true
+			continue
+		}
+		report.Archived = append(report.Archived, archive)
+	}
+
+	if err := m.rewriteArchives(ctx, conn.Conn(), report, pending); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// rewriteArchives applies the queued purges and the payload retention to
+// every archive. The queue is cleared only when all of them went through and
+// no partition is left waiting for its export, which would miss the purges
+// otherwise.
+func (m *Manager) rewriteArchives(ctx context.Context, conn *pgx.Conn, report *Report, pending bool) error {
+	queries := sqlc.New(conn)
+	p, err := m.loadPurges(ctx, queries, report.StartedAt)
+	if err != nil {
+		return err
+	}
+	archives, err := queries.ListArchivedWebhookEventArchives(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list archives: %v", err)
+	}
+
+	failed := pending
+	for _, a := range archives {
+		rewritten, err := m.rewriteArchive(ctx, conn, a, p)
+		if rewritten {
+			report.Rewritten = append(report.Rewritten, a.PartitionName)
+		}
+		if err != nil {
+			report.fail(a.PartitionName, err)
+			failed = failed || !rewritten
+		}
+	}
+
+	if p.queued() && !failed {
+		if err := queries.DeleteWebhookArchivePurges(ctx, p.lastID); err != nil {
+			return fmt.Errorf("failed to clear archive purges: %v", err)
+		}
+	}
+	return nil
+}
+
+// Run maintains the partitions at startup and then on every interval until
+// ctx is cancelled. A zero interval only runs it at startup.
+func (m *Manager) Run(ctx context.Context) {
+	m.runOnce(ctx)
+	if m.interval <= 0 {
+		return
+	}
+	log.Printf("Starting webhook_events partition maintenance every %s", m.interval)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.runOnce(ctx)
+		}
+	}
+}
+
+func (m *Manager) runOnce(ctx context.Context) {
+	report, err := m.Maintain(ctx)
+	if err != nil {
+		log.Printf("Webhook partition maintenance failed: %v", err)
+		return
+	}
+	for _, name := range report.Created {
+		log.Printf("Created webhook_events partition %s", name)
+	}
+	for _, name := range report.Detached {
+		log.Printf("Detached webhook_events partition %s", name)
+	}
+	for _, archive := range report.Archived {
+		log.Printf("Archived webhook_events partition %s to %s (%d rows)", archive.Partition, archive.Key, archive.Rows)
+	}
+	for _, name := range report.Rewritten {
+		log.Printf("Rewrote webhook_events archive of %s", name)
+	}
+	for _, msg := range report.Errors {
+		log.Printf("Webhook partition maintenance error: %s", msg)
+	}
+}
+
+// monthsToCreate returns the current month, the next m.ahead ones and the
+// months of the rows that fell into the default partition.
+func (m *Manager) monthsToCreate(ctx context.Context, conn *pgx.Conn, now time.Time) ([]time.Time, error) {
+	seen := map[time.Time]bool{}
+	var months []time.Time
+	add := func(month time.Time) {
+		if !seen[month] {
+			seen[month] = true
+			months = append(months, month)
+		}
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT DISTINCT date_trunc('month', created_at AT TIME ZONE 'UTC')
+		FROM `+pgx.Identifier{defaultPartition}.Sanitize()+`
+		ORDER BY 1`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read default partition: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var month time.Time
+		if err := rows.Scan(&month); err != nil {
+			return nil, fmt.Errorf("failed to read default partition: %v", err)
+		}
+		add(monthStart(month))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read default partition: %v", err)
+	}
+
+	for i := 0; i <= m.ahead; i++ {
+		add(monthStart(now).AddDate(0, i, 0))
+	}
+	return months, nil
+}
+
+// createPartition creates and attaches the partition of month, moving the
+// rows of that month out of the default partition in the same transaction.
+// It reports false when the partition exists already.
+func createPartition(ctx context.Context, conn *pgx.Conn, month time.Time) (bool, error) {
+	name := nameOf(month)
+	exists, err := tableExists(ctx, conn, name)
+	if err != nil || exists {
+		return false, err
+	}
+
+	from, to := month, month.AddDate(0, 1, 0)
+	table := pgx.Identifier{name}.Sanitize()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	statements := []struct {
+		sql  string
+		args []any
+	}{
+		{`CREATE TABLE ` + table + ` (LIKE ` + parent + ` INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, nil},
+		{`WITH moved AS (
+			DELETE FROM ` + defaultPartition + `
+			WHERE created_at >= $1 AND created_at < $2
+			RETURNING *
+		)
+		INSERT INTO ` + table + ` SELECT * FROM moved`, []any{from, to}},
+		{`ALTER TABLE ` + parent + ` ATTACH PARTITION ` + table + ` FOR VALUES FROM (` + bound(from) + `) TO (` + bound(to) + `)`, nil},
+	}
+	for _, st := range statements {
+		if _, err := tx.Exec(ctx, st.sql, st.args...); err != nil {
+			return false, fmt.Errorf("failed to create partition: %v", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit partition: %v", err)
+	}
+	return true, nil
+}
+
+// detachPartition detaches a partition unless events in it are still queued,
+// and records it as waiting for its archive in the same transaction.
+func detachPartition(ctx context.Context, conn *pgx.Conn, name string) (bool, string, error) {
+	table := pgx.Identifier{name}.Sanitize()
+
+	var queued bool
+	err := conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE status IN ('pending', 'processing'))`).Scan(&queued)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to check queued events: %v", err)
+	}
+	if queued {
+		return false, "events still queued", nil
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	if _, err := tx.Exec(ctx, `ALTER TABLE `+parent+` DETACH PARTITION `+table); err != nil {
+		return false, "", fmt.Errorf("failed to detach partition: %v", err)
+	}
+	if err := sqlc.New(tx).CreateWebhookEventArchive(ctx, name); err != nil {
+		return false, "", fmt.Errorf("failed to record detached partition: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, "", fmt.Errorf("failed to commit detach: %v", err)
+	}
+	return true, "", nil
+}
+
+type partition struct {
+	name     string
+	month    time.Time
+	attached bool
+}
+
+// partitions lists the monthly partition tables, oldest first, with whether
+// each is still attached.
+func partitions(ctx context.Context, conn *pgx.Conn) ([]*partition, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT c.relname, EXISTS (
+			SELECT 1 FROM pg_inherits i
+			WHERE i.inhrelid = c.oid AND i.inhparent = $1::regclass
+		)
+		FROM pg_class c
+		WHERE c.relkind = 'r'
+		  AND c.relname ~ '^webhook_events_y[0-9]{4}m[0-9]{2}$'
+		  AND pg_table_is_visible(c.oid)
+		ORDER BY c.relname`, parent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %v", err)
+	}
+	defer rows.Close()
+
+	var list []*partition
+	for rows.Next() {
+		p := &partition{}
+		if err := rows.Scan(&p.name, &p.attached); err != nil {
+			return nil, fmt.Errorf("failed to list partitions: %v", err)
+		}
+		month, ok := monthOf(p.name)
+		if !ok {
+			continue
+		}
+		p.month = month
+		list = append(list, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %v", err)
+	}
+	return list, nil
+}
+
+func tableExists(ctx context.Context, conn *pgx.Conn, name string) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, pgx.Identifier{name}.Sanitize()).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %v", name, err)
+	}
+	return exists, nil
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func nameOf(month time.Time) string {
+	return fmt.Sprintf("%s_y%04dm%02d", parent, month.Year(), int(month.Month()))
+}
+
+func monthOf(name string) (time.Time, bool) {
+	match := partitionName.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, false
+	}
+	year, _ := strconv.Atoi(match[1])
+	month, _ := strconv.Atoi(match[2])
+	if month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// bound is the literal of a partition bound, in UTC.
+func bound(t time.Time) string {
+	return "'" + t.UTC().Format("2006-01-02 15:04:05") + "+00'"
+}
@@ -0,0 +1,290 @@
+package archive
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/privacy"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// purges is what the archives owe to erasures, deleted decisions and the
+// payload retention, applied like the same rules apply to webhook_events.
+type purges struct {
+	// erasedUsers and erasedSessions are keyed by tenant id.
+	erasedUsers    map[string]map[string]bool
+	erasedSessions map[string]map[string]bool
+	deleted        map[string]bool
+	// lastID is the last webhook_archive_purges row included.
+	lastID int64
+
+	// cutoff is the payload retention cutoff, zero when it is disabled.
+	cutoff time.Time
+	// Events of users under legal hold keep their payloads.
+	heldUsers    map[string]bool
+	heldSessions map[string]bool
+	now          time.Time
+}
+
+func (p *purges) queued() bool {
+	return p.lastID > 0
+}
+
+// loadPurges reads the queued purges and the legal holds.
+func (m *Manager) loadPurges(ctx context.Context, queries *sqlc.Queries, now time.Time) (*purges, error) {
+	p := &purges{
+		erasedUsers:    map[string]map[string]bool{},
+		erasedSessions: map[string]map[string]bool{},
+		deleted:        map[string]bool{},
+		heldUsers:      map[string]bool{},
+		heldSessions:   map[string]bool{},
+		now:            now,
+	}
+	if m.payloadRetention > 0 {
+		p.cutoff = now.Add(-m.payloadRetention)
+	}
+
+	queued, err := queries.ListWebhookArchivePurges(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive purges: %v", err)
+	}
+	add := func(set map[string]map[string]bool, tenantID string, value string) {
+		if set[tenantID] == nil {
+			set[tenantID] = map[string]bool{}
+		}
+		set[tenantID][value] = true
+	}
+	for _, q := range queued {
+		p.lastID = q.ID
+		switch {
+		case q.Action == models.ArchivePurgeDelete && q.SessionID.Valid:
+			p.deleted[q.SessionID.String] = true
+		case q.Action == models.ArchivePurgeErase && q.UserID.Valid:
+			add(p.erasedUsers, q.TenantID.UUID.String(), q.UserID.String)
+		case q.Action == models.ArchivePurgeErase && q.SessionID.Valid:
+			add(p.erasedSessions, q.TenantID.UUID.String(), q.SessionID.String)
+		}
+	}
+
+	holds, err := queries.ListLegalHolds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %v", err)
+	}
+	for _, h := range holds {
+		p.heldUsers[h.UserID] = true
+	}
+	sessions, err := queries.ListLegalHoldSessionIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal hold sessions: %v", err)
+	}
+	for _, s := range sessions {
+		p.heldSessions[s.String] = true
+	}
+	return p, nil
+}
+
+// archivedEvent holds the columns of an archived event the purges look at.
+type archivedEvent struct {
+	TenantID   string          `json:"tenant_id"`
+	EventType  string          `json:"event_type"`
+	SessionID  string          `json:"session_id"`
+	Status     string          `json:"status"`
+	CreatedAt  time.Time       `json:"created_at"`
+	RedactedAt *time.Time      `json:"redacted_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+type eventPayload struct {
+	Data struct {
+		UserID string          `json:"user_id"`
+		Status json.RawMessage `json:"status"`
+	} `json:"data"`
+}
+
+// rewriteStats counts what a pass over an archive changed.
+type rewriteStats struct {
+	rows    int
+	changed int
+	dropped int
+	held    int
+}
+
+// apply returns the line of an event after the purges, or nil when the event
+// is dropped.
+func (p *purges) apply(line []byte, redact bool, stats *rewriteStats) ([]byte, error) {
+	var columns map[string]json.RawMessage
+	var event archivedEvent
+	if err := json.Unmarshal(line, &columns); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(line, &event); err != nil {
+		return nil, err
+	}
+	var payload eventPayload
+	// Payloads that are not objects carry nothing to match on.
+	_ = json.Unmarshal(event.Payload, &payload)
+
+	if p.deleted[event.SessionID] {
+		stats.changed++
+		stats.dropped++
+		return nil, nil
+	}
+
+	changed := false
+	erased := payload.Data.UserID != "" && p.erasedUsers[event.TenantID][payload.Data.UserID]
+	if erased || p.erasedSessions[event.TenantID][event.SessionID] {
+		scrubbed, scrubbedAny, err := privacy.ScrubPayload(event.Payload)
+		if err == nil && scrubbedAny {
+			columns["payload"] = scrubbed
+			changed = true
+		}
+	}
+
+	// The same rule as RedactExpiredWebhookPayloads.
+	if redact && event.CreatedAt.Before(p.cutoff) && event.RedactedAt == nil &&
+		(event.Status == models.WebhookEventDone || event.Status == models.WebhookEventDead) {
+		if p.heldSessions[event.SessionID] || p.heldUsers[payload.Data.UserID] {
+			stats.held++
+		} else {
+			status := payload.Data.Status
+			if status == nil {
+				status = json.RawMessage("null")
+			}
+			redacted, err := json.Marshal(map[string]any{
+				"event_type": event.EventType,
+				"data": map[string]any{
+					"session_id": event.SessionID,
+					"status":     status,
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			redactedAt, err := json.Marshal(p.now)
+			if err != nil {
+				return nil, err
+			}
+			columns["payload"] = redacted
+			columns["redacted_at"] = redactedAt
+			changed = true
+		}
+	}
+
+	if !changed {
+		return line, nil
+	}
+	stats.changed++
+	return json.Marshal(columns)
+}
+
+// transform passes the events of an archive through the purges, writing the
+// result to w.
+func (m *Manager) transform(ctx context.Context, record sqlc.WebhookEventArchive, p *purges, redact bool, w io.Writer) (*rewriteStats, error) {
+	stats := &rewriteStats{}
+	err := m.open(ctx, record.ArchiveKey.String, record.Sha256.String, func(r io.Reader) error {
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(line) > 0 && line[len(line)-1] == '\n' {
+				line = line[:len(line)-1]
+			}
+			if len(line) > 0 {
+				stats.rows++
+				out, applyErr := p.apply(line, redact, stats)
+				if applyErr != nil {
+					return fmt.Errorf("failed to read archive line %d: %v", stats.rows, applyErr)
+				}
+				if out != nil {
+					if _, err := w.Write(append(out, '\n')); err != nil {
+						return fmt.Errorf("failed to compress archive: %v", err)
+					}
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read archive: %v", err)
+			}
+		}
+	})
+	return stats, err
+}
+
+// rewriteArchive applies the purges to an archive. The archive is read once
+// to find whether anything changes and, only then, written again under a new
+// key, which replaces the old one in the record before the old blob is
+// deleted.
+func (m *Manager) rewriteArchive(ctx context.Context, conn *pgx.Conn, record sqlc.WebhookEventArchive, p *purges) (bool, error) {
+	// Payload retention is due while the archive holds events from before
+	// the cutoff it has not been through yet.
+	redact := false
+	redactedUntil := record.RedactedUntil
+	if !p.cutoff.IsZero() {
+		month, _ := monthOf(record.PartitionName)
+		until := p.cutoff
+		if end := month.AddDate(0, 1, 0); end.Before(until) {
+			until = end
+		}
+		if month.Before(until) && (!record.RedactedUntil.Valid || record.RedactedUntil.Time.Before(until)) {
+			redact = true
+			redactedUntil = pgtype.Timestamptz{Time: until, Valid: true}
+		}
+	}
+	if !redact && !p.queued() {
+		return false, nil
+	}
+
+	stats, err := m.transform(ctx, record, p, redact, io.Discard)
+	if err != nil {
+		return false, err
+	}
+
+	params := sqlc.UpdateWebhookEventArchiveParams{
+		PartitionName: record.PartitionName,
+		ArchiveKey:    record.ArchiveKey,
+		EventCount:    record.EventCount,
+		SizeBytes:     record.SizeBytes,
+		Sha256:        record.Sha256,
+		RedactedUntil: redactedUntil,
+		HeldEvents:    record.HeldEvents,
+	}
+	if redact {
+		params.HeldEvents = int32(stats.held)
+	}
+
+	if stats.changed > 0 {
+		key := keyPrefix + record.PartitionName + "." + p.now.UTC().Format("20060102T150405") + ".ndjson.gz"
+		var written *rewriteStats
+		d, err := m.put(ctx, key, record.PartitionName+".ndjson", func(w io.Writer) error {
+			var err error
+			written, err = m.transform(ctx, record, p, redact, w)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+		params.ArchiveKey = pgtype.Text{String: key, Valid: true}
+		params.EventCount = pgtype.Int4{Int32: int32(written.rows - written.dropped), Valid: true}
+		params.SizeBytes = pgtype.Int8{Int64: int64(d.size), Valid: true}
+		params.Sha256 = pgtype.Text{String: d.sum(), Valid: true}
+	}
+
+	if err := sqlc.New(conn).UpdateWebhookEventArchive(ctx, params); err != nil {
+		return false, fmt.Errorf("failed to record archive: %v", err)
+	}
+	if stats.changed > 0 {
+		if err := m.store.Delete(ctx, record.ArchiveKey.String); err != nil {
+			return true, err
+		}
+	}
+	return stats.changed > 0, nil
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// The benchmarks compare the same queries through database/sql over the pgx
+// stdlib driver, the connection used before, and through sqlc over the pgx
+// pool, the one in use now. They need a database:
+//
+//	DATABASE_URL=postgres://... go test -run '^$' -bench . ./internal/database
+
+// benchSessionQuery is the session lookup of GetVerificationSessionBySessionID.
+const benchSessionQuery = `SELECT * FROM verification_sessions WHERE session_id = $1 AND tenant_id = $2`
+
+// openBench connects both drivers to DATABASE_URL, skipping the benchmark
+// when it is not set or the database cannot be reached.
+func openBench(b *testing.B) (*pgxpool.Pool, *sql.DB) {
+	b.Helper()
+	if os.Getenv("DATABASE_URL") == "" {
+		b.Skip("DATABASE_URL not set")
+	}
+	cfg := config.Load()
+	ctx := context.Background()
+
+	poolConfig, err := PoolConfig(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		b.Skipf("failed to open pool: %v", err)
+	}
+	b.Cleanup(pool.Close)
+	if err := pool.Ping(ctx); err != nil {
+		b.Skipf("database unavailable: %v", err)
+	}
+
+	stdDB, err := sql.Open("pgx", cfg.DatabaseURL)
+	if err != nil {
+		b.Fatalf("failed to open database/sql: %v", err)
+	}
+	b.Cleanup(func() { stdDB.Close() })
+	stdDB.SetMaxOpenConns(cfg.DBMaxConns)
+	stdDB.SetMaxIdleConns(cfg.DBMaxConns)
+
+	return pool, stdDB
+}
+
+func BenchmarkSelectOne(b *testing.B) {
+	pool, stdDB := openBench(b)
+	ctx := context.Background()
+
+	b.Run("database/sql", func(b *testing.B) {
+		for b.Loop() {
+			var one int
+			if err := stdDB.QueryRowContext(ctx, `SELECT 1`).Scan(&one); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pgxpool", func(b *testing.B) {
+		for b.Loop() {
+			var one int
+			if err := pool.QueryRow(ctx, `SELECT 1`).Scan(&one); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkSessionBySessionID looks up the most recent session, or an id
+// matching nothing on an empty database.
+func BenchmarkSessionBySessionID(b *testing.B) {
+	pool, stdDB := openBench(b)
+	ctx := context.Background()
+
+	sessionID, tenantID := "bench-missing-session", uuid.Nil
+	err := pool.QueryRow(ctx, `SELECT session_id, tenant_id FROM verification_sessions ORDER BY created_at DESC LIMIT 1`).Scan(&sessionID, &tenantID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		b.Fatalf("failed to pick a session: %v", err)
+	}
+
+	b.Run("database/sql", func(b *testing.B) {
+		for b.Loop() {
+			if err := drainSQL(stdDB.QueryContext(ctx, benchSessionQuery, sessionID, tenantID)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pgxpool+sqlc", func(b *testing.B) {
+		queries := sqlc.New(pool)
+		for b.Loop() {
+			_, err := queries.GetVerificationSessionBySessionID(ctx, sqlc.GetVerificationSessionBySessionIDParams{
+				SessionID: sessionID,
+				TenantID:  tenantID,
+			})
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// drainSQL reads every column of every row, as sqlc would scan them.
+func drainSQL(rows *sql.Rows, err error) error {
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
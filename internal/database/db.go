@@ -2,45 +2,125 @@ package database
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/FelipePn10/crispaybackend/config"
 	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
-
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type DB struct {
-	SQL *sql.DB
+	Pool *pgxpool.Pool
 }
 
 func NewDB(cfg *config.Config) (*DB, error) {
-	dbSQL, err := sql.Open("pgx", cfg.DatabaseURL)
+	poolConfig, err := PoolConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open DB: %w", err)
+		return nil, err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := dbSQL.PingContext(ctx); err != nil {
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DB: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("unable to ping DB: %w", err)
 	}
 
 	log.Println("Connected to the database successfully")
 
-	return &DB{SQL: dbSQL}, nil
+	return &DB{Pool: pool}, nil
+}
+
+// PoolConfig builds the pool configuration from DATABASE_URL and the DB_*
+// settings. Pool parameters in the URL (pool_max_conns and so on) are
+// overridden by the settings.
+func PoolConfig(cfg *config.Config) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATABASE_URL: %w", err)
+	}
+	if cfg.DBMaxConns < 1 || cfg.DBMinConns < 0 || cfg.DBMinConns > cfg.DBMaxConns {
+		return nil, fmt.Errorf("invalid DB pool size: DB_MIN_CONNS=%d DB_MAX_CONNS=%d", cfg.DBMinConns, cfg.DBMaxConns)
+	}
+
+	mode, err := queryExecMode(cfg.DBQueryExecMode)
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig.MaxConns = int32(cfg.DBMaxConns)
+	poolConfig.MinConns = int32(cfg.DBMinConns)
+	poolConfig.MaxConnLifetime = cfg.DBMaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.DBMaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.DBHealthCheckPeriod
+	poolConfig.ConnConfig.DefaultQueryExecMode = mode
+	poolConfig.ConnConfig.StatementCacheCapacity = cfg.DBStatementCacheCapacity
+	poolConfig.ConnConfig.DescriptionCacheCapacity = cfg.DBStatementCacheCapacity
+	return poolConfig, nil
+}
+
+func queryExecMode(name string) (pgx.QueryExecMode, error) {
+	switch name {
+	case "", "cache_statement":
+		return pgx.QueryExecModeCacheStatement, nil
+	case "cache_describe":
+		return pgx.QueryExecModeCacheDescribe, nil
+	case "describe_exec":
+		return pgx.QueryExecModeDescribeExec, nil
+	case "exec":
+		return pgx.QueryExecModeExec, nil
+	case "simple_protocol":
+		return pgx.QueryExecModeSimpleProtocol, nil
+	default:
+		return 0, fmt.Errorf("invalid DB_QUERY_EXEC_MODE %q", name)
+	}
 }
 
 func (db *DB) Queries() *sqlc.Queries {
-	return sqlc.New(db.SQL)
+	return sqlc.New(db.Pool)
+}
+
+// Ping checks that a connection can be acquired and answers.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.Pool.Ping(ctx)
+}
+
+// Stats reports the state of the connection pool.
+func (db *DB) Stats() PoolStats {
+	s := db.Pool.Stat()
+	return PoolStats{
+		MaxConns:        s.MaxConns(),
+		TotalConns:      s.TotalConns(),
+		IdleConns:       s.IdleConns(),
+		AcquiredConns:   s.AcquiredConns(),
+		AcquireCount:    s.AcquireCount(),
+		EmptyAcquires:   s.EmptyAcquireCount(),
+		AcquireDuration: s.AcquireDuration().String(),
+	}
+}
+
+// PoolStats is the state of the connection pool, as reported by /health.
+type PoolStats struct {
+	MaxConns      int32 `json:"max_conns"`
+	TotalConns    int32 `json:"total_conns"`
+	IdleConns     int32 `json:"idle_conns"`
+	AcquiredConns int32 `json:"acquired_conns"`
+	AcquireCount  int64 `json:"acquire_count"`
+	// EmptyAcquires counts acquires that had to wait for a connection.
+	EmptyAcquires   int64  `json:"empty_acquires"`
+	AcquireDuration string `json:"acquire_duration"`
 }
 
 func (db *DB) Close() {
-	if db.SQL != nil {
-		_ = db.SQL.Close()
+	if db.Pool != nil {
+		db.Pool.Close()
 	}
 }
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_webhook_events_session_order;
+DROP INDEX IF EXISTS idx_webhook_events_queue;
+
+ALTER TABLE webhook_events
+    DROP COLUMN processed_at,
+    DROP COLUMN last_error,
+    DROP COLUMN locked_at,
+    DROP COLUMN next_attempt_at,
+    DROP COLUMN attempts,
+    DROP COLUMN status;
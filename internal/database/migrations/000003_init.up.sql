@@ -0,0 +1,14 @@
+ALTER TABLE webhook_events
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN locked_at TIMESTAMPTZ,
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN processed_at TIMESTAMPTZ;
+
+-- Events received before the queue existed were handled synchronously.
+UPDATE webhook_events SET status = 'done', processed_at = created_at;
+
+CREATE INDEX idx_webhook_events_queue ON webhook_events(status, next_attempt_at)
+    WHERE status IN ('pending', 'processing');
+CREATE INDEX idx_webhook_events_session_order ON webhook_events(session_id, created_at, id);
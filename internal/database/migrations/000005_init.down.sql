@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS verification_sessions_status_notify ON verification_sessions;
+DROP FUNCTION IF EXISTS notify_verification_status();
@@ -0,0 +1,20 @@
+-- Broadcasts every verification status change so API replicas can push it
+-- to clients listening on the SSE stream.
+CREATE OR REPLACE FUNCTION notify_verification_status() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR OLD.status IS DISTINCT FROM NEW.status THEN
+        PERFORM pg_notify('verification_status', json_build_object(
+            'session_id', NEW.session_id,
+            'user_id', NEW.user_id,
+            'status', NEW.status,
+            'previous_status', CASE WHEN TG_OP = 'UPDATE' THEN OLD.status END,
+            'updated_at', NEW.updated_at
+        )::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER verification_sessions_status_notify
+    AFTER INSERT OR UPDATE OF status ON verification_sessions
+    FOR EACH ROW EXECUTE FUNCTION notify_verification_status();
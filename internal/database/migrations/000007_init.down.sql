@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE verification_sessions DROP COLUMN IF EXISTS erased_at;
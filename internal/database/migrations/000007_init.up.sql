@@ -0,0 +1,13 @@
+ALTER TABLE verification_sessions ADD COLUMN erased_at TIMESTAMPTZ;
+
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action VARCHAR(100) NOT NULL,
+    subject_user_id VARCHAR(255) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_subject_user_id ON audit_log(subject_user_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
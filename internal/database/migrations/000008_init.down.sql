@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS purge_runs;
+DROP TABLE IF EXISTS legal_holds;
+
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS redacted_at;
@@ -0,0 +1,17 @@
+ALTER TABLE webhook_events ADD COLUMN redacted_at TIMESTAMPTZ;
+
+CREATE TABLE legal_holds (
+    user_id VARCHAR(255) PRIMARY KEY,
+    reason TEXT NOT NULL,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE purge_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    report JSONB NOT NULL
+);
+
+CREATE INDEX idx_purge_runs_started_at ON purge_runs(started_at);
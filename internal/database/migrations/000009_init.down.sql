@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_verification_sessions_user_email_index;
+
+ALTER TABLE verification_sessions
+    DROP COLUMN IF EXISTS user_email_index,
+    ALTER COLUMN user_email TYPE VARCHAR(255),
+    ALTER COLUMN user_first_name TYPE VARCHAR(100),
+    ALTER COLUMN user_last_name TYPE VARCHAR(100);
@@ -0,0 +1,8 @@
+-- Encrypted values are longer than the plaintext they replace.
+ALTER TABLE verification_sessions
+    ALTER COLUMN user_email TYPE TEXT,
+    ALTER COLUMN user_first_name TYPE TEXT,
+    ALTER COLUMN user_last_name TYPE TEXT,
+    ADD COLUMN user_email_index VARCHAR(64);
+
+CREATE INDEX idx_verification_sessions_user_email_index ON verification_sessions(user_email_index);
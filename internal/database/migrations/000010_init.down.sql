@@ -0,0 +1,17 @@
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+DROP TRIGGER IF EXISTS audit_log_chain ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+DROP FUNCTION IF EXISTS audit_log_chain();
+DROP FUNCTION IF EXISTS audit_log_hash(audit_log);
+
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_entity_id;
+
+ALTER TABLE audit_log
+    DROP CONSTRAINT IF EXISTS audit_log_seq_key,
+    DROP COLUMN IF EXISTS hash,
+    DROP COLUMN IF EXISTS prev_hash,
+    DROP COLUMN IF EXISTS entity_id,
+    DROP COLUMN IF EXISTS entity_type,
+    DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,84 @@
+ALTER TABLE audit_log
+    ADD COLUMN seq BIGINT,
+    ADD COLUMN entity_type VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN entity_id VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN hash VARCHAR(64) NOT NULL DEFAULT '';
+
+-- The hash covers every column of the entry plus the previous entry's hash.
+-- repository.AuditHash computes the same value to verify the chain.
+CREATE OR REPLACE FUNCTION audit_log_hash(e audit_log) RETURNS VARCHAR AS $$
+    SELECT encode(sha256(convert_to(concat_ws('|',
+        e.seq,
+        e.prev_hash,
+        e.action,
+        e.subject_user_id,
+        e.actor,
+        e.entity_type,
+        e.entity_id,
+        e.details::text,
+        EXTRACT(EPOCH FROM date_trunc('second', e.created_at))::bigint * 1000000
+            + EXTRACT(MICROSECONDS FROM e.created_at)::bigint % 1000000
+    ), 'UTF8')), 'hex');
+$$ LANGUAGE sql STABLE;
+
+-- Chain the entries written before the hash columns existed.
+DO $$
+DECLARE
+    e audit_log;
+    n BIGINT := 0;
+    prev VARCHAR := '';
+BEGIN
+    FOR e IN SELECT * FROM audit_log ORDER BY created_at, id LOOP
+        n := n + 1;
+        e.seq := n;
+        e.prev_hash := prev;
+        e.hash := audit_log_hash(e);
+        UPDATE audit_log SET seq = e.seq, prev_hash = e.prev_hash, hash = e.hash WHERE id = e.id;
+        prev := e.hash;
+    END LOOP;
+END $$;
+
+ALTER TABLE audit_log
+    ALTER COLUMN seq SET NOT NULL,
+    ADD CONSTRAINT audit_log_seq_key UNIQUE (seq);
+
+-- Appends are serialized with an advisory lock so every entry links to the
+-- one committed right before it. The lock is global, across tenants and
+-- replicas, and is held from the insert until its transaction ends: it caps
+-- audit-writing transactions at about one per commit round trip, and any slow
+-- work a transaction does after its audit insert stalls every other writer.
+CREATE OR REPLACE FUNCTION audit_log_chain() RETURNS TRIGGER AS $$
+DECLARE
+    last audit_log;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('audit_log'));
+    SELECT * INTO last FROM audit_log ORDER BY seq DESC LIMIT 1;
+    NEW.seq := COALESCE(last.seq, 0) + 1;
+    NEW.prev_hash := COALESCE(last.hash, '');
+    NEW.created_at := NOW();
+    NEW.hash := audit_log_hash(NEW);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_chain
+    BEFORE INSERT ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_chain();
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+CREATE INDEX idx_audit_log_entity_id ON audit_log(entity_id);
+CREATE INDEX idx_audit_log_action ON audit_log(action);
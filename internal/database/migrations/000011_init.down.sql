@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_verification_sessions_user_status_level;
+
+ALTER TABLE verification_sessions
+    DROP COLUMN IF EXISTS base_session_id,
+    DROP COLUMN IF EXISTS level;
@@ -0,0 +1,6 @@
+-- KYC tiers. An upgrade session points at the approval it builds on.
+ALTER TABLE verification_sessions
+    ADD COLUMN level VARCHAR(20) NOT NULL DEFAULT 'basic',
+    ADD COLUMN base_session_id VARCHAR(255);
+
+CREATE INDEX idx_verification_sessions_user_status_level ON verification_sessions(user_id, status, level);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_verification_sessions_next_review_at;
+DROP INDEX IF EXISTS idx_verification_sessions_expires_at;
+
+ALTER TABLE verification_sessions
+    DROP COLUMN IF EXISTS reminded_at,
+    DROP COLUMN IF EXISTS next_review_at,
+    DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,11 @@
+-- Ongoing monitoring of approved sessions: expires_at follows the identity
+-- document, next_review_at schedules the periodic re-screening.
+ALTER TABLE verification_sessions
+    ADD COLUMN expires_at TIMESTAMPTZ,
+    ADD COLUMN next_review_at TIMESTAMPTZ,
+    ADD COLUMN reminded_at TIMESTAMPTZ;
+
+CREATE INDEX idx_verification_sessions_expires_at ON verification_sessions(expires_at)
+    WHERE status = 'approved';
+CREATE INDEX idx_verification_sessions_next_review_at ON verification_sessions(next_review_at)
+    WHERE status = 'approved';
@@ -0,0 +1,17 @@
+-- Hits of the local sanctions list screening, kept for the reviewer.
+CREATE TABLE screening_matches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id VARCHAR(255) NOT NULL REFERENCES verification_sessions(session_id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    list VARCHAR(20) NOT NULL,
+    entry_id VARCHAR(100) NOT NULL,
+    entry_name TEXT NOT NULL,
+    matched_name TEXT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    dob_match BOOLEAN,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_screening_matches_session_id ON screening_matches(session_id);
+CREATE INDEX idx_screening_matches_user_id ON screening_matches(user_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS identity_links;
+
+DROP INDEX IF EXISTS idx_verification_sessions_identity_fingerprint;
+
+ALTER TABLE verification_sessions
+    DROP COLUMN IF EXISTS identity_fingerprint;
@@ -0,0 +1,28 @@
+-- Keyed hash of document number, issuing country and date of birth, set
+-- when a session is approved.
+ALTER TABLE verification_sessions
+    ADD COLUMN identity_fingerprint VARCHAR(64);
+
+CREATE INDEX idx_verification_sessions_identity_fingerprint
+    ON verification_sessions(identity_fingerprint)
+    WHERE identity_fingerprint IS NOT NULL;
+
+-- Accounts found to belong to the same person, kept for the reviewer.
+CREATE TABLE identity_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id VARCHAR(255) NOT NULL REFERENCES verification_sessions(session_id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    linked_session_id VARCHAR(255) NOT NULL REFERENCES verification_sessions(session_id) ON DELETE CASCADE,
+    linked_user_id VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    resolution TEXT,
+    resolved_by VARCHAR(255),
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (session_id, linked_session_id)
+);
+
+CREATE INDEX idx_identity_links_user_id ON identity_links(user_id);
+CREATE INDEX idx_identity_links_linked_user_id ON identity_links(linked_user_id);
+CREATE INDEX idx_identity_links_status ON identity_links(status, created_at);
@@ -0,0 +1,19 @@
+-- Risk score of every terminal decision, with the signals and fired rules,
+-- kept to tune the rules.
+CREATE TABLE risk_scores (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id VARCHAR(255) NOT NULL REFERENCES verification_sessions(session_id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    decision VARCHAR(20) NOT NULL,
+    didit_status VARCHAR(50) NOT NULL,
+    final_status VARCHAR(50) NOT NULL,
+    enforced BOOLEAN NOT NULL,
+    rules_version VARCHAR(100),
+    signals JSONB NOT NULL DEFAULT '{}',
+    reasons JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_risk_scores_session_id ON risk_scores(session_id);
+CREATE INDEX idx_risk_scores_created_at ON risk_scores(created_at);
@@ -0,0 +1,32 @@
+-- Business verification (KYB). A company is approved once every required
+-- person linked to it holds an approved individual verification.
+CREATE TABLE companies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cnpj VARCHAR(14) NOT NULL UNIQUE,
+    legal_name TEXT NOT NULL,
+    trade_name TEXT,
+    registered_address JSONB NOT NULL,
+    owner_user_id VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    approved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_companies_owner_user_id ON companies(owner_user_id);
+
+-- Ultimate beneficial owners and legal representatives. Their personal data
+-- lives in their verification sessions.
+CREATE TABLE company_people (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    role VARCHAR(30) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    ownership_percent DOUBLE PRECISION,
+    required BOOLEAN NOT NULL DEFAULT TRUE,
+    session_id VARCHAR(255) REFERENCES verification_sessions(session_id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, role, user_id)
+);
+
+CREATE INDEX idx_company_people_user_id ON company_people(user_id);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS user_cpfs;
+
+DROP INDEX IF EXISTS idx_verification_sessions_user_cpf_index;
+
+ALTER TABLE verification_sessions
+    DROP COLUMN IF EXISTS cpf_match,
+    DROP COLUMN IF EXISTS user_cpf_index,
+    DROP COLUMN IF EXISTS user_cpf;
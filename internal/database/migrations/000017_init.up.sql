@@ -0,0 +1,20 @@
+-- CPF given when the verification was started, encrypted, with a keyed
+-- hash for lookups. cpf_match records the cross-check against the document.
+ALTER TABLE verification_sessions
+    ADD COLUMN user_cpf TEXT,
+    ADD COLUMN user_cpf_index VARCHAR(64),
+    ADD COLUMN cpf_match BOOLEAN;
+
+CREATE INDEX idx_verification_sessions_user_cpf_index
+    ON verification_sessions(user_cpf_index)
+    WHERE user_cpf_index IS NOT NULL;
+
+-- One user per CPF. The first user to start a verification with a CPF
+-- claims it.
+CREATE TABLE user_cpfs (
+    cpf_index VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_cpfs_user_id ON user_cpfs(user_id);
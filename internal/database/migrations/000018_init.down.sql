@@ -0,0 +1,9 @@
+ALTER TABLE webhook_events
+    DROP COLUMN IF EXISTS provider;
+
+DROP INDEX IF EXISTS idx_verification_sessions_reconcile;
+
+ALTER TABLE verification_sessions
+    DROP COLUMN IF EXISTS reconciled_at,
+    DROP COLUMN IF EXISTS verification_url,
+    DROP COLUMN IF EXISTS provider;
@@ -0,0 +1,13 @@
+-- KYC provider that verifies each session and received each webhook.
+-- didit_session_id keeps the provider's own session id, whatever the provider.
+ALTER TABLE verification_sessions
+    ADD COLUMN provider VARCHAR(50) NOT NULL DEFAULT 'didit',
+    ADD COLUMN verification_url TEXT,
+    ADD COLUMN reconciled_at TIMESTAMPTZ;
+
+CREATE INDEX idx_verification_sessions_reconcile
+    ON verification_sessions(created_at)
+    WHERE status = 'pending' AND didit_session_id IS NOT NULL;
+
+ALTER TABLE webhook_events
+    ADD COLUMN provider VARCHAR(50) NOT NULL DEFAULT 'didit';
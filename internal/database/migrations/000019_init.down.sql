@@ -0,0 +1,31 @@
+CREATE OR REPLACE FUNCTION notify_verification_status() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR OLD.status IS DISTINCT FROM NEW.status THEN
+        PERFORM pg_notify('verification_status', json_build_object(
+            'session_id', NEW.session_id,
+            'user_id', NEW.user_id,
+            'status', NEW.status,
+            'previous_status', CASE WHEN TG_OP = 'UPDATE' THEN OLD.status END,
+            'updated_at', NEW.updated_at
+        )::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DELETE FROM user_cpfs
+WHERE tenant_id <> '00000000-0000-0000-0000-000000000000';
+
+ALTER TABLE user_cpfs DROP CONSTRAINT user_cpfs_pkey;
+ALTER TABLE user_cpfs ADD PRIMARY KEY (cpf_index);
+ALTER TABLE user_cpfs DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE webhook_events
+    DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS idx_verification_sessions_tenant_user_id;
+
+ALTER TABLE verification_sessions
+    DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
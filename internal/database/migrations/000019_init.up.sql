@@ -0,0 +1,63 @@
+-- Tenants are the partners verifying their own users through this service.
+-- The default tenant owns the sessions created before multi-tenancy and
+-- those of callers without an API key; it uses the environment's Didit
+-- credentials and email identity. Empty columns of other tenants fall back
+-- to the same settings.
+CREATE TABLE tenants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    -- SHA-256 of the tenant's API key; the key itself is only shown once.
+    api_key_hash VARCHAR(64) UNIQUE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    -- Didit credentials, encrypted like session PII.
+    didit_api_key TEXT,
+    didit_workflow_id VARCHAR(255),
+    didit_workflow_url TEXT,
+    didit_webhook_secret TEXT,
+    -- Email identity and branding.
+    sender_name VARCHAR(255),
+    sender_email VARCHAR(255),
+    logo_url TEXT,
+    primary_color VARCHAR(7),
+    support_url TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO tenants (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000000', 'default', 'CrisPay');
+
+ALTER TABLE verification_sessions
+    ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES tenants(id);
+
+CREATE INDEX idx_verification_sessions_tenant_user_id
+    ON verification_sessions(tenant_id, user_id);
+
+ALTER TABLE webhook_events
+    ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES tenants(id);
+
+-- User ids are only unique within a tenant, and so are CPF claims: the same
+-- person may be a customer of several tenants.
+ALTER TABLE user_cpfs
+    ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES tenants(id);
+
+ALTER TABLE user_cpfs DROP CONSTRAINT user_cpfs_pkey;
+ALTER TABLE user_cpfs ADD PRIMARY KEY (tenant_id, cpf_index);
+
+-- Status notifications name the tenant so listeners act for it.
+CREATE OR REPLACE FUNCTION notify_verification_status() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR OLD.status IS DISTINCT FROM NEW.status THEN
+        PERFORM pg_notify('verification_status', json_build_object(
+            'session_id', NEW.session_id,
+            'tenant_id', NEW.tenant_id,
+            'user_id', NEW.user_id,
+            'status', NEW.status,
+            'previous_status', CASE WHEN TG_OP = 'UPDATE' THEN OLD.status END,
+            'updated_at', NEW.updated_at
+        )::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
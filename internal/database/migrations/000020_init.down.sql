@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_verification_media_user_id;
+DROP TABLE IF EXISTS verification_media;
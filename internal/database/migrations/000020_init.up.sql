@@ -0,0 +1,18 @@
+-- Verification media (document images, selfie, liveness video) downloaded
+-- from the provider's decision. The files live encrypted in the blob store;
+-- rows keep where, and the SHA-256 of the original and of the stored object.
+CREATE TABLE verification_media (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id VARCHAR(255) NOT NULL REFERENCES verification_sessions(session_id),
+    user_id VARCHAR(255) NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    stored_sha256 CHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (session_id, kind)
+);
+
+CREATE INDEX idx_verification_media_user_id ON verification_media(user_id);
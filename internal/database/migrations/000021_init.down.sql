@@ -0,0 +1,38 @@
+-- Archived partitions are not brought back; restore them with
+-- `crispay webhooks restore` first if they are needed.
+CREATE TABLE webhook_events_unpartitioned (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_type VARCHAR(100) NOT NULL,
+    session_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    processed BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_at TIMESTAMPTZ,
+    last_error TEXT,
+    processed_at TIMESTAMPTZ,
+    redacted_at TIMESTAMPTZ,
+    provider VARCHAR(50) NOT NULL DEFAULT 'didit',
+    tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES tenants(id)
+);
+
+INSERT INTO webhook_events_unpartitioned
+SELECT
+    id, event_type, session_id, payload, processed, created_at, status,
+    attempts, next_attempt_at, locked_at, last_error, processed_at,
+    redacted_at, provider, tenant_id
+FROM webhook_events;
+
+-- Dropping the partitioned table drops its partitions and their indexes.
+DROP TABLE webhook_events;
+
+ALTER TABLE webhook_events_unpartitioned RENAME CONSTRAINT webhook_events_unpartitioned_pkey TO webhook_events_pkey;
+ALTER TABLE webhook_events_unpartitioned RENAME TO webhook_events;
+
+CREATE INDEX idx_webhook_events_session_id ON webhook_events(session_id);
+CREATE INDEX idx_webhook_events_created_at ON webhook_events(created_at);
+CREATE INDEX idx_webhook_events_queue ON webhook_events(status, next_attempt_at)
+    WHERE status IN ('pending', 'processing');
+CREATE INDEX idx_webhook_events_session_order ON webhook_events(session_id, created_at, id);
@@ -0,0 +1,75 @@
+-- webhook_events is partitioned by month of created_at. The service creates
+-- the partitions of the coming months and detaches and archives old ones;
+-- this migration creates those holding the current rows. The default
+-- partition catches rows outside every partition until the service moves
+-- them to their own. A partitioned table's primary key must include the
+-- partition key.
+ALTER TABLE webhook_events RENAME TO webhook_events_unpartitioned;
+ALTER TABLE webhook_events_unpartitioned RENAME CONSTRAINT webhook_events_pkey TO webhook_events_unpartitioned_pkey;
+
+DROP INDEX IF EXISTS idx_webhook_events_session_id;
+DROP INDEX IF EXISTS idx_webhook_events_created_at;
+DROP INDEX IF EXISTS idx_webhook_events_queue;
+DROP INDEX IF EXISTS idx_webhook_events_session_order;
+
+CREATE TABLE webhook_events (
+    id UUID NOT NULL DEFAULT gen_random_uuid(),
+    event_type VARCHAR(100) NOT NULL,
+    session_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    processed BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_at TIMESTAMPTZ,
+    last_error TEXT,
+    processed_at TIMESTAMPTZ,
+    redacted_at TIMESTAMPTZ,
+    provider VARCHAR(50) NOT NULL DEFAULT 'didit',
+    tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES tenants(id),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX idx_webhook_events_session_id ON webhook_events(session_id);
+CREATE INDEX idx_webhook_events_created_at ON webhook_events(created_at);
+CREATE INDEX idx_webhook_events_queue ON webhook_events(status, next_attempt_at)
+    WHERE status IN ('pending', 'processing');
+CREATE INDEX idx_webhook_events_session_order ON webhook_events(session_id, created_at, id);
+
+CREATE TABLE webhook_events_default PARTITION OF webhook_events DEFAULT;
+
+-- Monthly partitions, in UTC, from the oldest event to three months ahead.
+DO $$
+DECLARE
+    from_month TIMESTAMP;
+    to_month TIMESTAMP := date_trunc('month', NOW() AT TIME ZONE 'UTC') + INTERVAL '3 months';
+BEGIN
+    SELECT date_trunc('month', MIN(created_at) AT TIME ZONE 'UTC') INTO from_month
+    FROM webhook_events_unpartitioned;
+    from_month := LEAST(COALESCE(from_month, to_month), date_trunc('month', NOW() AT TIME ZONE 'UTC'));
+
+    WHILE from_month <= to_month LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF webhook_events FOR VALUES FROM (%L) TO (%L)',
+            'webhook_events_' || to_char(from_month, '"y"YYYY"m"MM'),
+            from_month::text || '+00',
+            (from_month + INTERVAL '1 month')::text || '+00'
+        );
+        from_month := from_month + INTERVAL '1 month';
+    END LOOP;
+END;
+$$;
+
+INSERT INTO webhook_events (
+    id, event_type, session_id, payload, processed, created_at, status,
+    attempts, next_attempt_at, locked_at, last_error, processed_at,
+    redacted_at, provider, tenant_id
+)
+SELECT
+    id, event_type, session_id, payload, processed, created_at, status,
+    attempts, next_attempt_at, locked_at, last_error, processed_at,
+    redacted_at, provider, tenant_id
+FROM webhook_events_unpartitioned;
+
+DROP TABLE webhook_events_unpartitioned;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_webhook_subscriptions_tenant_id;
+
+ALTER TABLE webhook_subscriptions
+    DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,8 @@
+-- Subscriptions belong to the tenant that registered them and only receive
+-- the transitions of its sessions. Existing ones stay with the default
+-- tenant.
+ALTER TABLE webhook_subscriptions
+    ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES tenants(id);
+
+CREATE INDEX idx_webhook_subscriptions_tenant_id
+    ON webhook_subscriptions(tenant_id, created_at);
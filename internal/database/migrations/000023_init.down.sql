@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS webhook_archive_purges;
+DROP TABLE IF EXISTS webhook_event_archives;
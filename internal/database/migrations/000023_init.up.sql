@@ -0,0 +1,33 @@
+-- Partitions of webhook_events detached by the partition manager. Only the
+-- partitions listed here are archived and dropped, so a table detached by
+-- hand or restored under a partition's name is never touched.
+CREATE TABLE webhook_event_archives (
+    partition_name VARCHAR(63) PRIMARY KEY,
+    -- detached: waiting for its export; archived: exported and dropped.
+    status VARCHAR(20) NOT NULL DEFAULT 'detached',
+    archive_key TEXT,
+    event_count INTEGER,
+    size_bytes BIGINT,
+    -- SHA-256 of the stored archive, checked whenever it is read back.
+    sha256 VARCHAR(64),
+    -- Events created before this time went through the payload retention;
+    -- those of users under legal hold, counted in held_events, were kept.
+    redacted_until TIMESTAMPTZ,
+    held_events INTEGER NOT NULL DEFAULT 0,
+    detached_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    archived_at TIMESTAMPTZ
+);
+
+-- Changes the archives still owe to erasures and to the retention of
+-- decisions: "erase" scrubs the events of a user (user_id) or of a Didit
+-- session (session_id) of a tenant, "delete" drops the events of a Didit
+-- session. The partition manager rewrites the archives on its next run and
+-- then deletes the rows.
+CREATE TABLE webhook_archive_purges (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(20) NOT NULL,
+    tenant_id UUID REFERENCES tenants(id),
+    user_id VARCHAR(255),
+    session_id VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
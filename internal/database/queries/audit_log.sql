@@ -0,0 +1,32 @@
+-- name: CreateAuditLogEntry :one
+-- seq, prev_hash, hash and created_at are set by the audit_log_chain trigger.
+INSERT INTO audit_log (
+    action,
+    subject_user_id,
+    actor,
+    entity_type,
+    entity_id,
+    details
+) VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListAuditLogEntriesByUserID :many
+SELECT * FROM audit_log
+WHERE subject_user_id = $1
+ORDER BY seq ASC;
+
+-- name: ListAuditLogEntries :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg('subject_user_id')::text IS NULL OR subject_user_id = sqlc.narg('subject_user_id')::text)
+  AND (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action')::text)
+  AND (sqlc.narg('entity_id')::text IS NULL OR entity_id = sqlc.narg('entity_id')::text)
+  AND (sqlc.narg('since')::timestamptz IS NULL OR created_at >= sqlc.narg('since')::timestamptz)
+  AND (sqlc.narg('before_seq')::bigint IS NULL OR seq < sqlc.narg('before_seq')::bigint)
+ORDER BY seq DESC
+LIMIT sqlc.arg('max_rows')::int;
+
+-- name: ListAuditLogEntriesAfterSeq :many
+SELECT * FROM audit_log
+WHERE seq > $1
+ORDER BY seq ASC
+LIMIT $2;
@@ -0,0 +1,66 @@
+-- name: CreateCompany :one
+INSERT INTO companies (
+    cnpj,
+    legal_name,
+    trade_name,
+    registered_address,
+    owner_user_id
+) VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetCompany :one
+SELECT * FROM companies
+WHERE id = $1 LIMIT 1;
+
+-- name: GetCompanyByCNPJ :one
+SELECT * FROM companies
+WHERE cnpj = $1 LIMIT 1;
+
+-- name: ListCompanyIDs :many
+SELECT id FROM companies
+ORDER BY created_at;
+
+-- name: ListCompaniesByOwnerUserID :many
+SELECT * FROM companies
+WHERE owner_user_id = $1
+ORDER BY created_at DESC;
+
+-- name: UpdateCompanyStatus :one
+-- Only changes the row when the status differs, so replicas refreshing the
+-- same company concurrently record the change once.
+UPDATE companies
+SET
+    status = sqlc.arg('status')::text,
+    approved_at = CASE WHEN sqlc.arg('status')::text = 'approved' THEN NOW() ELSE NULL END,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')::uuid AND status <> sqlc.arg('status')::text
+RETURNING *;
+
+-- name: CreateCompanyPerson :one
+INSERT INTO company_people (
+    company_id,
+    role,
+    user_id,
+    ownership_percent,
+    required
+) VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetCompanyPerson :one
+SELECT * FROM company_people
+WHERE id = $1 AND company_id = $2 LIMIT 1;
+
+-- name: ListCompanyPeople :many
+SELECT * FROM company_people
+WHERE company_id = $1
+ORDER BY created_at, id;
+
+-- name: SetCompanyPersonSession :exec
+UPDATE company_people
+SET session_id = $2
+WHERE id = $1;
+
+-- name: ListCompanyIDsByPersonUserID :many
+SELECT company_id FROM company_people
+WHERE user_id = $1
+GROUP BY company_id;
@@ -0,0 +1,16 @@
+-- name: ClaimCPF :one
+-- Returns the user holding the CPF: the caller when the claim is new or
+-- already theirs, someone else otherwise.
+INSERT INTO user_cpfs (cpf_index, user_id, tenant_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (tenant_id, cpf_index) DO UPDATE SET cpf_index = EXCLUDED.cpf_index
+RETURNING user_id;
+
+-- name: DeleteUserCPFsByUserID :execrows
+DELETE FROM user_cpfs
+WHERE user_id = $1 AND tenant_id = $2;
+
+-- name: SetVerificationSessionCPFMatch :exec
+UPDATE verification_sessions
+SET cpf_match = $2, updated_at = NOW()
+WHERE session_id = $1 AND tenant_id = $3;
@@ -0,0 +1,53 @@
+-- name: SetVerificationSessionFingerprint :exec
+UPDATE verification_sessions
+SET identity_fingerprint = $2, updated_at = NOW()
+WHERE session_id = $1 AND tenant_id = $3;
+
+-- name: ListOtherUserSessionsByFingerprint :many
+-- Approved sessions of other users sharing the fingerprint, latest first.
+-- Declined and blocked sessions do not count: the document may have been
+-- stolen, and its owner must still be able to verify.
+SELECT * FROM verification_sessions
+WHERE identity_fingerprint = sqlc.arg('identity_fingerprint')::text
+  AND tenant_id = sqlc.arg('tenant_id')
+  AND user_id <> sqlc.arg('user_id')::text
+  AND status = 'approved'
+ORDER BY created_at DESC;
+
+-- name: CreateIdentityLink :one
+INSERT INTO identity_links (
+    session_id,
+    user_id,
+    linked_session_id,
+    linked_user_id,
+    action
+) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (session_id, linked_session_id) DO UPDATE SET
+    action = EXCLUDED.action
+RETURNING *;
+
+-- name: GetIdentityLink :one
+SELECT * FROM identity_links
+WHERE id = $1 LIMIT 1;
+
+-- name: ListIdentityLinksByStatus :many
+SELECT * FROM identity_links
+WHERE status = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ListIdentityLinksByUserID :many
+-- Links on either side of the user.
+SELECT * FROM identity_links
+WHERE user_id = $1 OR linked_user_id = $1
+ORDER BY created_at DESC;
+
+-- name: ResolveIdentityLink :one
+UPDATE identity_links
+SET
+    status = $2,
+    resolution = $3,
+    resolved_by = $4,
+    resolved_at = NOW()
+WHERE id = $1 AND status = 'open'
+RETURNING *;
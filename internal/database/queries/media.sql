@@ -0,0 +1,50 @@
+-- name: CreateVerificationMedia :one
+INSERT INTO verification_media (
+    session_id,
+    user_id,
+    kind,
+    storage_key,
+    content_type,
+    size_bytes,
+    sha256,
+    stored_sha256
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetVerificationMedia :one
+SELECT * FROM verification_media
+WHERE id = $1 LIMIT 1;
+
+-- name: ListVerificationMediaBySessionID :many
+SELECT * FROM verification_media
+WHERE session_id = $1
+ORDER BY kind;
+
+-- name: ListVerificationMediaByUserID :many
+SELECT * FROM verification_media
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteVerificationMedia :exec
+DELETE FROM verification_media
+WHERE id = $1;
+
+-- name: CountExpiredVerificationMedia :one
+SELECT COUNT(*) FROM verification_media m
+JOIN verification_sessions s ON s.session_id = m.session_id
+WHERE s.status <> 'pending'
+  AND s.updated_at < sqlc.arg('before')::timestamptz
+  AND NOT EXISTS (SELECT 1 FROM legal_holds h WHERE h.user_id = s.user_id);
+
+-- name: ListExpiredVerificationMedia :many
+-- Media of the decided sessions the decisions retention policy is about to
+-- delete, which keeps them until their media are gone.
+SELECT * FROM verification_media
+WHERE session_id IN (
+    SELECT s.session_id FROM verification_sessions s
+    WHERE s.status <> 'pending'
+      AND s.updated_at < sqlc.arg('before')::timestamptz
+      AND NOT EXISTS (SELECT 1 FROM legal_holds h WHERE h.user_id = s.user_id)
+)
+ORDER BY created_at
+LIMIT sqlc.arg('batch_size')::int;
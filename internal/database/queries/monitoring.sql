@@ -0,0 +1,54 @@
+-- name: SetVerificationSessionExpiry :exec
+UPDATE verification_sessions
+SET
+    expires_at = sqlc.arg('expires_at')::timestamptz,
+    reminded_at = NULL,
+    updated_at = NOW()
+WHERE session_id = sqlc.arg('session_id') AND tenant_id = sqlc.arg('tenant_id');
+
+-- name: SetVerificationSessionNextReview :exec
+UPDATE verification_sessions
+SET
+    next_review_at = sqlc.arg('next_review_at')::timestamptz,
+    updated_at = NOW()
+WHERE session_id = sqlc.arg('session_id') AND tenant_id = sqlc.arg('tenant_id');
+
+-- name: ListSessionsDueForReminder :many
+SELECT * FROM verification_sessions
+WHERE status = 'approved'
+  AND erased_at IS NULL
+  AND reminded_at IS NULL
+  AND expires_at > NOW()
+  AND expires_at <= sqlc.arg('expires_before')::timestamptz
+ORDER BY expires_at
+LIMIT sqlc.arg('batch_size')::int;
+
+-- name: MarkVerificationSessionReminded :exec
+UPDATE verification_sessions
+SET reminded_at = NOW()
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListExpiredApprovedSessions :many
+SELECT * FROM verification_sessions
+WHERE status = 'approved'
+  AND expires_at <= NOW()
+ORDER BY expires_at
+LIMIT sqlc.arg('batch_size')::int;
+
+-- name: ListSessionsDueForReview :many
+SELECT * FROM verification_sessions
+WHERE status = 'approved'
+  AND next_review_at <= NOW()
+ORDER BY next_review_at
+LIMIT sqlc.arg('batch_size')::int;
+
+-- name: StartVerificationSessionReview :exec
+-- A due review gives the user until expires_at to verify again. The
+-- re-verification email doubles as the expiry reminder.
+UPDATE verification_sessions
+SET
+    next_review_at = NULL,
+    expires_at = LEAST(expires_at, sqlc.arg('expires_at')::timestamptz),
+    reminded_at = NOW(),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND tenant_id = sqlc.arg('tenant_id');
@@ -0,0 +1,20 @@
+-- name: RefillRateLimitBucket :one
+-- Refills the bucket for the time elapsed since its last use without taking
+-- a token. The upsert locks the row until the transaction ends, so the
+-- tokens read stay valid until SpendRateLimitToken.
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES (sqlc.arg('key'), sqlc.arg('burst')::float8, TRUE, NOW())
+ON CONFLICT (key) DO UPDATE SET
+    tokens = LEAST(sqlc.arg('burst')::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * sqlc.arg('rate')::float8),
+    allowed = LEAST(sqlc.arg('burst')::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * sqlc.arg('rate')::float8) >= 1,
+    updated_at = NOW()
+RETURNING tokens;
+
+-- name: SpendRateLimitToken :exec
+UPDATE rate_limit_buckets
+SET tokens = tokens - 1
+WHERE key = $1;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
@@ -0,0 +1,16 @@
+-- name: ListSessionsToReconcile :many
+-- Pending sessions opened at a provider long enough ago that a decision may
+-- have been missed, skipping those checked recently.
+SELECT * FROM verification_sessions
+WHERE status = 'pending'
+  AND didit_session_id IS NOT NULL
+  AND erased_at IS NULL
+  AND created_at < sqlc.arg('created_before')::timestamptz
+  AND (reconciled_at IS NULL OR reconciled_at < sqlc.arg('checked_before')::timestamptz)
+ORDER BY created_at
+LIMIT sqlc.arg('max_rows');
+
+-- name: MarkVerificationSessionReconciled :exec
+UPDATE verification_sessions
+SET reconciled_at = NOW()
+WHERE session_id = $1;
@@ -0,0 +1,135 @@
+-- name: CreateLegalHold :one
+INSERT INTO legal_holds (
+    user_id,
+    reason,
+    created_by
+) VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET
+    reason = EXCLUDED.reason,
+    created_by = EXCLUDED.created_by
+RETURNING *;
+
+-- name: GetLegalHold :one
+SELECT * FROM legal_holds
+WHERE user_id = $1 LIMIT 1;
+
+-- name: ListLegalHolds :many
+SELECT * FROM legal_holds
+ORDER BY created_at DESC;
+
+-- name: DeleteLegalHold :execrows
+DELETE FROM legal_holds
+WHERE user_id = $1;
+
+-- name: CountExpiredWebhookPayloads :one
+SELECT COUNT(*) FROM webhook_events w
+WHERE w.created_at < sqlc.arg('before')::timestamptz
+  AND w.redacted_at IS NULL
+  AND w.status IN ('done', 'dead')
+  AND NOT EXISTS (
+      SELECT 1 FROM legal_holds h
+      JOIN verification_sessions s ON s.user_id = h.user_id
+      WHERE s.didit_session_id = w.session_id
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM legal_holds h
+      WHERE h.user_id = w.payload->'data'->>'user_id'
+  );
+
+-- name: RedactExpiredWebhookPayloads :execrows
+-- Replaces the raw payload with the event type, session id and status, which
+-- is all the decision trail needs.
+UPDATE webhook_events
+SET
+    payload = jsonb_build_object(
+        'event_type', event_type,
+        'data', jsonb_build_object(
+            'session_id', session_id,
+            'status', payload->'data'->'status'
+        )
+    ),
+    redacted_at = NOW()
+WHERE id IN (
+    SELECT w.id FROM webhook_events w
+    WHERE w.created_at < sqlc.arg('before')::timestamptz
+      AND w.redacted_at IS NULL
+      AND w.status IN ('done', 'dead')
+      AND NOT EXISTS (
+          SELECT 1 FROM legal_holds h
+          JOIN verification_sessions s ON s.user_id = h.user_id
+          WHERE s.didit_session_id = w.session_id
+      )
+      AND NOT EXISTS (
+          SELECT 1 FROM legal_holds h
+          WHERE h.user_id = w.payload->'data'->>'user_id'
+      )
+    LIMIT sqlc.arg('batch_size')::int
+);
+
+-- name: CountAbandonedSessions :one
+SELECT COUNT(*) FROM verification_sessions s
+WHERE s.status = 'pending'
+  AND s.created_at < sqlc.arg('before')::timestamptz
+  AND NOT EXISTS (SELECT 1 FROM legal_holds h WHERE h.user_id = s.user_id);
+
+-- name: DeleteAbandonedSessions :execrows
+DELETE FROM verification_sessions
+WHERE id IN (
+    SELECT s.id FROM verification_sessions s
+    WHERE s.status = 'pending'
+      AND s.created_at < sqlc.arg('before')::timestamptz
+      AND NOT EXISTS (SELECT 1 FROM legal_holds h WHERE h.user_id = s.user_id)
+    LIMIT sqlc.arg('batch_size')::int
+);
+
+-- name: CountExpiredDecisions :one
+SELECT COUNT(*) FROM verification_sessions s
+WHERE s.status <> 'pending'
+  AND s.updated_at < sqlc.arg('before')::timestamptz
+  AND NOT EXISTS (SELECT 1 FROM legal_holds h WHERE h.user_id = s.user_id)
+  AND NOT EXISTS (SELECT 1 FROM verification_media m WHERE m.session_id = s.session_id);
+
+-- name: DeleteExpiredDecisions :execrows
+-- Deletes a batch of decided sessions together with their webhook events,
+-- which are only linked to the user through the session's Didit id. Sessions
+-- still holding media wait until the media policy deleted their files.
+WITH expired AS (
+    SELECT s.id, s.didit_session_id FROM verification_sessions s
+    WHERE s.status <> 'pending'
+      AND s.updated_at < sqlc.arg('before')::timestamptz
+      AND NOT EXISTS (SELECT 1 FROM legal_holds h WHERE h.user_id = s.user_id)
+      AND NOT EXISTS (SELECT 1 FROM verification_media m WHERE m.session_id = s.session_id)
+    LIMIT sqlc.arg('batch_size')::int
+), deleted_events AS (
+    DELETE FROM webhook_events w
+    USING expired e
+    WHERE w.session_id = e.didit_session_id
+), archived_events AS (
+    -- Their archived events are dropped on the next partition maintenance.
+    INSERT INTO webhook_archive_purges (action, session_id)
+    SELECT 'delete', e.didit_session_id FROM expired e
+    WHERE e.didit_session_id IS NOT NULL
+      AND EXISTS (SELECT 1 FROM webhook_event_archives)
+)
+DELETE FROM verification_sessions
+WHERE id IN (SELECT id FROM expired);
+
+-- name: ListLegalHoldSessionIDs :many
+-- The Didit sessions of users under legal hold, whose webhook events the
+-- payload retention keeps.
+SELECT didit_session_id FROM verification_sessions
+WHERE user_id IN (SELECT user_id FROM legal_holds)
+  AND didit_session_id IS NOT NULL;
+
+-- name: CreatePurgeRun :one
+INSERT INTO purge_runs (
+    started_at,
+    finished_at,
+    report
+) VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListPurgeRuns :many
+SELECT * FROM purge_runs
+ORDER BY started_at DESC
+LIMIT $1;
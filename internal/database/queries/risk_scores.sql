@@ -0,0 +1,26 @@
+-- name: CreateRiskScore :one
+INSERT INTO risk_scores (
+    session_id,
+    user_id,
+    score,
+    decision,
+    didit_status,
+    final_status,
+    enforced,
+    rules_version,
+    signals,
+    reasons
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: ListRiskScoresBySessionID :many
+SELECT * FROM risk_scores
+WHERE session_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListRiskScoresSince :many
+-- Scores in creation order, for exporting to rule tuning.
+SELECT * FROM risk_scores
+WHERE created_at >= sqlc.arg('since')::timestamptz
+ORDER BY created_at
+LIMIT sqlc.arg('max_rows')::int;
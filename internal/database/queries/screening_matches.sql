@@ -0,0 +1,23 @@
+-- name: CreateScreeningMatch :one
+INSERT INTO screening_matches (
+    session_id,
+    user_id,
+    list,
+    entry_id,
+    entry_name,
+    matched_name,
+    score,
+    dob_match,
+    details
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: ListScreeningMatchesBySessionID :many
+SELECT * FROM screening_matches
+WHERE session_id = $1
+ORDER BY score DESC;
+
+-- name: ListScreeningMatchesByUserID :many
+SELECT * FROM screening_matches
+WHERE user_id = $1
+ORDER BY created_at DESC;
@@ -0,0 +1,56 @@
+-- name: CreateTenant :one
+INSERT INTO tenants (
+    slug,
+    name,
+    api_key_hash,
+    didit_api_key,
+    didit_workflow_id,
+    didit_workflow_url,
+    didit_webhook_secret,
+    sender_name,
+    sender_email,
+    logo_url,
+    primary_color,
+    support_url
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING *;
+
+-- name: GetTenant :one
+SELECT * FROM tenants
+WHERE id = $1 LIMIT 1;
+
+-- name: GetTenantBySlug :one
+SELECT * FROM tenants
+WHERE slug = $1 LIMIT 1;
+
+-- name: GetTenantByAPIKeyHash :one
+SELECT * FROM tenants
+WHERE api_key_hash = $1 LIMIT 1;
+
+-- name: ListTenants :many
+SELECT * FROM tenants
+ORDER BY created_at;
+
+-- name: UpdateTenant :one
+UPDATE tenants
+SET
+    name = $2,
+    active = $3,
+    didit_api_key = $4,
+    didit_workflow_id = $5,
+    didit_workflow_url = $6,
+    didit_webhook_secret = $7,
+    sender_name = $8,
+    sender_email = $9,
+    logo_url = $10,
+    primary_color = $11,
+    support_url = $12,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetTenantAPIKeyHash :one
+UPDATE tenants
+SET api_key_hash = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -6,21 +6,34 @@ INSERT INTO verification_sessions (
     user_email,
     user_first_name,
     user_last_name,
-    status
-) VALUES ($1, $2, $3, $4, $5, $6, $7)
+    status,
+    user_email_index,
+    level,
+    base_session_id,
+    user_cpf,
+    user_cpf_index,
+    provider,
+    verification_url,
+    tenant_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 RETURNING *;
 
 -- name: GetVerificationSessionByID :one
 SELECT * FROM verification_sessions 
-WHERE id = $1 LIMIT 1;
+WHERE id = $1 AND tenant_id = $2 LIMIT 1;
 
 -- name: GetVerificationSessionBySessionID :one
 SELECT * FROM verification_sessions 
-WHERE session_id = $1 LIMIT 1;
+WHERE session_id = $1 AND tenant_id = $2 LIMIT 1;
+
+-- name: LockVerificationSession :one
+SELECT * FROM verification_sessions
+WHERE session_id = $1 AND tenant_id = $2
+FOR UPDATE;
 
 -- name: GetVerificationSessionByDiditSessionID :one
 SELECT * FROM verification_sessions 
-WHERE didit_session_id = $1 LIMIT 1;
+WHERE didit_session_id = $1 AND tenant_id = $2 LIMIT 1;
 
 -- name: UpdateVerificationSessionStatus :one
 UPDATE verification_sessions 
@@ -31,7 +44,7 @@ SET
         WHEN $2 IN ('approved', 'rejected', 'failed') THEN NOW() 
         ELSE completed_at 
     END
-WHERE session_id = $1
+WHERE session_id = $1 AND tenant_id = $3
 RETURNING *;
 
 -- name: UpdateDiditSessionID :one
@@ -39,28 +52,146 @@ UPDATE verification_sessions
 SET 
     didit_session_id = $2,
     updated_at = NOW()
-WHERE session_id = $1
+WHERE session_id = $1 AND tenant_id = $3
 RETURNING *;
 
 -- name: ListVerificationSessionsByUserID :many
 SELECT * FROM verification_sessions 
-WHERE user_id = $1 
+WHERE user_id = $1 AND tenant_id = $2
 ORDER BY created_at DESC;
 
 -- name: ListVerificationSessionsByStatus :many
 SELECT * FROM verification_sessions 
-WHERE status = $1 
+WHERE status = $1 AND tenant_id = $2
 ORDER BY created_at DESC;
 
 -- name: CreateWebhookEvent :one
 INSERT INTO webhook_events (
     event_type,
     session_id,
-    payload
-) VALUES ($1, $2, $3)
+    payload,
+    provider,
+    tenant_id
+) VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetWebhookEventsBySessionID :many
 SELECT * FROM webhook_events 
-WHERE session_id = $1 
-ORDER BY created_at DESC;
\ No newline at end of file
+WHERE session_id = $1 AND tenant_id = $2
+ORDER BY created_at DESC;
+-- name: ListWebhookEventsForReplay :many
+SELECT * FROM webhook_events
+WHERE tenant_id = sqlc.arg('tenant_id')
+  AND (sqlc.narg('session_id')::text IS NULL OR session_id = sqlc.narg('session_id')::text)
+  AND (sqlc.narg('since')::timestamptz IS NULL OR created_at >= sqlc.narg('since')::timestamptz)
+  AND (NOT sqlc.arg('only_unprocessed')::boolean OR processed IS NOT TRUE)
+ORDER BY created_at ASC;
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET
+    processed = TRUE,
+    status = 'done',
+    processed_at = NOW(),
+    locked_at = NULL,
+    last_error = NULL
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetLatestPendingSessionByUserID :one
+SELECT * FROM verification_sessions
+WHERE tenant_id = sqlc.arg('tenant_id')
+  AND user_id = sqlc.arg('user_id')
+  AND level = sqlc.arg('level')
+  AND status = 'pending'
+  AND created_at >= sqlc.arg('created_after')::timestamptz
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CountVerificationSessionsByUserIDSince :one
+SELECT COUNT(*) FROM verification_sessions
+WHERE tenant_id = sqlc.arg('tenant_id')
+  AND user_id = sqlc.arg('user_id')
+  AND created_at >= sqlc.arg('created_after')::timestamptz;
+
+-- name: LockUserVerificationSessions :exec
+-- Serializes the starts of a user until the transaction ends.
+SELECT pg_advisory_xact_lock(hashtextextended(sqlc.arg('tenant_id')::text || ':' || sqlc.arg('user_id')::text, 0));
+
+-- name: ListWebhookEventsByUserID :many
+-- Webhook events are keyed by the Didit session id, so they are found through
+-- the user's sessions or the user id Didit echoes back in the payload.
+SELECT * FROM webhook_events
+WHERE tenant_id = sqlc.arg('tenant_id')
+  AND (
+    session_id IN (
+        SELECT didit_session_id FROM verification_sessions
+        WHERE tenant_id = sqlc.arg('tenant_id')
+          AND user_id = sqlc.arg('user_id')::text
+          AND didit_session_id IS NOT NULL
+    )
+    OR payload->'data'->>'user_id' = sqlc.arg('user_id')::text
+  )
+ORDER BY created_at ASC;
+
+-- name: UpdateWebhookEventPayload :exec
+UPDATE webhook_events
+SET payload = $2
+WHERE id = $1 AND tenant_id = $3;
+
+-- name: EraseVerificationSessionsByUserID :execrows
+UPDATE verification_sessions
+SET
+    user_email = '',
+    user_email_index = NULL,
+    user_first_name = NULL,
+    user_last_name = NULL,
+    metadata = NULL,
+    identity_fingerprint = NULL,
+    user_cpf = NULL,
+    user_cpf_index = NULL,
+    verification_url = NULL,
+    erased_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND tenant_id = $2 AND erased_at IS NULL;
+
+-- name: ListVerificationSessionsByEmailIndex :many
+SELECT * FROM verification_sessions
+WHERE user_email_index = $1 AND tenant_id = $2
+ORDER BY created_at DESC;
+
+-- name: ListVerificationSessionsAfterID :many
+SELECT * FROM verification_sessions
+WHERE id > $1 AND tenant_id = $3
+ORDER BY id
+LIMIT $2;
+
+-- name: UpdateVerificationSessionPII :exec
+UPDATE verification_sessions
+SET
+    user_email = $2,
+    user_first_name = $3,
+    user_last_name = $4,
+    user_email_index = $5,
+    user_cpf = $6,
+    user_cpf_index = $7,
+    verification_url = $8
+WHERE id = $1 AND tenant_id = $9;
+
+-- name: ListWebhookEventsAfterID :many
+SELECT * FROM webhook_events
+WHERE id > $1 AND tenant_id = $3
+ORDER BY id
+LIMIT $2;
+
+-- name: GetLatestTerminalSessionByUserID :one
+SELECT * FROM verification_sessions
+WHERE user_id = $1 AND tenant_id = $2
+  AND status IN ('approved', 'rejected', 'failed', 'expired')
+ORDER BY updated_at DESC
+LIMIT 1;
+
+-- name: ListApprovedSessionsByUserID :many
+SELECT * FROM verification_sessions
+WHERE user_id = $1 AND tenant_id = $2
+  AND status = 'approved'
+ORDER BY completed_at DESC NULLS LAST;
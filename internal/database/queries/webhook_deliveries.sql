@@ -0,0 +1,103 @@
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+    subscription_id,
+    event_id,
+    event_type,
+    payload
+) VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = sqlc.arg('id')
+  AND subscription_id IN (
+    SELECT id FROM webhook_subscriptions
+    WHERE tenant_id = sqlc.arg('tenant_id')
+  )
+LIMIT 1;
+
+-- name: ListWebhookDeliveriesBySubscription :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+  AND subscription_id IN (
+    SELECT id FROM webhook_subscriptions
+    WHERE tenant_id = $3
+  )
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ClaimNextWebhookDelivery :one
+UPDATE webhook_deliveries
+SET
+    status = 'delivering',
+    attempts = attempts + 1,
+    locked_at = NOW(),
+    updated_at = NOW()
+WHERE id = (
+    SELECT d.id FROM webhook_deliveries d
+    JOIN webhook_subscriptions s ON s.id = d.subscription_id
+    WHERE s.active = TRUE
+      AND (
+        (d.status = 'pending' AND d.next_attempt_at <= NOW())
+        OR (d.status = 'delivering' AND d.locked_at < sqlc.arg('stale_before')::timestamptz)
+      )
+    ORDER BY d.next_attempt_at
+    LIMIT 1
+    FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET
+    status = 'succeeded',
+    last_status_code = $2,
+    last_error = NULL,
+    locked_at = NULL,
+    delivered_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET
+    status = 'pending',
+    next_attempt_at = $2,
+    last_status_code = $3,
+    last_error = $4,
+    locked_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET
+    status = 'failed',
+    last_status_code = $2,
+    last_error = $3,
+    locked_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (
+    delivery_id,
+    attempt,
+    status_code,
+    error,
+    duration_ms
+) VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY attempt;
+
+-- name: ListWebhookDeliveriesByUserID :many
+SELECT * FROM webhook_deliveries
+WHERE payload->'data'->>'user_id' = sqlc.arg('user_id')::text
+  AND subscription_id IN (
+    SELECT id FROM webhook_subscriptions
+    WHERE tenant_id = sqlc.arg('tenant_id')
+  )
+ORDER BY created_at ASC;
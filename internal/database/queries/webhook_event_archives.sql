@@ -0,0 +1,69 @@
+-- name: CreateWebhookEventArchive :exec
+INSERT INTO webhook_event_archives (partition_name)
+VALUES ($1);
+
+-- name: GetWebhookEventArchive :one
+SELECT * FROM webhook_event_archives
+WHERE partition_name = $1 LIMIT 1;
+
+-- name: GetWebhookEventArchiveByKey :one
+SELECT * FROM webhook_event_archives
+WHERE archive_key = $1 LIMIT 1;
+
+-- name: ListDetachedWebhookEventArchives :many
+SELECT * FROM webhook_event_archives
+WHERE status = 'detached'
+ORDER BY partition_name;
+
+-- name: ListArchivedWebhookEventArchives :many
+SELECT * FROM webhook_event_archives
+WHERE status = 'archived'
+ORDER BY partition_name;
+
+-- name: MarkWebhookEventArchived :exec
+UPDATE webhook_event_archives
+SET
+    status = 'archived',
+    archive_key = $2,
+    event_count = $3,
+    size_bytes = $4,
+    sha256 = $5,
+    archived_at = NOW()
+WHERE partition_name = $1;
+
+-- name: UpdateWebhookEventArchive :exec
+UPDATE webhook_event_archives
+SET
+    archive_key = $2,
+    event_count = $3,
+    size_bytes = $4,
+    sha256 = $5,
+    redacted_until = $6,
+    held_events = $7
+WHERE partition_name = $1;
+
+-- name: ResetWebhookEventArchiveRedaction :exec
+-- Archives keeping events of users under legal hold go through the payload
+-- retention again once a hold is lifted.
+UPDATE webhook_event_archives
+SET redacted_until = NULL
+WHERE held_events > 0;
+
+-- name: CreateWebhookArchivePurge :exec
+-- Nothing is queued while no partition was ever archived.
+INSERT INTO webhook_archive_purges (
+    action,
+    tenant_id,
+    user_id,
+    session_id
+)
+SELECT sqlc.arg('action')::text, sqlc.narg('tenant_id')::uuid, sqlc.narg('user_id')::text, sqlc.narg('session_id')::text
+WHERE EXISTS (SELECT 1 FROM webhook_event_archives);
+
+-- name: ListWebhookArchivePurges :many
+SELECT * FROM webhook_archive_purges
+ORDER BY id;
+
+-- name: DeleteWebhookArchivePurges :exec
+DELETE FROM webhook_archive_purges
+WHERE id <= $1;
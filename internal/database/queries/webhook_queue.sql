@@ -0,0 +1,71 @@
+-- name: ClaimNextWebhookEvent :one
+UPDATE webhook_events
+SET
+    status = 'processing',
+    attempts = attempts + 1,
+    locked_at = NOW()
+WHERE id = (
+    SELECT e.id FROM webhook_events e
+    WHERE (
+        (e.status = 'pending' AND e.next_attempt_at <= NOW())
+        OR (e.status = 'processing' AND e.locked_at < sqlc.arg('stale_before')::timestamptz)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM webhook_events p
+        WHERE p.session_id = e.session_id
+          AND p.status IN ('pending', 'processing')
+          AND (p.created_at, p.id) < (e.created_at, e.id)
+    )
+    ORDER BY e.created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteWebhookEvent :exec
+UPDATE webhook_events
+SET
+    status = 'done',
+    processed = TRUE,
+    processed_at = NOW(),
+    locked_at = NULL,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: RetryWebhookEvent :exec
+UPDATE webhook_events
+SET
+    status = 'pending',
+    next_attempt_at = $2,
+    last_error = $3,
+    locked_at = NULL
+WHERE id = $1;
+
+-- name: DeadLetterWebhookEvent :exec
+UPDATE webhook_events
+SET
+    status = 'dead',
+    last_error = $2,
+    locked_at = NULL
+WHERE id = $1;
+
+-- name: ListDeadWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE status = 'dead'
+ORDER BY created_at DESC
+LIMIT $1;
+
+-- name: RequeueDeadWebhookEvent :one
+UPDATE webhook_events
+SET
+    status = 'pending',
+    attempts = 0,
+    next_attempt_at = NOW(),
+    last_error = NULL
+WHERE id = $1 AND status = 'dead'
+RETURNING *;
+
+-- name: CountWebhookEventsByStatus :many
+SELECT status, COUNT(*) AS total FROM webhook_events
+GROUP BY status
+ORDER BY status;
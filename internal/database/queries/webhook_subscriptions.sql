@@ -0,0 +1,65 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+    url,
+    secret,
+    event_types,
+    description,
+    tenant_id
+) VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 AND tenant_id = $2 LIMIT 1;
+
+-- name: GetDeliveryWebhookSubscription :one
+-- The delivery worker serves every tenant.
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE tenant_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListActiveWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE tenant_id = $1 AND active = TRUE
+ORDER BY created_at;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: EnableWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET
+    active = TRUE,
+    consecutive_failures = 0,
+    disabled_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: ResetWebhookSubscriptionFailures :exec
+UPDATE webhook_subscriptions
+SET
+    consecutive_failures = 0,
+    updated_at = NOW()
+WHERE id = $1 AND consecutive_failures > 0;
+
+-- name: RecordWebhookSubscriptionFailure :one
+UPDATE webhook_subscriptions
+SET
+    consecutive_failures = consecutive_failures + 1,
+    active = CASE
+        WHEN consecutive_failures + 1 >= sqlc.arg('disable_after')::int THEN FALSE
+        ELSE active
+    END,
+    disabled_at = CASE
+        WHEN active AND consecutive_failures + 1 >= sqlc.arg('disable_after')::int THEN NOW()
+        ELSE disabled_at
+    END,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
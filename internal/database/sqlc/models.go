@@ -5,34 +5,261 @@
 package sqlc
 
 import (
-	"database/sql"
 	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/sqlc-dev/pqtype"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AuditLog struct {
+	ID            uuid.UUID
+	Action        string
+	SubjectUserID string
+	Actor         string
+	Details       json.RawMessage
+	CreatedAt     time.Time
+	Seq           int64
+	EntityType    string
+	EntityID      string
+	PrevHash      string
+	Hash          string
+}
+
+type Company struct {
+	ID                uuid.UUID
+	Cnpj              string
+	LegalName         string
+	TradeName         pgtype.Text
+	RegisteredAddress json.RawMessage
+	OwnerUserID       string
+	Status            string
+	ApprovedAt        pgtype.Timestamptz
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type CompanyPeople struct {
+	ID               uuid.UUID
+	CompanyID        uuid.UUID
+	Role             string
+	UserID           string
+	OwnershipPercent pgtype.Float8
+	Required         bool
+	SessionID        pgtype.Text
+	CreatedAt        time.Time
+}
+
+type IdentityLink struct {
+	ID              uuid.UUID
+	SessionID       string
+	UserID          string
+	LinkedSessionID string
+	LinkedUserID    string
+	Action          string
+	Status          string
+	Resolution      pgtype.Text
+	ResolvedBy      pgtype.Text
+	ResolvedAt      pgtype.Timestamptz
+	CreatedAt       time.Time
+}
+
+type LegalHold struct {
+	UserID    string
+	Reason    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+type PurgeRun struct {
+	ID         uuid.UUID
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Report     json.RawMessage
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt time.Time
+}
+
+type RiskScore struct {
+	ID           uuid.UUID
+	SessionID    string
+	UserID       string
+	Score        float64
+	Decision     string
+	DiditStatus  string
+	FinalStatus  string
+	Enforced     bool
+	RulesVersion pgtype.Text
+	Signals      json.RawMessage
+	Reasons      json.RawMessage
+	CreatedAt    time.Time
+}
+
+type ScreeningMatch struct {
+	ID          uuid.UUID
+	SessionID   string
+	UserID      string
+	List        string
+	EntryID     string
+	EntryName   string
+	MatchedName string
+	Score       float64
+	DobMatch    pgtype.Bool
+	Details     json.RawMessage
+	CreatedAt   time.Time
+}
+
+type Tenant struct {
+	ID                 uuid.UUID
+	Slug               string
+	Name               string
+	ApiKeyHash         pgtype.Text
+	Active             bool
+	DiditApiKey        pgtype.Text
+	DiditWorkflowID    pgtype.Text
+	DiditWorkflowUrl   pgtype.Text
+	DiditWebhookSecret pgtype.Text
+	SenderName         pgtype.Text
+	SenderEmail        pgtype.Text
+	LogoUrl            pgtype.Text
+	PrimaryColor       pgtype.Text
+	SupportUrl         pgtype.Text
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+type UserCpf struct {
+	CpfIndex  string
+	UserID    string
+	CreatedAt time.Time
+	TenantID  uuid.UUID
+}
+
+type VerificationMedia struct {
+	ID           uuid.UUID
+	SessionID    string
+	UserID       string
+	Kind         string
+	StorageKey   string
+	ContentType  string
+	SizeBytes    int64
+	Sha256       string
+	StoredSha256 string
+	CreatedAt    time.Time
+}
+
 type VerificationSession struct {
+	ID                  uuid.UUID
+	UserID              string
+	SessionID           string
+	Status              string
+	DiditSessionID      pgtype.Text
+	UserEmail           string
+	UserFirstName       pgtype.Text
+	UserLastName        pgtype.Text
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	CompletedAt         pgtype.Timestamptz
+	Metadata            []byte
+	ErasedAt            pgtype.Timestamptz
+	UserEmailIndex      pgtype.Text
+	Level               string
+	BaseSessionID       pgtype.Text
+	ExpiresAt           pgtype.Timestamptz
+	NextReviewAt        pgtype.Timestamptz
+	RemindedAt          pgtype.Timestamptz
+	IdentityFingerprint pgtype.Text
+	UserCpf             pgtype.Text
+	UserCpfIndex        pgtype.Text
+	CpfMatch            pgtype.Bool
+	Provider            string
+	VerificationUrl     pgtype.Text
+	ReconciledAt        pgtype.Timestamptz
+	TenantID            uuid.UUID
+}
+
+type WebhookArchivePurge struct {
+	ID        int64
+	Action    string
+	TenantID  uuid.NullUUID
+	UserID    pgtype.Text
+	SessionID pgtype.Text
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
 	ID             uuid.UUID
-	UserID         string
-	SessionID      string
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        json.RawMessage
 	Status         string
-	DiditSessionID sql.NullString
-	UserEmail      string
-	UserFirstName  sql.NullString
-	UserLastName   sql.NullString
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LockedAt       pgtype.Timestamptz
+	LastStatusCode pgtype.Int4
+	LastError      pgtype.Text
+	DeliveredAt    pgtype.Timestamptz
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	CompletedAt    sql.NullTime
-	Metadata       pqtype.NullRawMessage
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         uuid.UUID
+	DeliveryID uuid.UUID
+	Attempt    int32
+	StatusCode pgtype.Int4
+	Error      pgtype.Text
+	DurationMs int32
+	CreatedAt  time.Time
 }
 
 type WebhookEvent struct {
-	ID        uuid.UUID
-	EventType string
-	SessionID string
-	Payload   json.RawMessage
-	Processed sql.NullBool
-	CreatedAt time.Time
+	ID            uuid.UUID
+	EventType     string
+	SessionID     string
+	Payload       json.RawMessage
+	Processed     pgtype.Bool
+	CreatedAt     time.Time
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LockedAt      pgtype.Timestamptz
+	LastError     pgtype.Text
+	ProcessedAt   pgtype.Timestamptz
+	RedactedAt    pgtype.Timestamptz
+	Provider      string
+	TenantID      uuid.UUID
+}
+
+type WebhookEventArchive struct {
+	PartitionName string
+	Status        string
+	ArchiveKey    pgtype.Text
+	EventCount    pgtype.Int4
+	SizeBytes     pgtype.Int8
+	Sha256        pgtype.Text
+	RedactedUntil pgtype.Timestamptz
+	HeldEvents    int32
+	DetachedAt    time.Time
+	ArchivedAt    pgtype.Timestamptz
+}
+
+type WebhookSubscription struct {
+	ID                  uuid.UUID
+	Url                 string
+	Secret              string
+	EventTypes          json.RawMessage
+	Description         pgtype.Text
+	Active              bool
+	ConsecutiveFailures int32
+	DisabledAt          pgtype.Timestamptz
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	TenantID            uuid.UUID
 }
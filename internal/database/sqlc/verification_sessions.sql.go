@@ -7,12 +7,33 @@ package sqlc
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countVerificationSessionsByUserIDSince = `-- name: CountVerificationSessionsByUserIDSince :one
+SELECT COUNT(*) FROM verification_sessions
+WHERE tenant_id = $1
+  AND user_id = $2
+  AND created_at >= $3::timestamptz
+`
+
+type CountVerificationSessionsByUserIDSinceParams struct {
+	TenantID     uuid.UUID
+	UserID       string
+	CreatedAfter time.Time
+}
+
+func (q *Queries) CountVerificationSessionsByUserIDSince(ctx context.Context, arg CountVerificationSessionsByUserIDSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countVerificationSessionsByUserIDSince, arg.TenantID, arg.UserID, arg.CreatedAfter)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createVerificationSession = `-- name: CreateVerificationSession :one
 INSERT INTO verification_sessions (
     user_id,
@@ -21,23 +42,39 @@ INSERT INTO verification_sessions (
     user_email,
     user_first_name,
     user_last_name,
-    status
-) VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata
+    status,
+    user_email_index,
+    level,
+    base_session_id,
+    user_cpf,
+    user_cpf_index,
+    provider,
+    verification_url,
+    tenant_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+RETURNING id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id
 `
 
 type CreateVerificationSessionParams struct {
-	UserID         string
-	SessionID      string
-	DiditSessionID sql.NullString
-	UserEmail      string
-	UserFirstName  sql.NullString
-	UserLastName   sql.NullString
-	Status         string
+	UserID          string
+	SessionID       string
+	DiditSessionID  pgtype.Text
+	UserEmail       string
+	UserFirstName   pgtype.Text
+	UserLastName    pgtype.Text
+	Status          string
+	UserEmailIndex  pgtype.Text
+	Level           string
+	BaseSessionID   pgtype.Text
+	UserCpf         pgtype.Text
+	UserCpfIndex    pgtype.Text
+	Provider        string
+	VerificationUrl pgtype.Text
+	TenantID        uuid.UUID
 }
 
 func (q *Queries) CreateVerificationSession(ctx context.Context, arg CreateVerificationSessionParams) (VerificationSession, error) {
-	row := q.db.QueryRowContext(ctx, createVerificationSession,
+	row := q.db.QueryRow(ctx, createVerificationSession,
 		arg.UserID,
 		arg.SessionID,
 		arg.DiditSessionID,
@@ -45,6 +82,14 @@ func (q *Queries) CreateVerificationSession(ctx context.Context, arg CreateVerif
 		arg.UserFirstName,
 		arg.UserLastName,
 		arg.Status,
+		arg.UserEmailIndex,
+		arg.Level,
+		arg.BaseSessionID,
+		arg.UserCpf,
+		arg.UserCpfIndex,
+		arg.Provider,
+		arg.VerificationUrl,
+		arg.TenantID,
 	)
 	var i VerificationSession
 	err := row.Scan(
@@ -60,6 +105,21 @@ func (q *Queries) CreateVerificationSession(ctx context.Context, arg CreateVerif
 		&i.UpdatedAt,
 		&i.CompletedAt,
 		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
 	)
 	return i, err
 }
@@ -68,19 +128,29 @@ const createWebhookEvent = `-- name: CreateWebhookEvent :one
 INSERT INTO webhook_events (
     event_type,
     session_id,
-    payload
-) VALUES ($1, $2, $3)
-RETURNING id, event_type, session_id, payload, processed, created_at
+    payload,
+    provider,
+    tenant_id
+) VALUES ($1, $2, $3, $4, $5)
+RETURNING id, event_type, session_id, payload, processed, created_at, status, attempts, next_attempt_at, locked_at, last_error, processed_at, redacted_at, provider, tenant_id
 `
 
 type CreateWebhookEventParams struct {
 	EventType string
 	SessionID string
 	Payload   json.RawMessage
+	Provider  string
+	TenantID  uuid.UUID
 }
 
 func (q *Queries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (WebhookEvent, error) {
-	row := q.db.QueryRowContext(ctx, createWebhookEvent, arg.EventType, arg.SessionID, arg.Payload)
+	row := q.db.QueryRow(ctx, createWebhookEvent,
+		arg.EventType,
+		arg.SessionID,
+		arg.Payload,
+		arg.Provider,
+		arg.TenantID,
+	)
 	var i WebhookEvent
 	err := row.Scan(
 		&i.ID,
@@ -89,17 +159,167 @@ func (q *Queries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEvent
 		&i.Payload,
 		&i.Processed,
 		&i.CreatedAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LockedAt,
+		&i.LastError,
+		&i.ProcessedAt,
+		&i.RedactedAt,
+		&i.Provider,
+		&i.TenantID,
+	)
+	return i, err
+}
+
+const eraseVerificationSessionsByUserID = `-- name: EraseVerificationSessionsByUserID :execrows
+UPDATE verification_sessions
+SET
+    user_email = '',
+    user_email_index = NULL,
+    user_first_name = NULL,
+    user_last_name = NULL,
+    metadata = NULL,
+    identity_fingerprint = NULL,
+    user_cpf = NULL,
+    user_cpf_index = NULL,
+    verification_url = NULL,
+    erased_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND tenant_id = $2 AND erased_at IS NULL
+`
+
+type EraseVerificationSessionsByUserIDParams struct {
+	UserID   string
+	TenantID uuid.UUID
+}
+
+func (q *Queries) EraseVerificationSessionsByUserID(ctx context.Context, arg EraseVerificationSessionsByUserIDParams) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseVerificationSessionsByUserID, arg.UserID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getLatestPendingSessionByUserID = `-- name: GetLatestPendingSessionByUserID :one
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions
+WHERE tenant_id = $1
+  AND user_id = $2
+  AND level = $3
+  AND status = 'pending'
+  AND created_at >= $4::timestamptz
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetLatestPendingSessionByUserIDParams struct {
+	TenantID     uuid.UUID
+	UserID       string
+	Level        string
+	CreatedAfter time.Time
+}
+
+func (q *Queries) GetLatestPendingSessionByUserID(ctx context.Context, arg GetLatestPendingSessionByUserIDParams) (VerificationSession, error) {
+	row := q.db.QueryRow(ctx, getLatestPendingSessionByUserID,
+		arg.TenantID,
+		arg.UserID,
+		arg.Level,
+		arg.CreatedAfter,
+	)
+	var i VerificationSession
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SessionID,
+		&i.Status,
+		&i.DiditSessionID,
+		&i.UserEmail,
+		&i.UserFirstName,
+		&i.UserLastName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
+	)
+	return i, err
+}
+
+const getLatestTerminalSessionByUserID = `-- name: GetLatestTerminalSessionByUserID :one
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions
+WHERE user_id = $1 AND tenant_id = $2
+  AND status IN ('approved', 'rejected', 'failed', 'expired')
+ORDER BY updated_at DESC
+LIMIT 1
+`
+
+type GetLatestTerminalSessionByUserIDParams struct {
+	UserID   string
+	TenantID uuid.UUID
+}
+
+func (q *Queries) GetLatestTerminalSessionByUserID(ctx context.Context, arg GetLatestTerminalSessionByUserIDParams) (VerificationSession, error) {
+	row := q.db.QueryRow(ctx, getLatestTerminalSessionByUserID, arg.UserID, arg.TenantID)
+	var i VerificationSession
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SessionID,
+		&i.Status,
+		&i.DiditSessionID,
+		&i.UserEmail,
+		&i.UserFirstName,
+		&i.UserLastName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
 const getVerificationSessionByDiditSessionID = `-- name: GetVerificationSessionByDiditSessionID :one
-SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata FROM verification_sessions 
-WHERE didit_session_id = $1 LIMIT 1
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions 
+WHERE didit_session_id = $1 AND tenant_id = $2 LIMIT 1
 `
 
-func (q *Queries) GetVerificationSessionByDiditSessionID(ctx context.Context, diditSessionID sql.NullString) (VerificationSession, error) {
-	row := q.db.QueryRowContext(ctx, getVerificationSessionByDiditSessionID, diditSessionID)
+type GetVerificationSessionByDiditSessionIDParams struct {
+	DiditSessionID pgtype.Text
+	TenantID       uuid.UUID
+}
+
+func (q *Queries) GetVerificationSessionByDiditSessionID(ctx context.Context, arg GetVerificationSessionByDiditSessionIDParams) (VerificationSession, error) {
+	row := q.db.QueryRow(ctx, getVerificationSessionByDiditSessionID, arg.DiditSessionID, arg.TenantID)
 	var i VerificationSession
 	err := row.Scan(
 		&i.ID,
@@ -114,17 +334,37 @@ func (q *Queries) GetVerificationSessionByDiditSessionID(ctx context.Context, di
 		&i.UpdatedAt,
 		&i.CompletedAt,
 		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
 const getVerificationSessionByID = `-- name: GetVerificationSessionByID :one
-SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata FROM verification_sessions 
-WHERE id = $1 LIMIT 1
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions 
+WHERE id = $1 AND tenant_id = $2 LIMIT 1
 `
 
-func (q *Queries) GetVerificationSessionByID(ctx context.Context, id uuid.UUID) (VerificationSession, error) {
-	row := q.db.QueryRowContext(ctx, getVerificationSessionByID, id)
+type GetVerificationSessionByIDParams struct {
+	ID       uuid.UUID
+	TenantID uuid.UUID
+}
+
+func (q *Queries) GetVerificationSessionByID(ctx context.Context, arg GetVerificationSessionByIDParams) (VerificationSession, error) {
+	row := q.db.QueryRow(ctx, getVerificationSessionByID, arg.ID, arg.TenantID)
 	var i VerificationSession
 	err := row.Scan(
 		&i.ID,
@@ -139,17 +379,37 @@ func (q *Queries) GetVerificationSessionByID(ctx context.Context, id uuid.UUID)
 		&i.UpdatedAt,
 		&i.CompletedAt,
 		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
 const getVerificationSessionBySessionID = `-- name: GetVerificationSessionBySessionID :one
-SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata FROM verification_sessions 
-WHERE session_id = $1 LIMIT 1
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions 
+WHERE session_id = $1 AND tenant_id = $2 LIMIT 1
 `
 
-func (q *Queries) GetVerificationSessionBySessionID(ctx context.Context, sessionID string) (VerificationSession, error) {
-	row := q.db.QueryRowContext(ctx, getVerificationSessionBySessionID, sessionID)
+type GetVerificationSessionBySessionIDParams struct {
+	SessionID string
+	TenantID  uuid.UUID
+}
+
+func (q *Queries) GetVerificationSessionBySessionID(ctx context.Context, arg GetVerificationSessionBySessionIDParams) (VerificationSession, error) {
+	row := q.db.QueryRow(ctx, getVerificationSessionBySessionID, arg.SessionID, arg.TenantID)
 	var i VerificationSession
 	err := row.Scan(
 		&i.ID,
@@ -164,18 +424,38 @@ func (q *Queries) GetVerificationSessionBySessionID(ctx context.Context, session
 		&i.UpdatedAt,
 		&i.CompletedAt,
 		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
 const getWebhookEventsBySessionID = `-- name: GetWebhookEventsBySessionID :many
-SELECT id, event_type, session_id, payload, processed, created_at FROM webhook_events 
-WHERE session_id = $1 
+SELECT id, event_type, session_id, payload, processed, created_at, status, attempts, next_attempt_at, locked_at, last_error, processed_at, redacted_at, provider, tenant_id FROM webhook_events 
+WHERE session_id = $1 AND tenant_id = $2
 ORDER BY created_at DESC
 `
 
-func (q *Queries) GetWebhookEventsBySessionID(ctx context.Context, sessionID string) ([]WebhookEvent, error) {
-	rows, err := q.db.QueryContext(ctx, getWebhookEventsBySessionID, sessionID)
+type GetWebhookEventsBySessionIDParams struct {
+	SessionID string
+	TenantID  uuid.UUID
+}
+
+func (q *Queries) GetWebhookEventsBySessionID(ctx context.Context, arg GetWebhookEventsBySessionIDParams) ([]WebhookEvent, error) {
+	rows, err := q.db.Query(ctx, getWebhookEventsBySessionID, arg.SessionID, arg.TenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -190,28 +470,160 @@ func (q *Queries) GetWebhookEventsBySessionID(ctx context.Context, sessionID str
 			&i.Payload,
 			&i.Processed,
 			&i.CreatedAt,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LockedAt,
+			&i.LastError,
+			&i.ProcessedAt,
+			&i.RedactedAt,
+			&i.Provider,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return items, nil
+}
+
+const listApprovedSessionsByUserID = `-- name: ListApprovedSessionsByUserID :many
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions
+WHERE user_id = $1 AND tenant_id = $2
+  AND status = 'approved'
+ORDER BY completed_at DESC NULLS LAST
+`
+
+type ListApprovedSessionsByUserIDParams struct {
+	UserID   string
+	TenantID uuid.UUID
+}
+
+func (q *Queries) ListApprovedSessionsByUserID(ctx context.Context, arg ListApprovedSessionsByUserIDParams) ([]VerificationSession, error) {
+	rows, err := q.db.Query(ctx, listApprovedSessionsByUserID, arg.UserID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []VerificationSession
+	for rows.Next() {
+		var i VerificationSession
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SessionID,
+			&i.Status,
+			&i.DiditSessionID,
+			&i.UserEmail,
+			&i.UserFirstName,
+			&i.UserLastName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+			&i.Metadata,
+			&i.ErasedAt,
+			&i.UserEmailIndex,
+			&i.Level,
+			&i.BaseSessionID,
+			&i.ExpiresAt,
+			&i.NextReviewAt,
+			&i.RemindedAt,
+			&i.IdentityFingerprint,
+			&i.UserCpf,
+			&i.UserCpfIndex,
+			&i.CpfMatch,
+			&i.Provider,
+			&i.VerificationUrl,
+			&i.ReconciledAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
-const listVerificationSessionsByStatus = `-- name: ListVerificationSessionsByStatus :many
-SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata FROM verification_sessions 
-WHERE status = $1 
+const listVerificationSessionsAfterID = `-- name: ListVerificationSessionsAfterID :many
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions
+WHERE id > $1 AND tenant_id = $3
+ORDER BY id
+LIMIT $2
+`
+
+type ListVerificationSessionsAfterIDParams struct {
+	ID       uuid.UUID
+	Limit    int32
+	TenantID uuid.UUID
+}
+
+func (q *Queries) ListVerificationSessionsAfterID(ctx context.Context, arg ListVerificationSessionsAfterIDParams) ([]VerificationSession, error) {
+	rows, err := q.db.Query(ctx, listVerificationSessionsAfterID, arg.ID, arg.Limit, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []VerificationSession
+	for rows.Next() {
+		var i VerificationSession
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SessionID,
+			&i.Status,
+			&i.DiditSessionID,
+			&i.UserEmail,
+			&i.UserFirstName,
+			&i.UserLastName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+			&i.Metadata,
+			&i.ErasedAt,
+			&i.UserEmailIndex,
+			&i.Level,
+			&i.BaseSessionID,
+			&i.ExpiresAt,
+			&i.NextReviewAt,
+			&i.RemindedAt,
+			&i.IdentityFingerprint,
+			&i.UserCpf,
+			&i.UserCpfIndex,
+			&i.CpfMatch,
+			&i.Provider,
+			&i.VerificationUrl,
+			&i.ReconciledAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVerificationSessionsByEmailIndex = `-- name: ListVerificationSessionsByEmailIndex :many
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions
+WHERE user_email_index = $1 AND tenant_id = $2
 ORDER BY created_at DESC
 `
 
-func (q *Queries) ListVerificationSessionsByStatus(ctx context.Context, status string) ([]VerificationSession, error) {
-	rows, err := q.db.QueryContext(ctx, listVerificationSessionsByStatus, status)
+type ListVerificationSessionsByEmailIndexParams struct {
+	UserEmailIndex pgtype.Text
+	TenantID       uuid.UUID
+}
+
+func (q *Queries) ListVerificationSessionsByEmailIndex(ctx context.Context, arg ListVerificationSessionsByEmailIndexParams) ([]VerificationSession, error) {
+	rows, err := q.db.Query(ctx, listVerificationSessionsByEmailIndex, arg.UserEmailIndex, arg.TenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -232,14 +644,85 @@ func (q *Queries) ListVerificationSessionsByStatus(ctx context.Context, status s
 			&i.UpdatedAt,
 			&i.CompletedAt,
 			&i.Metadata,
+			&i.ErasedAt,
+			&i.UserEmailIndex,
+			&i.Level,
+			&i.BaseSessionID,
+			&i.ExpiresAt,
+			&i.NextReviewAt,
+			&i.RemindedAt,
+			&i.IdentityFingerprint,
+			&i.UserCpf,
+			&i.UserCpfIndex,
+			&i.CpfMatch,
+			&i.Provider,
+			&i.VerificationUrl,
+			&i.ReconciledAt,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVerificationSessionsByStatus = `-- name: ListVerificationSessionsByStatus :many
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions 
+WHERE status = $1 AND tenant_id = $2
+ORDER BY created_at DESC
+`
+
+type ListVerificationSessionsByStatusParams struct {
+	Status   string
+	TenantID uuid.UUID
+}
+
+func (q *Queries) ListVerificationSessionsByStatus(ctx context.Context, arg ListVerificationSessionsByStatusParams) ([]VerificationSession, error) {
+	rows, err := q.db.Query(ctx, listVerificationSessionsByStatus, arg.Status, arg.TenantID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	var items []VerificationSession
+	for rows.Next() {
+		var i VerificationSession
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.SessionID,
+			&i.Status,
+			&i.DiditSessionID,
+			&i.UserEmail,
+			&i.UserFirstName,
+			&i.UserLastName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CompletedAt,
+			&i.Metadata,
+			&i.ErasedAt,
+			&i.UserEmailIndex,
+			&i.Level,
+			&i.BaseSessionID,
+			&i.ExpiresAt,
+			&i.NextReviewAt,
+			&i.RemindedAt,
+			&i.IdentityFingerprint,
+			&i.UserCpf,
+			&i.UserCpfIndex,
+			&i.CpfMatch,
+			&i.Provider,
+			&i.VerificationUrl,
+			&i.ReconciledAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -247,13 +730,18 @@ func (q *Queries) ListVerificationSessionsByStatus(ctx context.Context, status s
 }
 
 const listVerificationSessionsByUserID = `-- name: ListVerificationSessionsByUserID :many
-SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata FROM verification_sessions 
-WHERE user_id = $1 
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions 
+WHERE user_id = $1 AND tenant_id = $2
 ORDER BY created_at DESC
 `
 
-func (q *Queries) ListVerificationSessionsByUserID(ctx context.Context, userID string) ([]VerificationSession, error) {
-	rows, err := q.db.QueryContext(ctx, listVerificationSessionsByUserID, userID)
+type ListVerificationSessionsByUserIDParams struct {
+	UserID   string
+	TenantID uuid.UUID
+}
+
+func (q *Queries) ListVerificationSessionsByUserID(ctx context.Context, arg ListVerificationSessionsByUserIDParams) ([]VerificationSession, error) {
+	rows, err := q.db.Query(ctx, listVerificationSessionsByUserID, arg.UserID, arg.TenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -274,36 +762,295 @@ func (q *Queries) ListVerificationSessionsByUserID(ctx context.Context, userID s
 			&i.UpdatedAt,
 			&i.CompletedAt,
 			&i.Metadata,
+			&i.ErasedAt,
+			&i.UserEmailIndex,
+			&i.Level,
+			&i.BaseSessionID,
+			&i.ExpiresAt,
+			&i.NextReviewAt,
+			&i.RemindedAt,
+			&i.IdentityFingerprint,
+			&i.UserCpf,
+			&i.UserCpfIndex,
+			&i.CpfMatch,
+			&i.Provider,
+			&i.VerificationUrl,
+			&i.ReconciledAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookEventsAfterID = `-- name: ListWebhookEventsAfterID :many
+SELECT id, event_type, session_id, payload, processed, created_at, status, attempts, next_attempt_at, locked_at, last_error, processed_at, redacted_at, provider, tenant_id FROM webhook_events
+WHERE id > $1 AND tenant_id = $3
+ORDER BY id
+LIMIT $2
+`
+
+type ListWebhookEventsAfterIDParams struct {
+	ID       uuid.UUID
+	Limit    int32
+	TenantID uuid.UUID
+}
+
+func (q *Queries) ListWebhookEventsAfterID(ctx context.Context, arg ListWebhookEventsAfterIDParams) ([]WebhookEvent, error) {
+	rows, err := q.db.Query(ctx, listWebhookEventsAfterID, arg.ID, arg.Limit, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEvent
+	for rows.Next() {
+		var i WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.SessionID,
+			&i.Payload,
+			&i.Processed,
+			&i.CreatedAt,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LockedAt,
+			&i.LastError,
+			&i.ProcessedAt,
+			&i.RedactedAt,
+			&i.Provider,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return items, nil
+}
+
+const listWebhookEventsByUserID = `-- name: ListWebhookEventsByUserID :many
+SELECT id, event_type, session_id, payload, processed, created_at, status, attempts, next_attempt_at, locked_at, last_error, processed_at, redacted_at, provider, tenant_id FROM webhook_events
+WHERE tenant_id = $1
+  AND (
+    session_id IN (
+        SELECT didit_session_id FROM verification_sessions
+        WHERE tenant_id = $1
+          AND user_id = $2::text
+          AND didit_session_id IS NOT NULL
+    )
+    OR payload->'data'->>'user_id' = $2::text
+  )
+ORDER BY created_at ASC
+`
+
+type ListWebhookEventsByUserIDParams struct {
+	TenantID uuid.UUID
+	UserID   string
+}
+
+// Webhook events are keyed by the Didit session id, so they are found through
+// the user's sessions or the user id Didit echoes back in the payload.
+func (q *Queries) ListWebhookEventsByUserID(ctx context.Context, arg ListWebhookEventsByUserIDParams) ([]WebhookEvent, error) {
+	rows, err := q.db.Query(ctx, listWebhookEventsByUserID, arg.TenantID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEvent
+	for rows.Next() {
+		var i WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.SessionID,
+			&i.Payload,
+			&i.Processed,
+			&i.CreatedAt,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LockedAt,
+			&i.LastError,
+			&i.ProcessedAt,
+			&i.RedactedAt,
+			&i.Provider,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+const listWebhookEventsForReplay = `-- name: ListWebhookEventsForReplay :many
+SELECT id, event_type, session_id, payload, processed, created_at, status, attempts, next_attempt_at, locked_at, last_error, processed_at, redacted_at, provider, tenant_id FROM webhook_events
+WHERE tenant_id = $1
+  AND ($2::text IS NULL OR session_id = $2::text)
+  AND ($3::timestamptz IS NULL OR created_at >= $3::timestamptz)
+  AND (NOT $4::boolean OR processed IS NOT TRUE)
+ORDER BY created_at ASC
+`
+
+type ListWebhookEventsForReplayParams struct {
+	TenantID        uuid.UUID
+	SessionID       pgtype.Text
+	Since           pgtype.Timestamptz
+	OnlyUnprocessed bool
+}
+
+func (q *Queries) ListWebhookEventsForReplay(ctx context.Context, arg ListWebhookEventsForReplayParams) ([]WebhookEvent, error) {
+	rows, err := q.db.Query(ctx, listWebhookEventsForReplay,
+		arg.TenantID,
+		arg.SessionID,
+		arg.Since,
+		arg.OnlyUnprocessed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEvent
+	for rows.Next() {
+		var i WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.SessionID,
+			&i.Payload,
+			&i.Processed,
+			&i.CreatedAt,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LockedAt,
+			&i.LastError,
+			&i.ProcessedAt,
+			&i.RedactedAt,
+			&i.Provider,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserVerificationSessions = `-- name: LockUserVerificationSessions :exec
+SELECT pg_advisory_xact_lock(hashtextextended($1::text || ':' || $2::text, 0))
+`
+
+type LockUserVerificationSessionsParams struct {
+	TenantID string
+	UserID   string
+}
+
+// Serializes the starts of a user until the transaction ends.
+func (q *Queries) LockUserVerificationSessions(ctx context.Context, arg LockUserVerificationSessionsParams) error {
+	_, err := q.db.Exec(ctx, lockUserVerificationSessions, arg.TenantID, arg.UserID)
+	return err
+}
+
+const lockVerificationSession = `-- name: LockVerificationSession :one
+SELECT id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id FROM verification_sessions
+WHERE session_id = $1 AND tenant_id = $2
+FOR UPDATE
+`
+
+type LockVerificationSessionParams struct {
+	SessionID string
+	TenantID  uuid.UUID
+}
+
+func (q *Queries) LockVerificationSession(ctx context.Context, arg LockVerificationSessionParams) (VerificationSession, error) {
+	row := q.db.QueryRow(ctx, lockVerificationSession, arg.SessionID, arg.TenantID)
+	var i VerificationSession
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.SessionID,
+		&i.Status,
+		&i.DiditSessionID,
+		&i.UserEmail,
+		&i.UserFirstName,
+		&i.UserLastName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
+	)
+	return i, err
+}
+
+const markWebhookEventProcessed = `-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET
+    processed = TRUE,
+    status = 'done',
+    processed_at = NOW(),
+    locked_at = NULL,
+    last_error = NULL
+WHERE id = $1 AND tenant_id = $2
+`
+
+type MarkWebhookEventProcessedParams struct {
+	ID       uuid.UUID
+	TenantID uuid.UUID
+}
+
+func (q *Queries) MarkWebhookEventProcessed(ctx context.Context, arg MarkWebhookEventProcessedParams) error {
+	_, err := q.db.Exec(ctx, markWebhookEventProcessed, arg.ID, arg.TenantID)
+	return err
+}
+
 const updateDiditSessionID = `-- name: UpdateDiditSessionID :one
 UPDATE verification_sessions 
 SET 
     didit_session_id = $2,
     updated_at = NOW()
-WHERE session_id = $1
-RETURNING id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata
+WHERE session_id = $1 AND tenant_id = $3
+RETURNING id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id
 `
 
 type UpdateDiditSessionIDParams struct {
 	SessionID      string
-	DiditSessionID sql.NullString
+	DiditSessionID pgtype.Text
+	TenantID       uuid.UUID
 }
 
 func (q *Queries) UpdateDiditSessionID(ctx context.Context, arg UpdateDiditSessionIDParams) (VerificationSession, error) {
-	row := q.db.QueryRowContext(ctx, updateDiditSessionID, arg.SessionID, arg.DiditSessionID)
+	row := q.db.QueryRow(ctx, updateDiditSessionID, arg.SessionID, arg.DiditSessionID, arg.TenantID)
 	var i VerificationSession
 	err := row.Scan(
 		&i.ID,
@@ -318,10 +1065,65 @@ func (q *Queries) UpdateDiditSessionID(ctx context.Context, arg UpdateDiditSessi
 		&i.UpdatedAt,
 		&i.CompletedAt,
 		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
+const updateVerificationSessionPII = `-- name: UpdateVerificationSessionPII :exec
+UPDATE verification_sessions
+SET
+    user_email = $2,
+    user_first_name = $3,
+    user_last_name = $4,
+    user_email_index = $5,
+    user_cpf = $6,
+    user_cpf_index = $7,
+    verification_url = $8
+WHERE id = $1 AND tenant_id = $9
+`
+
+type UpdateVerificationSessionPIIParams struct {
+	ID              uuid.UUID
+	UserEmail       string
+	UserFirstName   pgtype.Text
+	UserLastName    pgtype.Text
+	UserEmailIndex  pgtype.Text
+	UserCpf         pgtype.Text
+	UserCpfIndex    pgtype.Text
+	VerificationUrl pgtype.Text
+	TenantID        uuid.UUID
+}
+
+func (q *Queries) UpdateVerificationSessionPII(ctx context.Context, arg UpdateVerificationSessionPIIParams) error {
+	_, err := q.db.Exec(ctx, updateVerificationSessionPII,
+		arg.ID,
+		arg.UserEmail,
+		arg.UserFirstName,
+		arg.UserLastName,
+		arg.UserEmailIndex,
+		arg.UserCpf,
+		arg.UserCpfIndex,
+		arg.VerificationUrl,
+		arg.TenantID,
+	)
+	return err
+}
+
 const updateVerificationSessionStatus = `-- name: UpdateVerificationSessionStatus :one
 UPDATE verification_sessions 
 SET 
@@ -331,17 +1133,18 @@ SET
         WHEN $2 IN ('approved', 'rejected', 'failed') THEN NOW() 
         ELSE completed_at 
     END
-WHERE session_id = $1
-RETURNING id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata
+WHERE session_id = $1 AND tenant_id = $3
+RETURNING id, user_id, session_id, status, didit_session_id, user_email, user_first_name, user_last_name, created_at, updated_at, completed_at, metadata, erased_at, user_email_index, level, base_session_id, expires_at, next_review_at, reminded_at, identity_fingerprint, user_cpf, user_cpf_index, cpf_match, provider, verification_url, reconciled_at, tenant_id
 `
 
 type UpdateVerificationSessionStatusParams struct {
 	SessionID string
 	Status    string
+	TenantID  uuid.UUID
 }
 
 func (q *Queries) UpdateVerificationSessionStatus(ctx context.Context, arg UpdateVerificationSessionStatusParams) (VerificationSession, error) {
-	row := q.db.QueryRowContext(ctx, updateVerificationSessionStatus, arg.SessionID, arg.Status)
+	row := q.db.QueryRow(ctx, updateVerificationSessionStatus, arg.SessionID, arg.Status, arg.TenantID)
 	var i VerificationSession
 	err := row.Scan(
 		&i.ID,
@@ -356,6 +1159,38 @@ func (q *Queries) UpdateVerificationSessionStatus(ctx context.Context, arg Updat
 		&i.UpdatedAt,
 		&i.CompletedAt,
 		&i.Metadata,
+		&i.ErasedAt,
+		&i.UserEmailIndex,
+		&i.Level,
+		&i.BaseSessionID,
+		&i.ExpiresAt,
+		&i.NextReviewAt,
+		&i.RemindedAt,
+		&i.IdentityFingerprint,
+		&i.UserCpf,
+		&i.UserCpfIndex,
+		&i.CpfMatch,
+		&i.Provider,
+		&i.VerificationUrl,
+		&i.ReconciledAt,
+		&i.TenantID,
 	)
 	return i, err
 }
+
+const updateWebhookEventPayload = `-- name: UpdateWebhookEventPayload :exec
+UPDATE webhook_events
+SET payload = $2
+WHERE id = $1 AND tenant_id = $3
+`
+
+type UpdateWebhookEventPayloadParams struct {
+	ID       uuid.UUID
+	Payload  json.RawMessage
+	TenantID uuid.UUID
+}
+
+func (q *Queries) UpdateWebhookEventPayload(ctx context.Context, arg UpdateWebhookEventPayloadParams) error {
+	_, err := q.db.Exec(ctx, updateWebhookEventPayload, arg.ID, arg.Payload, arg.TenantID)
+	return err
+}
@@ -0,0 +1,113 @@
+package didit
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// breaker stops calls to Didit after failures consecutive failed calls.
+// After the cooldown a single probe call is let through: its success closes
+// the circuit and its failure opens it again.
+type breaker struct {
+	failures int
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	state     string
+	count     int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(failures int, cooldown time.Duration) *breaker {
+	return &breaker{
+		failures: failures,
+		cooldown: cooldown,
+		state:    CircuitClosed,
+	}
+}
+
+// allow reports whether a call may go out, and returns the error to fail
+// fast with otherwise.
+func (b *breaker) allow() error {
+	if b.failures <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Now().Before(b.openUntil) {
+			return &circuitOpenError{until: b.openUntil}
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probing {
+			return &circuitOpenError{until: time.Now().Add(time.Second)}
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != CircuitClosed {
+		log.Printf("Didit API recovered; circuit breaker closed")
+	}
+	b.state = CircuitClosed
+	b.count = 0
+	b.probing = false
+}
+
+func (b *breaker) failure(err error) {
+	if b.failures <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.count++
+	if b.state == CircuitHalfOpen || b.count >= b.failures {
+		b.state = CircuitOpen
+		b.openUntil = time.Now().Add(b.cooldown)
+		b.count = 0
+		b.probing = false
+		log.Printf("Didit API failing; circuit breaker open until %s: %v", b.openUntil.Format(time.RFC3339), err)
+	}
+}
+
+// release ends a probe call that neither succeeded nor failed, such as one
+// Didit rejected for a bad request, so the next call probes again.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) status() (string, *time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen {
+		if !time.Now().Before(b.openUntil) {
+			return CircuitHalfOpen, nil
+		}
+		until := b.openUntil
+		return b.state, &until
+	}
+	return b.state, nil
+}
@@ -5,20 +5,67 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
 
 	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/pkg/kycgate"
 )
 
+// Client calls the Didit API. The clients of every tenant share one
+// transport, circuit breaker and set of stats, as they call the same API.
 type Client struct {
-	config *config.Config
+	config  *config.Config
+	http    *http.Client
+	breaker *breaker
+	stats   *apiStats
 }
 
 func NewClient(cfg *config.Config) *Client {
 	return &Client{
-		config: cfg,
+		config:  cfg,
+		http:    &http.Client{Transport: newTransport(cfg)},
+		breaker: newBreaker(cfg.DiditBreakerFailures, cfg.DiditBreakerCooldown),
+		stats:   &apiStats{},
 	}
 }
 
+// ForTenant returns a client using the Didit credentials of t, falling back
+// to the configured ones for the settings t leaves empty. A tenant with its
+// own API key has its own Didit account and never verifies webhooks with the
+// configured secret. A tenant workflow verifies the basic level. A nil t is
+// the default tenant, served by c itself.
+func (c *Client) ForTenant(t *models.Tenant) *Client {
+	if t == nil {
+		return c
+	}
+
+	cfg := *c.config
+	if t.DiditAPIKey != "" {
+		cfg.DiditAPIKey = t.DiditAPIKey
+	}
+	if t.DiditWebhookSecret != "" || t.DiditAPIKey != "" {
+		cfg.DiditWebhookSecret = t.DiditWebhookSecret
+	}
+	if t.DiditWorkflowID != "" || t.DiditWorkflowURL != "" {
+		cfg.DiditWorkflows = maps.Clone(c.config.DiditWorkflows)
+		basic := cfg.DiditWorkflows[string(kycgate.LevelBasic)]
+		if t.DiditWorkflowID != "" {
+			basic.ID = t.DiditWorkflowID
+			cfg.DiditWorkflowID = t.DiditWorkflowID
+		}
+		if t.DiditWorkflowURL != "" {
+			basic.URL = t.DiditWorkflowURL
+			cfg.DiditWorkflowURL = t.DiditWorkflowURL
+		}
+		cfg.DiditWorkflows[string(kycgate.LevelBasic)] = basic
+	}
+
+	return &Client{config: &cfg, http: c.http, breaker: c.breaker, stats: c.stats}
+}
+
 // VerifyWebhookSignature validates the webhook signature using HMAC-SHA256
 func (c *Client) VerifyWebhookSignature(payload []byte, signature string) bool {
 	if signature == "" || c.config.DiditWebhookSecret == "" {
@@ -33,10 +80,28 @@ func (c *Client) VerifyWebhookSignature(payload []byte, signature string) bool {
 	return hmac.Equal([]byte(signature), []byte(expectedSignature))
 }
 
-// GetVerificationURL returns the fixed URL of the Didit workflow.
+// GetVerificationURL returns the fixed URL of the basic Didit workflow.
 func (c *Client) GetVerificationURL(userID, email, firstName, lastName string) string {
-	baseURL := "https://verify.didit.me/verify/ynzf6TUsQ6BPDyVMWe8btQ"
+	return workflowURL(c.config.DiditWorkflowURL, userID, email, firstName, lastName)
+}
+
+// GetWorkflowURL returns the fixed URL of the workflow verifying level. An
+// upgrade uses the level's upgrade workflow when one is configured.
+func (c *Client) GetWorkflowURL(level string, upgrade bool, userID, email, firstName, lastName string) (string, error) {
+	workflow, ok := c.config.DiditWorkflows[level]
+	if !ok || workflow.URL == "" {
+		return "", fmt.Errorf("no Didit workflow configured for level %q", level)
+	}
+
+	baseURL := workflow.URL
+	if upgrade && workflow.UpgradeURL != "" {
+		baseURL = workflow.UpgradeURL
+	}
+
+	return workflowURL(baseURL, userID, email, firstName, lastName), nil
+}
 
+func workflowURL(baseURL, userID, email, firstName, lastName string) string {
 	return fmt.Sprintf("%s?user_id=%s&email=%s&first_name=%s&last_name=%s",
-		baseURL, userID, email, firstName, lastName)
+		baseURL, url.QueryEscape(userID), url.QueryEscape(email), url.QueryEscape(firstName), url.QueryEscape(lastName))
 }
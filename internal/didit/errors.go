@@ -0,0 +1,79 @@
+package didit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+)
+
+var (
+	// ErrUnauthorized is returned when Didit rejects the API key.
+	ErrUnauthorized = errors.New("Didit rejected the API key")
+	// ErrRateLimited is returned when Didit keeps answering 429 after the
+	// retries, or asks to wait longer than the client would.
+	ErrRateLimited = errors.New("Didit rate limit exceeded")
+	// ErrSessionNotFound is returned for a session Didit does not know. It
+	// matches kyc.ErrProviderSessionNotFound.
+	ErrSessionNotFound = fmt.Errorf("Didit: %w", kyc.ErrProviderSessionNotFound)
+	// ErrCircuitOpen is returned without calling Didit while the circuit
+	// breaker is open after repeated failures.
+	ErrCircuitOpen = errors.New("Didit API unavailable, circuit breaker open")
+)
+
+// APIError is an unexpected response of the Didit API. It unwraps to the
+// typed error of its status, if any.
+type APIError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is how long Didit asked to wait before the next request.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Didit API returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("Didit API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusNotFound:
+		return ErrSessionNotFound
+	default:
+		return nil
+	}
+}
+
+// RetryAfter returns how long Didit asked to wait, if err carries it, or how
+// long the circuit breaker stays open.
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	var open *circuitOpenError
+	if errors.As(err, &open) {
+		return time.Until(open.until)
+	}
+	return 0
+}
+
+type circuitOpenError struct {
+	until time.Time
+}
+
+func (e *circuitOpenError) Error() string {
+	return ErrCircuitOpen.Error()
+}
+
+func (e *circuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
@@ -0,0 +1,254 @@
+package didit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+)
+
+// newTransport returns the transport shared by every Didit call, whatever
+// the tenant, so connections to the API are reused.
+func newTransport(cfg *config.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+	transport.TLSHandshakeTimeout = 5 * time.Second
+	transport.ResponseHeaderTimeout = cfg.DiditTimeout
+	return transport
+}
+
+// apiStats counts calls to the Didit API.
+type apiStats struct {
+	requests atomic.Uint64
+	retries  atomic.Uint64
+
+	mu     sync.Mutex
+	errors map[string]uint64
+}
+
+func (s *apiStats) recordError(kind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errors == nil {
+		s.errors = map[string]uint64{}
+	}
+	s.errors[kind]++
+}
+
+// APIStats reports the calls made to the Didit API and the state of the
+// circuit breaker, for every tenant.
+func (c *Client) APIStats() kyc.APIStats {
+	c.stats.mu.Lock()
+	errs := make(map[string]uint64, len(c.stats.errors))
+	for kind, n := range c.stats.errors {
+		errs[kind] = n
+	}
+	c.stats.mu.Unlock()
+
+	circuit, openUntil := c.breaker.status()
+	return kyc.APIStats{
+		Requests:  c.stats.requests.Load(),
+		Retries:   c.stats.retries.Load(),
+		Errors:    errs,
+		Circuit:   circuit,
+		OpenUntil: openUntil,
+	}
+}
+
+// do calls the Didit API, decoding a successful response into out. Each
+// attempt is bounded by DIDIT_TIMEOUT. Failed GETs are retried on 429 and
+// 5xx responses and on network errors up to DIDIT_MAX_RETRIES times with
+// jittered exponential backoff, waiting Retry-After instead when Didit sends
+// it. A POST may have created a billable session even when the gateway in
+// front of Didit failed, so it is only retried on 429 or a refused
+// connection, which Didit never processed. Calls fail fast with
+// ErrCircuitOpen while the circuit breaker is open.
+func (c *Client) do(ctx context.Context, method string, path string, body []byte, out any) error {
+	if err := c.breaker.allow(); err != nil {
+		c.stats.recordError("circuit_open")
+		return err
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var resp *http.Response
+		resp, err = c.attempt(ctx, method, path, body, out)
+		wait, retry := c.retryable(method, resp, err)
+		if !retry || attempt >= c.config.DiditMaxRetries || ctx.Err() != nil {
+			break
+		}
+		if wait == 0 {
+			wait = backoff(c.config.DiditRetryBackoff, c.config.DiditRetryMaxWait, attempt)
+		}
+		if wait > c.config.DiditRetryMaxWait {
+			break
+		}
+
+		c.stats.retries.Add(1)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.breaker.release()
+			return fmt.Errorf("%w (gave up waiting to retry: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	if err == nil {
+		c.breaker.success()
+		return nil
+	}
+
+	if errors.Is(err, context.Canceled) {
+		c.breaker.release()
+		return err
+	}
+	kind := errorKind(err)
+	c.stats.recordError(kind)
+	switch kind {
+	case "server", "network", "timeout":
+		c.breaker.failure(err)
+	default:
+		// Didit answered; the failure is ours or the request's.
+		c.breaker.success()
+	}
+	return err
+}
+
+// attempt makes a single call. The response is returned, with its body
+// already consumed, so the caller can decide whether to retry.
+func (c *Client) attempt(ctx context.Context, method string, path string, body []byte, out any) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.DiditTimeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.config.DiditAPIURL, "/")+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", c.config.DiditAPIKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.stats.requests.Add(1)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(message)),
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp, fmt.Errorf("failed to decode Didit response: %v", err)
+	}
+	return resp, nil
+}
+
+// retryable reports whether a failed attempt is worth retrying, and how long
+// Didit asked to wait before doing so.
+func (c *Client) retryable(method string, resp *http.Response, err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests:
+			return apiErr.RetryAfter, true
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return apiErr.RetryAfter, method == http.MethodGet
+		case http.StatusInternalServerError:
+			return 0, method == http.MethodGet
+		default:
+			return 0, false
+		}
+	}
+	// Transport errors and timeouts; a body that failed to decode is not
+	// retried.
+	if resp != nil {
+		return 0, false
+	}
+	return 0, method == http.MethodGet || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// backoff returns the wait before retry number attempt+1: an exponential
+// delay from base, capped at limit, of which a random half is dropped so
+// replicas do not retry in lockstep.
+func backoff(base time.Duration, limit time.Duration, attempt int) time.Duration {
+	d := base << attempt
+	if d <= 0 || d > limit {
+		d = limit
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// errorKind classifies a failed call for the stats and the circuit breaker.
+func errorKind(err error) string {
+	var apiErr *APIError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrSessionNotFound):
+		return "not_found"
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 500:
+		return "server"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "network"
+	default:
+		return "other"
+	}
+}
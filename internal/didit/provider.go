@@ -0,0 +1,148 @@
+package didit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+)
+
+// Name is the provider name of Didit.
+const Name = "didit"
+
+// SignatureHeader carries the HMAC-SHA256 of Didit webhooks.
+const SignatureHeader = "X-Didit-Signature"
+
+var _ kyc.Provider = (*Client)(nil)
+
+func (c *Client) Name() string {
+	return Name
+}
+
+// CreateSession opens a session through the Didit API when an API key and
+// the level's workflow id are configured, so the session id is known up
+// front and the decision can be fetched later. Otherwise, and for upgrades
+// with their own workflow link, it hands out the workflow's fixed link, which
+// returns users to the callback configured on the workflow.
+func (c *Client) CreateSession(ctx context.Context, req kyc.SessionRequest) (*kyc.ProviderSession, error) {
+	c = c.ForTenant(tenant.FromContext(ctx))
+	workflow := c.config.DiditWorkflows[req.Level]
+	fixedUpgrade := req.Upgrade && workflow.UpgradeURL != ""
+	if c.config.DiditAPIKey == "" || workflow.ID == "" || fixedUpgrade {
+		verificationURL, err := c.GetWorkflowURL(req.Level, req.Upgrade, req.UserID, req.Email, req.FirstName, req.LastName)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", kyc.ErrLevelNotSupported, err)
+		}
+		return &kyc.ProviderSession{Provider: Name, URL: verificationURL}, nil
+	}
+
+	params := map[string]any{
+		"workflow_id": workflow.ID,
+		"vendor_data": req.UserID,
+		"metadata": map[string]string{
+			"user_id":    req.UserID,
+			"session_id": req.SessionID,
+			"tenant_id":  tenant.ID(ctx).String(),
+		},
+		"contact_details": map[string]string{"email": req.Email},
+	}
+	if req.CallbackURL != "" {
+		params["callback"] = req.CallbackURL
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var created struct {
+		SessionID string `json:"session_id"`
+		URL       string `json:"url"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v2/session/", body, &created); err != nil {
+		return nil, fmt.Errorf("failed to create Didit session: %w", err)
+	}
+	if created.SessionID == "" || created.URL == "" {
+		return nil, fmt.Errorf("failed to create Didit session: incomplete response")
+	}
+	return &kyc.ProviderSession{Provider: Name, ID: created.SessionID, URL: created.URL}, nil
+}
+
+// FetchDecision reads a session's decision from the Didit API. The whole
+// response becomes the event's decision, as its sections match those of the
+// decision in webhooks.
+func (c *Client) FetchDecision(ctx context.Context, providerSessionID string) (*models.WebhookEvent, error) {
+	c = c.ForTenant(tenant.FromContext(ctx))
+	if c.config.DiditAPIKey == "" {
+		return nil, fmt.Errorf("DIDIT_API_KEY is required to fetch decisions")
+	}
+
+	var decision map[string]interface{}
+	err := c.do(ctx, http.MethodGet, "/v2/session/"+url.PathEscape(providerSessionID)+"/decision/", nil, &decision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Didit decision: %w", err)
+	}
+
+	status, _ := decision["status"].(string)
+	event := &models.WebhookEvent{
+		EventType: kyc.EventTypeForStatus(decisionStatus(status)),
+		Data: models.WebhookData{
+			SessionID: providerSessionID,
+			Status:    status,
+			Decision:  decision,
+		},
+		Timestamp: time.Now().UTC(),
+	}
+	if userID, ok := decision["vendor_data"].(string); ok {
+		event.Data.UserID = userID
+	}
+	if metadata, ok := decision["metadata"].(map[string]interface{}); ok {
+		event.Data.Metadata = metadata
+	}
+	return event, nil
+}
+
+// VerifyWebhook checks the X-Didit-Signature header. Without a webhook secret
+// every webhook is rejected, unless WEBHOOK_ALLOW_UNSIGNED opts into
+// accepting them unchecked.
+func (c *Client) VerifyWebhook(ctx context.Context, payload []byte, header http.Header) bool {
+	c = c.ForTenant(tenant.FromContext(ctx))
+	if c.config.DiditWebhookSecret == "" {
+		if c.config.WebhookAllowUnsigned {
+			return true
+		}
+		log.Printf("Rejecting Didit webhook: no webhook secret configured")
+		return false
+	}
+	return c.VerifyWebhookSignature(payload, header.Get(SignatureHeader))
+}
+
+func (c *Client) ParseWebhook(payload []byte) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse Didit webhook: %v", err)
+	}
+	return &event, nil
+}
+
+// decisionStatus maps the statuses of the Didit API ("Approved", "Declined",
+// "In Review") to ours.
+func decisionStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "approved":
+		return "approved"
+	case "declined":
+		return "rejected"
+	case "in review":
+		return "review"
+	default:
+		return ""
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"html/template"
 	"log"
 	"net/smtp"
+	"sync"
 )
 
 //go:embed templates/*.gohtml
@@ -20,21 +21,60 @@ type EmailConfig struct {
 	SenderName  string
 }
 
+// Default branding of the emails, used where a tenant sets none.
+const (
+	defaultBrandName    = "CrisPay"
+	defaultPrimaryColor = "#37322F"
+)
+
+// Branding customizes the emails of a tenant. Empty fields keep the default
+// look and the configured sender. The sender address is only used in the
+// From header; the SMTP account must be allowed to send as it.
+type Branding struct {
+	Name         string
+	SenderName   string
+	SenderEmail  string
+	LogoURL      string
+	PrimaryColor string
+	SupportURL   string
+}
+
 type User struct {
-	Name  string
-	Email string
+	Name     string
+	Email    string
+	Branding Branding
 }
 
 type EmailService struct {
 	config EmailConfig
+	wg     sync.WaitGroup
 }
 
 func NewEmailService(config EmailConfig) *EmailService {
 	return &EmailService{config: config}
 }
 
+// brand fills the empty fields of b with the defaults.
+func (s *EmailService) brand(b Branding) Branding {
+	if b.Name == "" {
+		b.Name = defaultBrandName
+	}
+	if b.PrimaryColor == "" {
+		b.PrimaryColor = defaultPrimaryColor
+	}
+	if b.SenderName == "" {
+		b.SenderName = s.config.SenderName
+	}
+	if b.SenderEmail == "" {
+		b.SenderEmail = s.config.SenderEmail
+	}
+	return b
+}
+
 // Email confirming success with KYC approval.
 func (s *EmailService) SendApprovedKycEmail(user User) error {
+	user.Branding = s.brand(user.Branding)
+
 	tmpl, err := template.ParseFS(templatesFS, "templates/approved_kyc.gohtml")
 	if err != nil {
 		return fmt.Errorf("error parsing template: %w", err)
@@ -46,7 +86,7 @@ func (s *EmailService) SendApprovedKycEmail(user User) error {
 	}
 
 	headers := map[string]string{
-		"From":         fmt.Sprintf("%s <%s>", s.config.SenderName, s.config.SenderEmail),
+		"From":         fmt.Sprintf("%s <%s>", user.Branding.SenderName, user.Branding.SenderEmail),
 		"To":           user.Email,
 		"Subject":      "KYC Aprovado. Parabéns!",
 		"MIME-Version": "1.0",
@@ -71,6 +111,8 @@ func (s *EmailService) SendApprovedKycEmail(user User) error {
 }
 
 func (s *EmailService) SendFailedKycEmail(user User) error {
+	user.Branding = s.brand(user.Branding)
+
 	tmpl, err := template.ParseFS(templatesFS, "templates/failed_kyc.gohtml")
 	if err != nil {
 		return fmt.Errorf("error parsing template: %w", err)
@@ -82,7 +124,7 @@ func (s *EmailService) SendFailedKycEmail(user User) error {
 	}
 
 	headers := make(map[string]string)
-	headers["From"] = fmt.Sprintf("%s <%s>", s.config.SenderName, s.config.SenderEmail)
+	headers["From"] = fmt.Sprintf("%s <%s>", user.Branding.SenderName, user.Branding.SenderEmail)
 	headers["To"] = user.Email
 	headers["Subject"] = "KYC Reprovado."
 	headers["MIME-Version"] = "1.0"
@@ -108,7 +150,9 @@ func (s *EmailService) SendFailedKycEmail(user User) error {
 
 // SendWelcomeEmailAsync sends the email asynchronously (does not block)
 func (s *EmailService) SendApprovedKycEmailAsync(user User) {
+	s.wg.Add(1)
 	go func() {
+		defer s.wg.Done()
 		log.Printf("Trying to send an email to: %s", user.Email)
 		log.Printf("SMTP Config - Host: %s, Port: %s, Sender: %s",
 			s.config.SMTPHost, s.config.SMTPPort, s.config.SenderEmail)
@@ -122,7 +166,9 @@ func (s *EmailService) SendApprovedKycEmailAsync(user User) {
 }
 
 func (s *EmailService) SendFailedKycEmailAsync(user User) {
+	s.wg.Add(1)
 	go func() {
+		defer s.wg.Done()
 		log.Printf("Trying to send an email to: %s", user.Email)
 		log.Printf("SMTP Config - Host: %s, Port: %s, Sender: %s",
 			s.config.SMTPHost, s.config.SMTPPort, s.config.SenderEmail)
@@ -134,3 +180,90 @@ func (s *EmailService) SendFailedKycEmailAsync(user User) {
 		}
 	}()
 }
+
+// Reverification is the data of the emails asking a verified user to verify
+// again.
+type Reverification struct {
+	Name            string
+	Email           string
+	VerificationURL string
+	// Deadline is the date access will be restricted, already formatted.
+	Deadline string
+	Branding Branding
+}
+
+// SendReverificationEmail asks the user to complete a new verification.
+func (s *EmailService) SendReverificationEmail(r Reverification) error {
+	r.Branding = s.brand(r.Branding)
+	return s.send(r.Email, "Atualize sua verificação de identidade", "templates/reverify_kyc.gohtml", r, r.Branding)
+}
+
+// SendExpiryReminderEmail warns the user that their verification is about to
+// expire with the identity document it was based on.
+func (s *EmailService) SendExpiryReminderEmail(r Reverification) error {
+	r.Branding = s.brand(r.Branding)
+	return s.send(r.Email, "Sua verificação de identidade vai vencer", "templates/kyc_expiry_reminder.gohtml", r, r.Branding)
+}
+
+func (s *EmailService) SendReverificationEmailAsync(r Reverification) {
+	s.async(r.Email, func() error { return s.SendReverificationEmail(r) })
+}
+
+func (s *EmailService) SendExpiryReminderEmailAsync(r Reverification) {
+	s.async(r.Email, func() error { return s.SendExpiryReminderEmail(r) })
+}
+
+func (s *EmailService) send(to, subject, templateName string, data any, branding Branding) error {
+	tmpl, err := template.ParseFS(templatesFS, templateName)
+	if err != nil {
+		return fmt.Errorf("error parsing template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("error executing template: %w", err)
+	}
+
+	headers := map[string]string{
+		"From":         fmt.Sprintf("%s <%s>", branding.SenderName, branding.SenderEmail),
+		"To":           to,
+		"Subject":      subject,
+		"MIME-Version": "1.0",
+		"Content-Type": "text/html; charset=UTF-8",
+	}
+
+	var message bytes.Buffer
+	for k, v := range headers {
+		message.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	auth := smtp.PlainAuth("", s.config.SenderEmail, s.config.SenderPass, s.config.SMTPHost)
+	addr := fmt.Sprintf("%s:%s", s.config.SMTPHost, s.config.SMTPPort)
+
+	if err := smtp.SendMail(addr, auth, s.config.SenderEmail, []string{to}, message.Bytes()); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+
+	return nil
+}
+
+func (s *EmailService) async(to string, send func() error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		log.Printf("Trying to send an email to: %s", to)
+
+		if err := send(); err != nil {
+			log.Printf("Error sending email to %s: %v", to, err)
+		} else {
+			log.Printf("Email sent successfully to: %s", to)
+		}
+	}()
+}
+
+// Wait blocks until every email started by the async senders has finished.
+func (s *EmailService) Wait() {
+	s.wg.Wait()
+}
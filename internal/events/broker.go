@@ -0,0 +1,220 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Channel is the Postgres NOTIFY channel written by the
+// verification_sessions_status_notify trigger.
+const Channel = "verification_status"
+
+// StatusChange is the payload of a verification_status notification.
+type StatusChange struct {
+	SessionID      string    `json:"session_id"`
+	TenantID       uuid.UUID `json:"tenant_id"`
+	UserID         string    `json:"user_id"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Broker holds a single LISTEN connection and fans notifications out to
+// in-process subscribers. Because the notifications come from a database
+// trigger, every replica sees every change regardless of which one wrote it.
+type Broker struct {
+	databaseURL string
+
+	mu          sync.Mutex
+	nextID      int
+	subscribers map[int]*subscriber
+}
+
+type subscriber struct {
+	sessionID string
+	ch        chan StatusChange
+	// queue replaces ch for subscribers that must not miss any change.
+	queue *changeQueue
+}
+
+func NewBroker(databaseURL string) *Broker {
+	return &Broker{
+		databaseURL: databaseURL,
+		subscribers: make(map[int]*subscriber),
+	}
+}
+
+// SubscribeSession returns a channel receiving the changes of one session and
+// a function that must be called to unsubscribe. A slow reader misses
+// intermediate changes.
+func (b *Broker) SubscribeSession(sessionID string) (<-chan StatusChange, func()) {
+	sub := &subscriber{sessionID: sessionID, ch: make(chan StatusChange, 8)}
+	return sub.ch, b.subscribe(sub)
+}
+
+// SubscribeAll returns a channel receiving every status change, in order, and
+// a function that must be called to unsubscribe. It is meant for consumers
+// keeping state in sync, like caches, so a slow reader misses nothing: the
+// changes queue up in memory until read.
+func (b *Broker) SubscribeAll() (<-chan StatusChange, func()) {
+	queue := &changeQueue{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	out := make(chan StatusChange)
+	go queue.pump(out)
+
+	unsubscribe := b.subscribe(&subscriber{queue: queue})
+	var once sync.Once
+	return out, func() {
+		unsubscribe()
+		once.Do(func() { close(queue.done) })
+	}
+}
+
+func (b *Broker) subscribe(sub *subscriber) func() {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+func (b *Broker) publish(change StatusChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, sub := range b.subscribers {
+		if sub.sessionID != "" && sub.sessionID != change.SessionID {
+			continue
+		}
+		if sub.queue != nil {
+			sub.queue.push(change)
+			continue
+		}
+		select {
+		case sub.ch <- change:
+		default:
+			// Slow consumers miss intermediate changes rather than blocking
+			// everyone; they still get the next one.
+			log.Printf("Dropping status change for slow subscriber (session %s)", change.SessionID)
+		}
+	}
+}
+
+// changeQueue is an unbounded queue of changes for one subscriber.
+type changeQueue struct {
+	mu      sync.Mutex
+	pending []StatusChange
+	// wake is signalled when pending grows; done when the subscriber left.
+	wake chan struct{}
+	done chan struct{}
+}
+
+func (q *changeQueue) push(change StatusChange) {
+	q.mu.Lock()
+	q.pending = append(q.pending, change)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pump delivers the queued changes to out until the subscriber leaves, then
+// closes out.
+func (q *changeQueue) pump(out chan<- StatusChange) {
+	defer close(out)
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-q.wake:
+		}
+
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.pending = nil
+				q.mu.Unlock()
+				break
+			}
+			change := q.pending[0]
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+
+			select {
+			case out <- change:
+			case <-q.done:
+				return
+			}
+		}
+	}
+}
+
+// Run listens for notifications until ctx is cancelled, reconnecting with a
+// backoff when the connection drops.
+func (b *Broker) Run(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("Status listener disconnected: %v (reconnecting in %s)", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.databaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	log.Printf("Listening for verification status changes")
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var change StatusChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			log.Printf("Invalid status notification payload: %v", err)
+			continue
+		}
+		b.publish(change)
+	}
+}
@@ -0,0 +1,352 @@
+// Package fieldcrypt encrypts PII columns and the identity fields of stored
+// payloads with envelope encryption: every value gets its own data key, which
+// is wrapped by a key-encryption key held by a KeyProvider.
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/pii"
+)
+
+// prefix marks encrypted values: enc:v1:<key id>:<wrapped data key>:<ciphertext>.
+const prefix = "enc:v1:"
+
+var b64 = base64.RawURLEncoding
+
+// Encryptor encrypts and decrypts field values. With a nil provider it passes
+// values through unchanged, which keeps development setups key-free.
+type Encryptor struct {
+	provider KeyProvider
+}
+
+func New(provider KeyProvider) *Encryptor {
+	return &Encryptor{
+		provider: provider,
+	}
+}
+
+// FromConfig builds the Encryptor selected by FIELD_ENCRYPTION_PROVIDER.
+func FromConfig(cfg *config.Config) (*Encryptor, error) {
+	switch cfg.FieldEncryptionProvider {
+	case "", "none":
+		return New(nil), nil
+	case "env":
+		provider, err := NewEnvKeyProvider(cfg.FieldEncryptionKeys, cfg.FieldEncryptionBlindIndexKey)
+		if err != nil {
+			return nil, err
+		}
+		return New(provider), nil
+	case "keyfile":
+		provider, err := NewKeyfileProvider(cfg.FieldEncryptionKeyfile)
+		if err != nil {
+			return nil, err
+		}
+		return New(provider), nil
+	default:
+		return nil, fmt.Errorf("unknown FIELD_ENCRYPTION_PROVIDER %q", cfg.FieldEncryptionProvider)
+	}
+}
+
+// Enabled reports whether values are actually encrypted.
+func (e *Encryptor) Enabled() bool {
+	return e.provider != nil
+}
+
+// IsEncrypted reports whether s is an encrypted value.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+// EncryptString encrypts a column value. Empty strings stay empty.
+func (e *Encryptor) EncryptString(ctx context.Context, s string) (string, error) {
+	if e.provider == nil || s == "" {
+		return s, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	keyID := e.provider.CurrentKeyID()
+	wrapped, err := e.provider.WrapKey(ctx, keyID, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %v", err)
+	}
+	ciphertext, err := seal(dataKey, []byte(s))
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + keyID + ":" + b64.EncodeToString(wrapped) + ":" + b64.EncodeToString(ciphertext), nil
+}
+
+// DecryptString decrypts a column value. Values that are not encrypted, like
+// rows written before encryption was enabled, are returned as they are.
+func (e *Encryptor) DecryptString(ctx context.Context, s string) (string, error) {
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+	if e.provider == nil {
+		return "", fmt.Errorf("encrypted value found but field encryption is not configured")
+	}
+
+	parts := strings.Split(strings.TrimPrefix(s, prefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	wrapped, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %v", err)
+	}
+	ciphertext, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %v", err)
+	}
+
+	dataKey, err := e.provider.UnwrapKey(ctx, parts[0], wrapped)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+	plaintext, err := open(dataKey, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a stored column value should be rewritten:
+// it is plaintext while encryption is on, or it was encrypted under an older
+// key.
+func (e *Encryptor) NeedsRotation(s string) bool {
+	if e.provider == nil || s == "" {
+		return false
+	}
+	if !IsEncrypted(s) {
+		return true
+	}
+	keyID, _, _ := strings.Cut(strings.TrimPrefix(s, prefix), ":")
+	return keyID != e.provider.CurrentKeyID()
+}
+
+// BlindIndex returns a deterministic HMAC of a normalized email so sessions
+// can still be looked up by email. Without a provider the index is an
+// unkeyed hash, and re-encryption recomputes it once keys are configured.
+func (e *Encryptor) BlindIndex(ctx context.Context, email string) (string, error) {
+	return e.keyedHash(ctx, strings.ToLower(strings.TrimSpace(email)))
+}
+
+// IdentityFingerprint returns a deterministic HMAC of a document number,
+// issuing country and date of birth, which identifies a person across
+// accounts without storing the document. It is empty unless all three are
+// known. Unlike the email index it cannot be recomputed later, so
+// fingerprints taken before keys were configured stop matching new ones.
+func (e *Encryptor) IdentityFingerprint(ctx context.Context, documentNumber string, country string, dateOfBirth string) (string, error) {
+	documentNumber = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, documentNumber)
+	country = strings.ToUpper(strings.TrimSpace(country))
+	dateOfBirth = strings.TrimSpace(dateOfBirth)
+	if documentNumber == "" || country == "" || dateOfBirth == "" {
+		return "", nil
+	}
+
+	// The prefix keeps fingerprints apart from email indexes under the same key.
+	return e.keyedHash(ctx, "identity:"+documentNumber+"|"+country+"|"+dateOfBirth)
+}
+
+// CPFIndex returns a deterministic HMAC of a normalized CPF, used to look
+// sessions up by CPF and to keep one user per CPF. Like the email index it is
+// recomputed by re-encryption.
+func (e *Encryptor) CPFIndex(ctx context.Context, cpf string) (string, error) {
+	return e.keyedHash(ctx, "cpf:"+cpf)
+}
+
+func (e *Encryptor) keyedHash(ctx context.Context, value string) (string, error) {
+	var key []byte
+	if e.provider != nil {
+		var err error
+		key, err = e.provider.BlindIndexKey(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get blind index key: %v", err)
+		}
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// EncryptBlob encrypts a file, like a document image. The result is a text
+// header, enc:v1:<key id>:<wrapped data key>, a newline and the raw
+// ciphertext.
+func (e *Encryptor) EncryptBlob(ctx context.Context, data []byte) ([]byte, error) {
+	if e.provider == nil {
+		return nil, fmt.Errorf("field encryption is not configured")
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	keyID := e.provider.CurrentKeyID()
+	wrapped, err := e.provider.WrapKey(ctx, keyID, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %v", err)
+	}
+	ciphertext, err := seal(dataKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := prefix + keyID + ":" + b64.EncodeToString(wrapped) + "\n"
+	return append([]byte(header), ciphertext...), nil
+}
+
+// DecryptBlob reverses EncryptBlob.
+func (e *Encryptor) DecryptBlob(ctx context.Context, blob []byte) ([]byte, error) {
+	if e.provider == nil {
+		return nil, fmt.Errorf("field encryption is not configured")
+	}
+
+	header, ciphertext, ok := bytes.Cut(blob, []byte("\n"))
+	if !ok || !IsEncrypted(string(header)) {
+		return nil, fmt.Errorf("malformed encrypted blob")
+	}
+	parts := strings.Split(strings.TrimPrefix(string(header), prefix), ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed encrypted blob")
+	}
+	wrapped, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed encrypted blob: %v", err)
+	}
+
+	dataKey, err := e.provider.UnwrapKey(ctx, parts[0], wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+	data, err := open(dataKey, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt blob: %v", err)
+	}
+	return data, nil
+}
+
+// EncryptPayload encrypts the identity fields of a JSON document. Each value
+// is encrypted as its JSON encoding so objects and numbers round-trip.
+func (e *Encryptor) EncryptPayload(ctx context.Context, payload []byte) ([]byte, error) {
+	if e.provider == nil {
+		return payload, nil
+	}
+	return e.transformPayload(payload, func(key string, value any) (any, error) {
+		if !pii.IsIdentityField(key) || value == nil {
+			return value, nil
+		}
+		if s, ok := value.(string); ok && IsEncrypted(s) {
+			return value, nil
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return e.EncryptString(ctx, string(plaintext))
+	})
+}
+
+// DecryptPayload reverses EncryptPayload.
+func (e *Encryptor) DecryptPayload(ctx context.Context, payload []byte) ([]byte, error) {
+	if !strings.Contains(string(payload), prefix) {
+		return payload, nil
+	}
+	return e.transformPayload(payload, func(key string, value any) (any, error) {
+		s, ok := value.(string)
+		if !ok || !IsEncrypted(s) {
+			return value, nil
+		}
+		plaintext, err := e.DecryptString(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		var decoded any
+		if err := json.Unmarshal([]byte(plaintext), &decoded); err != nil {
+			return nil, err
+		}
+		return decoded, nil
+	})
+}
+
+// PayloadNeedsRotation reports whether any identity field of a stored
+// payload should be rewritten.
+func (e *Encryptor) PayloadNeedsRotation(payload []byte) bool {
+	if e.provider == nil {
+		return false
+	}
+	needs := false
+	_, _ = e.transformPayload(payload, func(key string, value any) (any, error) {
+		if !pii.IsIdentityField(key) || value == nil {
+			return value, nil
+		}
+		s, ok := value.(string)
+		if !ok || e.NeedsRotation(s) {
+			needs = true
+		}
+		return value, nil
+	})
+	return needs
+}
+
+// transformPayload applies fn to every object member of a JSON document, not
+// descending into members fn replaced.
+func (e *Encryptor) transformPayload(payload []byte, fn func(key string, value any) (any, error)) ([]byte, error) {
+	var doc any
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %v", err)
+	}
+
+	var walk func(v any) error
+	walk = func(v any) error {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, value := range v {
+				replaced, err := fn(key, value)
+				if err != nil {
+					return err
+				}
+				v[key] = replaced
+				if pii.IsIdentityField(key) {
+					continue
+				}
+				if err := walk(replaced); err != nil {
+					return err
+				}
+			}
+		case []any:
+			for _, value := range v {
+				if err := walk(value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(doc); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
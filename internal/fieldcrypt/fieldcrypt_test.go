@@ -0,0 +1,205 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// testKeys are the key-encryption keys the tests pick from.
+var testKeys = map[string][]byte{
+	"k1": bytes.Repeat([]byte{1}, 32),
+	"k2": bytes.Repeat([]byte{2}, 32),
+}
+
+var testBlindIndexKey = bytes.Repeat([]byte{9}, 32)
+
+// newTestEncryptor returns an Encryptor encrypting under current and able to
+// decrypt under every key in ids.
+func newTestEncryptor(t *testing.T, current string, ids ...string) *Encryptor {
+	t.Helper()
+	keys := map[string][]byte{}
+	for _, id := range ids {
+		keys[id] = testKeys[id]
+	}
+	provider, err := NewStaticKeyProvider(current, keys, testBlindIndexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(provider)
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEncryptor(t, "k1", "k1")
+
+	tests := []string{
+		"",
+		"maria@example.com",
+		"José da Silva",
+		"a:b:c",
+		strings.Repeat("x", 4096),
+	}
+	for _, plaintext := range tests {
+		encrypted, err := e.EncryptString(ctx, plaintext)
+		if err != nil {
+			t.Fatalf("EncryptString(%q): %v", plaintext, err)
+		}
+		if plaintext != "" && (!IsEncrypted(encrypted) || strings.Contains(encrypted, plaintext)) {
+			t.Errorf("EncryptString(%q) = %q, want an encrypted value", plaintext, encrypted)
+		}
+		decrypted, err := e.DecryptString(ctx, encrypted)
+		if err != nil {
+			t.Fatalf("DecryptString(%q): %v", encrypted, err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("round trip of %q = %q", plaintext, decrypted)
+		}
+	}
+
+	// Each value gets its own data key.
+	a, _ := e.EncryptString(ctx, "same")
+	b, _ := e.EncryptString(ctx, "same")
+	if a == b {
+		t.Error("encrypting the same value twice gave the same ciphertext")
+	}
+
+	// Rows written before encryption was enabled pass through.
+	if got, err := e.DecryptString(ctx, "plain"); err != nil || got != "plain" {
+		t.Errorf("DecryptString(plain) = %q, %v", got, err)
+	}
+}
+
+func TestDecryptRotatedKey(t *testing.T) {
+	ctx := context.Background()
+	old := newTestEncryptor(t, "k1", "k1")
+	encrypted, err := old.EncryptString(ctx, "maria@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestEncryptor(t, "k2", "k1", "k2")
+	if !rotated.NeedsRotation(encrypted) {
+		t.Error("value under the old key does not need rotation")
+	}
+	decrypted, err := rotated.DecryptString(ctx, encrypted)
+	if err != nil {
+		t.Fatalf("decrypting under the old key: %v", err)
+	}
+	if decrypted != "maria@example.com" {
+		t.Errorf("decrypted %q", decrypted)
+	}
+
+	reencrypted, err := rotated.EncryptString(ctx, decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.NeedsRotation(reencrypted) {
+		t.Error("value under the current key still needs rotation")
+	}
+
+	// Once the old key is dropped, its values can no longer be read.
+	retired := newTestEncryptor(t, "k2", "k2")
+	if _, err := retired.DecryptString(ctx, encrypted); err == nil {
+		t.Error("decrypted a value whose key was removed")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEncryptor(t, "k1", "k1", "k2")
+	encrypted, err := e.EncryptString(ctx, "maria@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, prefix), ":")
+
+	// flip changes one byte of an encoded part.
+	flip := func(part string) string {
+		raw, err := b64.DecodeString(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw[len(raw)/2] ^= 0xff
+		return b64.EncodeToString(raw)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"ciphertext", prefix + parts[0] + ":" + parts[1] + ":" + flip(parts[2])},
+		{"wrapped key", prefix + parts[0] + ":" + flip(parts[1]) + ":" + parts[2]},
+		{"key id", prefix + "k2:" + parts[1] + ":" + parts[2]},
+		{"unknown key id", prefix + "k9:" + parts[1] + ":" + parts[2]},
+		{"truncated", prefix + parts[0] + ":" + parts[1]},
+		{"bad encoding", prefix + parts[0] + ":" + parts[1] + ":!!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := e.DecryptString(ctx, tt.value); err == nil {
+				t.Errorf("DecryptString accepted a tampered value and returned %q", got)
+			}
+		})
+	}
+}
+
+func TestBlindIndexStability(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEncryptor(t, "k1", "k1")
+	// Rotating the encryption keys does not touch the blind index key.
+	rotated := newTestEncryptor(t, "k2", "k1", "k2")
+
+	index := func(e *Encryptor, email string) string {
+		t.Helper()
+		got, err := e.BlindIndex(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	want := index(e, "maria@example.com")
+	for _, email := range []string{"maria@example.com", "  Maria@Example.COM "} {
+		if got := index(e, email); got != want {
+			t.Errorf("BlindIndex(%q) = %s, want %s", email, got, want)
+		}
+		if got := index(rotated, email); got != want {
+			t.Errorf("BlindIndex(%q) after key rotation = %s, want %s", email, got, want)
+		}
+	}
+	if index(e, "joao@example.com") == want {
+		t.Error("different emails share a blind index")
+	}
+
+	// The unkeyed index of a key-free setup differs from the keyed one.
+	if index(New(nil), "maria@example.com") == want {
+		t.Error("keyed and unkeyed blind indexes match")
+	}
+
+	// Indexes of different kinds never collide on the same value.
+	cpf, err := e.CPFIndex(ctx, "52998224725")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpf == index(e, "52998224725") {
+		t.Error("CPF index matches the email index of the same value")
+	}
+	again, _ := rotated.CPFIndex(ctx, "52998224725")
+	if again != cpf {
+		t.Error("CPF index changed after key rotation")
+	}
+
+	fingerprint, err := e.IdentityFingerprint(ctx, "ab-123.456", "bra", "1990-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, _ := e.IdentityFingerprint(ctx, "AB123456", " BRA ", "1990-01-02")
+	if fingerprint == "" || fingerprint != same {
+		t.Errorf("fingerprints of the same document differ: %q, %q", fingerprint, same)
+	}
+	if missing, _ := e.IdentityFingerprint(ctx, "AB123456", "BRA", ""); missing != "" {
+		t.Errorf("fingerprint without a date of birth = %q, want empty", missing)
+	}
+}
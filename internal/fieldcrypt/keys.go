@@ -0,0 +1,189 @@
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyProvider wraps and unwraps the per-value data keys with key-encryption
+// keys it never hands out. The shape matches cloud KMS APIs so a KMS-backed
+// provider can be added without touching the Encryptor.
+type KeyProvider interface {
+	// CurrentKeyID is the key new data keys are wrapped with.
+	CurrentKeyID() string
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+	// BlindIndexKey is the HMAC key of deterministic lookup indexes.
+	BlindIndexKey(ctx context.Context) ([]byte, error)
+}
+
+// StaticKeyProvider keeps its key-encryption keys in memory. It backs both the
+// env and the keyfile providers.
+type StaticKeyProvider struct {
+	current    string
+	keys       map[string][]byte
+	blindIndex []byte
+}
+
+// NewStaticKeyProvider builds a provider from 32-byte AES-256 keys. current
+// must be one of the keys.
+func NewStaticKeyProvider(current string, keys map[string][]byte, blindIndexKey []byte) (*StaticKeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current key %q not found", current)
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, got %d", id, len(key))
+		}
+	}
+	if len(blindIndexKey) < 32 {
+		return nil, errors.New("blind index key must be at least 32 bytes")
+	}
+
+	return &StaticKeyProvider{
+		current:    current,
+		keys:       keys,
+		blindIndex: blindIndexKey,
+	}, nil
+}
+
+// NewEnvKeyProvider reads keys written as "id:base64,id:base64". The first
+// key is the current one; the others are kept to decrypt older data.
+func NewEnvKeyProvider(keys string, blindIndexKey string) (*StaticKeyProvider, error) {
+	parsed := make(map[string][]byte)
+	current := ""
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid key entry %q: expected id:base64", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", id, err)
+		}
+		if current == "" {
+			current = id
+		}
+		parsed[id] = key
+	}
+	if current == "" {
+		return nil, errors.New("no encryption keys configured")
+	}
+
+	biKey, err := base64.StdEncoding.DecodeString(blindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blind index key: %v", err)
+	}
+
+	return NewStaticKeyProvider(current, parsed, biKey)
+}
+
+// keyfile is the JSON layout read by NewKeyfileProvider.
+type keyfile struct {
+	Current       string            `json:"current"`
+	Keys          map[string]string `json:"keys"`
+	BlindIndexKey string            `json:"blind_index_key"`
+}
+
+// NewKeyfileProvider reads keys from a JSON file:
+//
+//	{"current": "k2", "keys": {"k1": "<base64>", "k2": "<base64>"}, "blind_index_key": "<base64>"}
+func NewKeyfileProvider(path string) (*StaticKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %v", err)
+	}
+
+	var kf keyfile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("failed to parse keyfile: %v", err)
+	}
+
+	keys := make(map[string][]byte, len(kf.Keys))
+	for id, encoded := range kf.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", id, err)
+		}
+		keys[id] = key
+	}
+
+	biKey, err := base64.StdEncoding.DecodeString(kf.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blind index key: %v", err)
+	}
+
+	return NewStaticKeyProvider(kf.Current, keys, biKey)
+}
+
+func (p *StaticKeyProvider) CurrentKeyID() string {
+	return p.current
+}
+
+func (p *StaticKeyProvider) WrapKey(_ context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+	return seal(key, dataKey)
+}
+
+func (p *StaticKeyProvider) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+	return open(key, wrapped)
+}
+
+func (p *StaticKeyProvider) BlindIndexKey(context.Context) ([]byte, error) {
+	return p.blindIndex, nil
+}
+
+// seal encrypts with AES-256-GCM and prepends the nonce.
+func seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	repo      *repository.VerificationRepository
+	processor *webhooks.Processor
+	queue     *webhooks.Queue
+}
+
+func NewAdminHandler(repo *repository.VerificationRepository, processor *webhooks.Processor, queue *webhooks.Queue) *AdminHandler {
+	return &AdminHandler{
+		repo:      repo,
+		processor: processor,
+		queue:     queue,
+	}
+}
+
+// ReplayWebhooks re-runs stored webhook events through the current pipeline.
+func (h *AdminHandler) ReplayWebhooks(c *gin.Context) {
+	var req webhooks.ReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.processor.Replay(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrInvalidReplayRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Webhook replay finished: matched=%d applied=%d failed=%d dry_run=%t",
+		report.Matched, report.Applied, report.Failed, report.DryRun)
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetWebhookQueueStats returns the number of stored webhook events per queue state.
+func (h *AdminHandler) GetWebhookQueueStats(c *gin.Context) {
+	counts, err := h.repo.CountWebhookEventsByStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"counts": counts})
+}
+
+// ListDeadWebhookEvents lists the events that exhausted their retries.
+func (h *AdminHandler) ListDeadWebhookEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	events, err := h.repo.ListDeadWebhookEvents(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// RetryDeadWebhookEvent puts a dead-lettered event back on the queue.
+func (h *AdminHandler) RetryDeadWebhookEvent(c *gin.Context) {
+	id, ok := parseUUIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	event, err := h.repo.RequeueDeadWebhookEvent(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead webhook event not found"})
+		return
+	}
+	h.queue.Notify()
+
+	c.JSON(http.StatusOK, event)
+}
+
+// ReviewSession records a manual approve/reject decision on a session.
+func (h *AdminHandler) ReviewSession(c *gin.Context) {
+	var req webhooks.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.processor.Review(c.Request.Context(), c.Param("sessionId"), req, Actor(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, webhooks.ErrInvalidDecision):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, webhooks.ErrSessionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	MarkAudited(c)
+
+	c.JSON(http.StatusOK, session)
+}
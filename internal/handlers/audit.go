@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// auditedKey marks requests whose handler already wrote a specific audit log
+// entry, so the generic admin request entry is skipped.
+const auditedKey = "audited"
+
+// MarkAudited flags the request as audited by its handler.
+func MarkAudited(c *gin.Context) {
+	c.Set(auditedKey, true)
+}
+
+// IsAudited reports whether the handler already audited the request.
+func IsAudited(c *gin.Context) bool {
+	return c.GetBool(auditedKey)
+}
+
+type AuditHandler struct {
+	repo *repository.AuditRepository
+}
+
+func NewAuditHandler(repo *repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{
+		repo: repo,
+	}
+}
+
+// ListAuditLog queries the audit log, newest first. Filters: user_id, action,
+// entity_id, since (RFC3339) and before_seq for paging.
+func (h *AuditHandler) ListAuditLog(c *gin.Context) {
+	filter := repository.AuditFilter{
+		SubjectUserID: c.Query("user_id"),
+		Action:        c.Query("action"),
+		EntityID:      c.Query("entity_id"),
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+	filter.Limit = limit
+
+	if since := c.Query("since"); since != "" {
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 time"})
+			return
+		}
+	}
+	if beforeSeq := c.Query("before_seq"); beforeSeq != "" {
+		filter.BeforeSeq, err = strconv.ParseInt(beforeSeq, 10, 64)
+		if err != nil || filter.BeforeSeq < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before_seq must be a positive integer"})
+			return
+		}
+	}
+
+	entries, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
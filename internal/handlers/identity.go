@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Resolutions of a duplicate identity link.
+const (
+	LinkConfirmed = "confirmed"
+	LinkDismissed = "dismissed"
+)
+
+type IdentityHandler struct {
+	repo *repository.IdentityRepository
+}
+
+func NewIdentityHandler(repo *repository.IdentityRepository) *IdentityHandler {
+	return &IdentityHandler{
+		repo: repo,
+	}
+}
+
+// ListLinks returns duplicate identity links by status (default open),
+// newest first.
+func (h *IdentityHandler) ListLinks(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	links, err := h.repo.ListByStatus(c.Request.Context(), c.DefaultQuery("status", "open"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// ListUserLinks returns the accounts linked to a user, on either side.
+func (h *IdentityHandler) ListUserLinks(c *gin.Context) {
+	links, err := h.repo.ListByUserID(c.Request.Context(), c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+type resolveLinkRequest struct {
+	Status     string `json:"status" binding:"required"`
+	Resolution string `json:"resolution"`
+}
+
+// ResolveLink closes an open link as confirmed (same person) or dismissed.
+// The held session itself is decided through the session review endpoint.
+func (h *IdentityHandler) ResolveLink(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid link id"})
+		return
+	}
+
+	var req resolveLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status != LinkConfirmed && req.Status != LinkDismissed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be confirmed or dismissed"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	link, err := h.repo.ResolveLink(ctx, id, req.Status, req.Resolution, Actor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if link == nil {
+		existing, err := h.repo.GetLink(ctx, id)
+		switch {
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		case existing == nil:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Identity link not found"})
+		default:
+			c.JSON(http.StatusConflict, gin.H{"error": "Identity link already resolved", "link": existing})
+		}
+		return
+	}
+
+	log.Printf("Identity link %s %s by %s", link.ID, link.Status, Actor(c))
+	c.JSON(http.StatusOK, link)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/FelipePn10/crispaybackend/internal/kyb"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/pkg/kycgate"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type KYBHandler struct {
+	service *kyb.Service
+	repo    *repository.CompanyRepository
+}
+
+func NewKYBHandler(service *kyb.Service, repo *repository.CompanyRepository) *KYBHandler {
+	return &KYBHandler{
+		service: service,
+		repo:    repo,
+	}
+}
+
+// RegisterCompany stores a company of the authenticated user with its
+// beneficial owners and legal representatives and starts their individual
+// verifications. The response carries each person's verification link.
+func (h *KYBHandler) RegisterCompany(c *gin.Context) {
+	var req kyb.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.OwnerUserID = c.GetString(kycgate.UserIDKey)
+
+	status, err := h.service.Register(c.Request.Context(), req, "client:"+c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, kyb.ErrInvalidCNPJ), errors.Is(err, kyb.ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, kyb.ErrCompanyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "Company already registered"})
+		case errors.Is(err, kyc.ErrInvalidRequest), errors.Is(err, kyc.ErrCPFInUse),
+			errors.Is(err, kyc.ErrDailyLimit), errors.Is(err, kyc.ErrProviderFailed):
+			// A person's verification could not start; nothing was kept.
+			respondStartError(c, err, req.OwnerUserID)
+		default:
+			log.Printf("Failed to register company %s: %v", req.CNPJ, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register company"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, status)
+}
+
+// GetCompany returns a company of the authenticated user with the
+// verification status of its people and the aggregate status.
+func (h *KYBHandler) GetCompany(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid company id"})
+		return
+	}
+
+	status, err := h.service.OwnedStatus(c.Request.Context(), id, c.GetString(kycgate.UserIDKey))
+	if err != nil {
+		if errors.Is(err, kyb.ErrCompanyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ListCompanies lists the companies of the authenticated user, optionally
+// only the one with a cnpj.
+func (h *KYBHandler) ListCompanies(c *gin.Context) {
+	ctx := c.Request.Context()
+	ownerUserID := c.GetString(kycgate.UserIDKey)
+
+	if cnpj := c.Query("cnpj"); cnpj != "" {
+		company, err := h.repo.GetCompanyByCNPJ(ctx, kyb.NormalizeCNPJ(cnpj))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		companies := []*models.Company{}
+		if company != nil && company.OwnerUserID == ownerUserID {
+			companies = append(companies, company)
+		}
+		c.JSON(http.StatusOK, companies)
+		return
+	}
+
+	companies, err := h.repo.ListCompaniesByOwner(ctx, ownerUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, companies)
+}
+
+// StartPersonVerification starts a new verification for a person of a
+// company of the authenticated user, after the previous one failed or
+// expired. The link goes to the identity given at registration.
+func (h *KYBHandler) StartPersonVerification(c *gin.Context) {
+	companyID, err := uuid.Parse(c.Param("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid company id"})
+		return
+	}
+	personID, err := uuid.Parse(c.Param("personId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person id"})
+		return
+	}
+
+	ownerUserID := c.GetString(kycgate.UserIDKey)
+	person, err := h.service.StartPersonVerification(c.Request.Context(), companyID, personID, ownerUserID, "client:"+c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, kyb.ErrCompanyNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		case errors.Is(err, kyb.ErrPersonNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		case errors.Is(err, kyb.ErrIdentityGone):
+			c.JSON(http.StatusConflict, gin.H{"error": "Person identity no longer stored"})
+		case errors.Is(err, kyb.ErrPersonIsApproved):
+			c.JSON(http.StatusConflict, gin.H{"error": "Person already verified"})
+		default:
+			respondStartError(c, err, personID.String())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/FelipePn10/crispaybackend/internal/links"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+// LinkHandler serves the signed links users follow: the link opening the
+// provider's verification page and the provider's redirect back once they
+// finish.
+type LinkHandler struct {
+	links     *links.Signer
+	repo      *repository.VerificationRepository
+	processor *webhooks.Processor
+	tenants   *repository.TenantRepository
+	returnURL string
+}
+
+func NewLinkHandler(signer *links.Signer, repo *repository.VerificationRepository, processor *webhooks.Processor, tenants *repository.TenantRepository, returnURL string) *LinkHandler {
+	return &LinkHandler{
+		links:     signer,
+		repo:      repo,
+		processor: processor,
+		tenants:   tenants,
+		returnURL: returnURL,
+	}
+}
+
+// OpenVerification redirects a signed /v/:token link to the provider's
+// verification page of its session. A finished session sends the user to the
+// return page instead.
+func (h *LinkHandler) OpenVerification(c *gin.Context) {
+	session, code, status := h.resolve(c, c.Param("token"), links.PurposeVerification)
+	if session == nil {
+		h.fail(c, status, code)
+		return
+	}
+
+	if session.Status != "pending" {
+		h.finish(c, session)
+		return
+	}
+	if session.VerificationURL == "" {
+		h.fail(c, http.StatusNotFound, "session_not_found")
+		return
+	}
+
+	// The provider's link may carry the user's details: keep it out of
+	// caches and of the Referer of the provider's page.
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Redirect(http.StatusFound, session.VerificationURL)
+}
+
+// Callback receives users the provider sends back after a session. It pulls
+// the session's decision from the provider right away, rather than waiting
+// for the webhook, and sends the user to the return page with the outcome.
+func (h *LinkHandler) Callback(c *gin.Context) {
+	session, code, status := h.resolve(c, c.Query("token"), links.PurposeCallback)
+	if session == nil {
+		h.fail(c, status, code)
+		return
+	}
+
+	// Didit appends the id of the session the user went through.
+	if providerID := c.Query("verificationSessionId"); providerID != "" && session.DiditSessionID != "" && providerID != session.DiditSessionID {
+		log.Printf("Callback of session %s came back with provider session %s", session.SessionID, providerID)
+		h.fail(c, http.StatusBadRequest, "invalid_link")
+		return
+	}
+
+	if session.Status == "pending" && session.DiditSessionID != "" {
+		ctx := c.Request.Context()
+		if _, err := h.processor.Reconcile(ctx, session); err != nil {
+			log.Printf("Failed to pull decision of session %s on callback: %v", session.SessionID, err)
+		} else if refreshed, err := h.repo.GetSessionBySessionID(ctx, session.SessionID); err != nil {
+			log.Printf("Failed to reload session %s on callback: %v", session.SessionID, err)
+		} else {
+			session = refreshed
+		}
+	}
+
+	h.finish(c, session)
+}
+
+// resolve parses a token and loads its session, acting for the session's
+// tenant from then on. Without a session it returns the error code and
+// status to fail with.
+func (h *LinkHandler) resolve(c *gin.Context, token string, purpose links.Purpose) (*models.VerificationSession, string, int) {
+	claims, err := h.links.Parse(token, purpose)
+	if errors.Is(err, links.ErrExpired) {
+		return nil, "link_expired", http.StatusGone
+	}
+	if err != nil {
+		return nil, "invalid_link", http.StatusBadRequest
+	}
+
+	ctx, err := h.tenants.Context(c.Request.Context(), claims.TenantID)
+	if err != nil {
+		log.Printf("Failed to resolve tenant of link to session %s: %v", claims.SessionID, err)
+		return nil, "session_not_found", http.StatusNotFound
+	}
+	c.Request = c.Request.WithContext(ctx)
+
+	session, err := h.repo.GetSessionBySessionID(ctx, claims.SessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, "session_not_found", http.StatusNotFound
+	}
+	if err != nil {
+		log.Printf("Failed to load session %s of link: %v", claims.SessionID, err)
+		return nil, "internal_error", http.StatusInternalServerError
+	}
+	return session, "", 0
+}
+
+// finish sends the user to the return page with the session's status, or
+// answers with it when no return page is configured.
+func (h *LinkHandler) finish(c *gin.Context, session *models.VerificationSession) {
+	h.returnTo(c, http.StatusOK, url.Values{
+		"session_id": {session.SessionID},
+		"status":     {session.Status},
+	})
+}
+
+func (h *LinkHandler) fail(c *gin.Context, status int, code string) {
+	h.returnTo(c, status, url.Values{"error": {code}})
+}
+
+func (h *LinkHandler) returnTo(c *gin.Context, status int, params url.Values) {
+	if h.returnURL == "" {
+		body := gin.H{}
+		for k := range params {
+			body[k] = params.Get(k)
+		}
+		c.JSON(status, body)
+		return
+	}
+
+	target, err := url.Parse(h.returnURL)
+	if err != nil {
+		log.Printf("Invalid VERIFICATION_RETURN_URL %q: %v", h.returnURL, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid return URL"})
+		return
+	}
+	query := target.Query()
+	for k := range params {
+		query.Set(k, params.Get(k))
+	}
+	target.RawQuery = query.Encode()
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target.String())
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/links"
+	"github.com/FelipePn10/crispaybackend/internal/media"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type MediaHandler struct {
+	media     *media.Service
+	repo      *repository.VerificationRepository
+	providers *kyc.Router
+}
+
+func NewMediaHandler(mediaService *media.Service, repo *repository.VerificationRepository, providers *kyc.Router) *MediaHandler {
+	return &MediaHandler{
+		media:     mediaService,
+		repo:      repo,
+		providers: providers,
+	}
+}
+
+// ListSessionMedia returns the media of a session, each with a link signed
+// for the reviewer in X-Actor that expires after MEDIA_URL_TTL.
+func (h *MediaHandler) ListSessionMedia(c *gin.Context) {
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.media.Links(c.Request.Context(), session, Actor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CaptureSessionMedia fetches the session's decision from its provider again,
+// with fresh media links, and keeps the media still missing.
+func (h *MediaHandler) CaptureSessionMedia(c *gin.Context) {
+	if !h.media.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "media storage disabled"})
+		return
+	}
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+	if session.DiditSessionID == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session has no provider session id"})
+		return
+	}
+
+	provider, err := h.providers.Provider(session.Provider)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	event, err := provider.FetchDecision(c.Request.Context(), session.DiditSessionID)
+	if err != nil {
+		status, _ := providerErrorStatus(c, err)
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.media.Capture(c.Request.Context(), session, event.Data.Decision)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ServeMedia serves the file a signed /media/:token link points to.
+func (h *MediaHandler) ServeMedia(c *gin.Context) {
+	m, data, err := h.media.Open(c.Request.Context(), c.Param("token"), c.ClientIP())
+	switch {
+	case errors.Is(err, links.ErrExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "Media link expired"})
+		return
+	case errors.Is(err, links.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media link"})
+		return
+	case errors.Is(err, media.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	case err != nil:
+		log.Printf("Failed to serve media: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read media"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", "inline; filename=\""+m.Kind+"\"")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, m.ContentType, data)
+}
+
+func (h *MediaHandler) loadSession(c *gin.Context) (*models.VerificationSession, bool) {
+	session, err := h.repo.GetSessionBySessionID(c.Request.Context(), c.Param("sessionId"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return session, true
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/FelipePn10/crispaybackend/internal/privacy"
+	"github.com/gin-gonic/gin"
+)
+
+// ActorHeader names the operator acting on a user's data. It is recorded in
+// the audit log.
+const ActorHeader = "X-Actor"
+
+type PrivacyHandler struct {
+	service *privacy.Service
+}
+
+func NewPrivacyHandler(service *privacy.Service) *PrivacyHandler {
+	return &PrivacyHandler{
+		service: service,
+	}
+}
+
+// ExportUserData returns everything stored about a user, as JSON or, with
+// ?format=zip, as a ZIP archive.
+func (h *PrivacyHandler) ExportUserData(c *gin.Context) {
+	userID := c.Param("userId")
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+		return
+	}
+
+	export, err := h.service.Export(c.Request.Context(), userID, Actor(c), format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Exported data of user %s (%s)", userID, format)
+
+	if format == "json" {
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.zip"`, userID))
+	c.Status(http.StatusOK)
+	if err := privacy.WriteZip(c.Writer, export); err != nil {
+		log.Printf("Failed to write export of user %s: %v", userID, err)
+	}
+}
+
+// EraseUserData pseudonymizes a user's PII while keeping the verification
+// trail required for AML retention.
+func (h *PrivacyHandler) EraseUserData(c *gin.Context) {
+	userID := c.Param("userId")
+
+	report, err := h.service.Erase(c.Request.Context(), userID, Actor(c))
+	if err != nil {
+		if errors.Is(err, privacy.ErrLegalHold) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Erased data of user %s: sessions=%d webhooks=%d", userID, report.SessionsErased, report.WebhooksScrubbed)
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Actor returns the operator named in the X-Actor header, or "admin".
+func Actor(c *gin.Context) string {
+	if a := c.GetHeader(ActorHeader); a != "" {
+		return a
+	}
+	return "admin"
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/didit"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+type ProviderHandler struct {
+	repo      *repository.VerificationRepository
+	processor *webhooks.Processor
+	providers *kyc.Router
+}
+
+func NewProviderHandler(repo *repository.VerificationRepository, processor *webhooks.Processor, providers *kyc.Router) *ProviderHandler {
+	return &ProviderHandler{
+		repo:      repo,
+		processor: processor,
+		providers: providers,
+	}
+}
+
+// GetProviders reports the routing in use and the failover state of every
+// provider.
+func (h *ProviderHandler) GetProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"routing":   h.providers.Routing(),
+		"providers": h.providers.Status(),
+	})
+}
+
+// ReconcileSession fetches the decision of a session from its provider and
+// applies it.
+func (h *ProviderHandler) ReconcileSession(c *gin.Context) {
+	session, err := h.repo.GetSessionBySessionID(c.Request.Context(), c.Param("sessionId"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	outcome, err := h.processor.Reconcile(c.Request.Context(), session)
+	if err != nil {
+		status, _ := providerErrorStatus(c, err)
+		c.JSON(status, gin.H{"error": err.Error(), "outcome": outcome})
+		return
+	}
+
+	c.JSON(http.StatusOK, outcome)
+}
+
+// ReconcilePending reconciles the pending sessions opened at a provider more
+// than ?older_than ago (default: all of them).
+func (h *ProviderHandler) ReconcilePending(c *gin.Context) {
+	var olderThan time.Duration
+	if v := c.Query("older_than"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "older_than must be a duration like 1h"})
+			return
+		}
+		olderThan = d
+	}
+
+	report, err := h.processor.ReconcilePending(c.Request.Context(), olderThan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// providerErrorStatus maps an error of a call to a KYC provider to a
+// response status and message, setting Retry-After when the provider is
+// unavailable for a known time. Other errors are 502.
+func providerErrorStatus(c *gin.Context, err error) (int, string) {
+	switch {
+	case errors.Is(err, didit.ErrCircuitOpen), errors.Is(err, didit.ErrRateLimited):
+		if wait := didit.RetryAfter(err); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		}
+		return http.StatusServiceUnavailable, "Verification provider unavailable, try again later"
+	case errors.Is(err, didit.ErrUnauthorized):
+		return http.StatusBadGateway, "Verification provider rejected our credentials"
+	case errors.Is(err, kyc.ErrProviderSessionNotFound):
+		return http.StatusNotFound, "Verification provider does not know the session"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "Verification provider timed out"
+	default:
+		return http.StatusBadGateway, "Verification provider failed"
+	}
+}
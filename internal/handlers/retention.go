@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/retention"
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionHandler struct {
+	repo   *repository.RetentionRepository
+	purger *retention.Purger
+}
+
+func NewRetentionHandler(repo *repository.RetentionRepository, purger *retention.Purger) *RetentionHandler {
+	return &RetentionHandler{
+		repo:   repo,
+		purger: purger,
+	}
+}
+
+type PurgeRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// Purge runs the retention policies now. With dry_run it only reports how
+// many rows each policy would affect.
+func (h *RetentionHandler) Purge(c *gin.Context) {
+	var req PurgeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	report, err := h.purger.Purge(c.Request.Context(), req.DryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Retention purge finished: dry_run=%t policies=%d", report.DryRun, len(report.Policies))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListPurgeRuns returns the reports of past purges.
+func (h *RetentionHandler) ListPurgeRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	runs, err := h.repo.ListPurgeRuns(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+type CreateLegalHoldRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (h *RetentionHandler) CreateLegalHold(c *gin.Context) {
+	var req CreateLegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hold, err := h.repo.CreateLegalHold(c.Request.Context(), req.UserID, req.Reason, Actor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Legal hold placed on user %s by %s", hold.UserID, hold.CreatedBy)
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+func (h *RetentionHandler) ListLegalHolds(c *gin.Context) {
+	holds, err := h.repo.ListLegalHolds(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+func (h *RetentionHandler) DeleteLegalHold(c *gin.Context) {
+	userID := c.Param("userId")
+
+	deleted, err := h.repo.DeleteLegalHold(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Legal hold not found"})
+		return
+	}
+
+	log.Printf("Legal hold released on user %s by %s", userID, Actor(c))
+
+	c.Status(http.StatusNoContent)
+}
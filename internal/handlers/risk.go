@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/risk"
+	"github.com/gin-gonic/gin"
+)
+
+type RiskHandler struct {
+	repo   *repository.RiskRepository
+	scorer *risk.Engine
+}
+
+func NewRiskHandler(repo *repository.RiskRepository, scorer *risk.Engine) *RiskHandler {
+	return &RiskHandler{
+		repo:   repo,
+		scorer: scorer,
+	}
+}
+
+// ListSessionScores returns the risk evaluations of a session, newest first.
+func (h *RiskHandler) ListSessionScores(c *gin.Context) {
+	scores, err := h.repo.ListBySessionID(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scores)
+}
+
+// ListScores exports stored scores for rule tuning, oldest first. Filters:
+// since (RFC3339, default 30 days ago) and limit.
+func (h *RiskHandler) ListScores(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "1000"))
+	if err != nil || limit < 1 || limit > 10000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 10000"})
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -30)
+	if s := c.Query("since"); s != "" {
+		since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 time"})
+			return
+		}
+	}
+
+	scores, err := h.repo.ListSince(c.Request.Context(), since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scores)
+}
+
+// GetRules returns the loaded rules.
+func (h *RiskHandler) GetRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":  h.scorer.Enabled(),
+		"enforced": h.scorer.Enforced(),
+		"rules":    h.scorer.Rules(),
+	})
+}
+
+// ReloadRules reads the rules file again. Invalid rules are rejected and the
+// loaded ones stay in place.
+func (h *RiskHandler) ReloadRules(c *gin.Context) {
+	if !h.scorer.Enabled() {
+		c.JSON(http.StatusConflict, gin.H{"error": "risk scoring is disabled"})
+		return
+	}
+	if err := h.scorer.Load(); err != nil {
+		log.Printf("Risk rules reload failed: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Risk rules %q reloaded by %s", h.scorer.Rules().Version, Actor(c))
+	c.JSON(http.StatusOK, h.scorer.Rules())
+}
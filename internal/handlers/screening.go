@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/sanctions"
+	"github.com/gin-gonic/gin"
+)
+
+type ScreeningHandler struct {
+	repo     *repository.ScreeningRepository
+	screener *sanctions.Screener
+}
+
+func NewScreeningHandler(repo *repository.ScreeningRepository, screener *sanctions.Screener) *ScreeningHandler {
+	return &ScreeningHandler{
+		repo:     repo,
+		screener: screener,
+	}
+}
+
+// ListSessionMatches returns the sanctions hits that sent a session to
+// review, best match first.
+func (h *ScreeningHandler) ListSessionMatches(c *gin.Context) {
+	matches, err := h.repo.ListBySessionID(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, matches)
+}
+
+// GetLists reports how many entries each sanctions list has loaded.
+func (h *ScreeningHandler) GetLists(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": h.screener.Enabled(),
+		"entries": h.screener.Counts(),
+	})
+}
+
+// ReloadLists reads the list files again after an out-of-band download.
+func (h *ScreeningHandler) ReloadLists(c *gin.Context) {
+	if err := h.screener.Load(); err != nil {
+		log.Printf("Sanctions list reload failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "entries": h.screener.Counts()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": h.screener.Counts()})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/events"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type StreamHandler struct {
+	repo      *repository.VerificationRepository
+	broker    *events.Broker
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(repo *repository.VerificationRepository, broker *events.Broker, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{
+		repo:      repo,
+		broker:    broker,
+		heartbeat: heartbeat,
+	}
+}
+
+// StreamVerificationStatus pushes the status of a session as Server-Sent
+// Events. The current status is sent first, then every change until the
+// session reaches a terminal status or the client disconnects.
+func (h *StreamHandler) StreamVerificationStatus(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+
+	// Subscribe before reading the current state so no change slips in between.
+	changes, unsubscribe := h.broker.SubscribeSession(sessionID)
+	defer unsubscribe()
+
+	session, err := h.repo.GetSessionBySessionID(c.Request.Context(), sessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The stream outlives the server's write timeout.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear write deadline for status stream: %v", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	current := events.StatusChange{
+		SessionID: session.SessionID,
+		UserID:    session.UserID,
+		Status:    session.Status,
+		UpdatedAt: session.UpdatedAt,
+	}
+	if h.send(c, current) {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case change := <-changes:
+			if h.send(c, change) {
+				return
+			}
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"time": time.Now().UTC()})
+			c.Writer.Flush()
+		}
+	}
+}
+
+// send writes a status event and reports whether the stream is finished.
+func (h *StreamHandler) send(c *gin.Context, change events.StatusChange) bool {
+	c.SSEvent("status", change)
+	if models.IsTerminalStatus(change.Status) {
+		c.SSEvent("end", gin.H{"status": change.Status})
+		c.Writer.Flush()
+		return true
+	}
+	c.Writer.Flush()
+	return false
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/outbound"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SubscriptionHandler struct {
+	repo       *repository.SubscriptionRepository
+	dispatcher *outbound.Dispatcher
+}
+
+func NewSubscriptionHandler(repo *repository.SubscriptionRepository, dispatcher *outbound.Dispatcher) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		repo:       repo,
+		dispatcher: dispatcher,
+	}
+}
+
+type CreateSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Secret      string   `json:"secret"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description"`
+}
+
+// CreateSubscription registers an endpoint for outbound KYC events. The
+// signing secret is only returned in this response.
+func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
+	var req CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http(s) URL"})
+		return
+	}
+
+	if req.Secret == "" {
+		req.Secret, err = outbound.NewSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+	}
+	if req.EventTypes == nil {
+		req.EventTypes = []string{}
+	}
+
+	sub, err := h.repo.CreateSubscription(c.Request.Context(), &models.WebhookSubscription{
+		URL:         req.URL,
+		Secret:      req.Secret,
+		EventTypes:  req.EventTypes,
+		Description: req.Description,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
+	subs, err := h.repo.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+	c.JSON(http.StatusOK, subs)
+}
+
+func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
+	id, ok := parseUUIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	sub, err := h.repo.GetSubscription(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	sub.Secret = ""
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
+	id, ok := parseUUIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	deleted, err := h.repo.DeleteSubscription(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// EnableSubscription re-activates an endpoint that was disabled after
+// repeated failures.
+func (h *SubscriptionHandler) EnableSubscription(c *gin.Context) {
+	id, ok := parseUUIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	sub, err := h.repo.EnableSubscription(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	sub.Secret = ""
+	c.JSON(http.StatusOK, sub)
+}
+
+// ListDeliveries returns the delivery log of a subscription.
+func (h *SubscriptionHandler) ListDeliveries(c *gin.Context) {
+	id, ok := parseUUIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	deliveries, err := h.repo.ListDeliveries(c.Request.Context(), id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDelivery returns a delivery together with its attempt log.
+func (h *SubscriptionHandler) GetDelivery(c *gin.Context) {
+	id, ok := parseUUIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.repo.GetDelivery(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if delivery == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+
+	delivery.AttemptLog, err = h.repo.ListAttempts(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// Redeliver queues a new delivery with the same payload as an existing one.
+func (h *SubscriptionHandler) Redeliver(c *gin.Context) {
+	id, ok := parseUUIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.dispatcher.Redeliver(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if delivery == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func parseUUIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return uuid.Nil, false
+	}
+	return id, true
+}
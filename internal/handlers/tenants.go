@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+	colorPattern      = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+)
+
+type TenantHandler struct {
+	repo *repository.TenantRepository
+}
+
+func NewTenantHandler(repo *repository.TenantRepository) *TenantHandler {
+	return &TenantHandler{
+		repo: repo,
+	}
+}
+
+// tenantKeyResponse returns a tenant with its API key, which is only shown
+// when it is generated.
+type tenantKeyResponse struct {
+	Tenant *models.Tenant `json:"tenant"`
+	APIKey string         `json:"api_key"`
+}
+
+// CreateTenant registers a tenant and generates its API key.
+func (h *TenantHandler) CreateTenant(c *gin.Context) {
+	var req models.TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Slug == nil || !tenantSlugPattern.MatchString(*req.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must be 2 to 63 lowercase letters, digits or dashes"})
+		return
+	}
+	if req.Name == nil || *req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	t := &models.Tenant{Slug: *req.Slug, Active: true}
+	if err := applyTenantRequest(t, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing, err := h.repo.GetTenantBySlug(c.Request.Context(), t.Slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tenant slug already in use"})
+		return
+	}
+
+	apiKey, err := tenant.NewAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	created, err := h.repo.CreateTenant(c.Request.Context(), t, tenant.HashAPIKey(apiKey))
+	if err != nil {
+		log.Printf("Failed to create tenant %s: %v", t.Slug, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tenantKeyResponse{Tenant: created, APIKey: apiKey})
+}
+
+func (h *TenantHandler) ListTenants(c *gin.Context) {
+	tenants, err := h.repo.ListTenants(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tenants)
+}
+
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	t, ok := h.loadTenant(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// UpdateTenant changes the settings present in the request. The slug cannot
+// change: it is part of the tenant's webhook URLs.
+func (h *TenantHandler) UpdateTenant(c *gin.Context) {
+	t, ok := h.loadTenant(c)
+	if !ok {
+		return
+	}
+
+	var req models.TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Slug != nil && *req.Slug != t.Slug {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug cannot be changed"})
+		return
+	}
+	if req.Name != nil && *req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+	if req.Active != nil && !*req.Active && t.ID == tenant.DefaultID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the default tenant cannot be deactivated"})
+		return
+	}
+	if err := applyTenantRequest(t, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.repo.UpdateTenant(c.Request.Context(), t)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// RotateAPIKey replaces the API key of a tenant. The old key stops working
+// immediately.
+func (h *TenantHandler) RotateAPIKey(c *gin.Context) {
+	t, ok := h.loadTenant(c)
+	if !ok {
+		return
+	}
+
+	apiKey, err := tenant.NewAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	updated, err := h.repo.SetAPIKeyHash(c.Request.Context(), t.ID, tenant.HashAPIKey(apiKey))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tenantKeyResponse{Tenant: updated, APIKey: apiKey})
+}
+
+func (h *TenantHandler) loadTenant(c *gin.Context) (*models.Tenant, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant id"})
+		return nil, false
+	}
+
+	t, err := h.repo.GetTenant(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return nil, false
+	}
+	return t, true
+}
+
+// applyTenantRequest validates the settings present in req and copies them to
+// t.
+func applyTenantRequest(t *models.Tenant, req models.TenantRequest) error {
+	urls := []struct {
+		name  string
+		value *string
+	}{
+		{"didit_workflow_url", req.DiditWorkflowURL},
+		{"logo_url", req.LogoURL},
+		{"support_url", req.SupportURL},
+	}
+	for _, u := range urls {
+		if u.value == nil || *u.value == "" {
+			continue
+		}
+		parsed, err := url.Parse(*u.value)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("%s must be an absolute http(s) URL", u.name)
+		}
+	}
+	if req.SenderEmail != nil && *req.SenderEmail != "" {
+		if _, err := mail.ParseAddress(*req.SenderEmail); err != nil {
+			return fmt.Errorf("sender_email is not a valid address")
+		}
+	}
+	if req.PrimaryColor != nil && *req.PrimaryColor != "" && !colorPattern.MatchString(*req.PrimaryColor) {
+		return fmt.Errorf("primary_color must be a hex color like #1A2B3C")
+	}
+
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	set(&t.Name, req.Name)
+	set(&t.DiditAPIKey, req.DiditAPIKey)
+	set(&t.DiditWorkflowID, req.DiditWorkflowID)
+	set(&t.DiditWorkflowURL, req.DiditWorkflowURL)
+	set(&t.DiditWebhookSecret, req.DiditWebhookSecret)
+	set(&t.SenderName, req.SenderName)
+	set(&t.SenderEmail, req.SenderEmail)
+	set(&t.LogoURL, req.LogoURL)
+	set(&t.PrimaryColor, req.PrimaryColor)
+	set(&t.SupportURL, req.SupportURL)
+	if req.Active != nil {
+		t.Active = *req.Active
+	}
+	if t.DiditAPIKey != "" && t.DiditWebhookSecret == "" {
+		return fmt.Errorf("didit_webhook_secret is required with didit_api_key")
+	}
+	return nil
+}
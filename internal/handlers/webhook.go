@@ -1,242 +1,138 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
 
-	"github.com/FelipePn10/crispaybackend/config"
-	"github.com/FelipePn10/crispaybackend/internal/didit"
-	"github.com/FelipePn10/crispaybackend/internal/email/service"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
 	"github.com/FelipePn10/crispaybackend/internal/models"
 	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/FelipePn10/crispaybackend/internal/webhooks"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type WebhookHandler struct {
-	diditClient *didit.Client
-	config      *config.Config
-	repo        *repository.VerificationRepository
-	email       *service.EmailService
+	providers *kyc.Router
+	repo      *repository.VerificationRepository
+	queue     *webhooks.Queue
+	levels    *kyc.Service
+	starter   *kyc.Starter
+	tenants   *repository.TenantRepository
 }
 
-func NewWebhookHandler(diditClient *didit.Client, cfg *config.Config, repo *repository.VerificationRepository, emailService *service.EmailService) *WebhookHandler {
+func NewWebhookHandler(providers *kyc.Router, repo *repository.VerificationRepository, queue *webhooks.Queue, levels *kyc.Service, starter *kyc.Starter, tenants *repository.TenantRepository) *WebhookHandler {
 	return &WebhookHandler{
-		diditClient: diditClient,
-		config:      cfg,
-		repo:        repo,
-		email:       emailService,
+		providers: providers,
+		repo:      repo,
+		queue:     queue,
+		levels:    levels,
+		starter:   starter,
+		tenants:   tenants,
 	}
 }
 
-// HandleVerificationWebhook processes Didit webhooks
+// HandleVerificationWebhook verifies and stores the webhooks of the provider
+// named in the path, then acknowledges them right away. Processing happens
+// asynchronously in the webhook queue so a slow database never makes the
+// provider time out and retry. Webhooks of a tenant arrive on a path ending
+// in its slug and are verified with its credentials.
 func (h *WebhookHandler) HandleVerificationWebhook(c *gin.Context) {
-	//signature := c.GetHeader("X-Didit-Signature")
-
-	body, err := io.ReadAll(c.Request.Body)
+	provider, err := h.providers.Provider(c.Param("provider"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
-	}
-
-	log.Printf("Webhook received: %s", string(body))
-
-	// Validate signature
-	// if !h.diditClient.VerifyWebhookSignature(body, signature) {
-	// 	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
-	// 	return
-	// }
-
-	var webhookEvent models.WebhookEvent
-	if err := json.Unmarshal(body, &webhookEvent); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
-		return
-	}
-
-	// Save webhook to database
-	if err := h.repo.CreateWebhookEvent(c.Request.Context(), webhookEvent.EventType, webhookEvent.Data.SessionID, body); err != nil {
-		log.Printf("Failed to save webhook event: %v", err)
-	}
-
-	// Process events
-	switch webhookEvent.EventType {
-	case "verification.completed", "verification.approved":
-		h.handleVerificationCompleted(c.Request.Context(), webhookEvent)
-	case "verification.failed", "verification.rejected":
-		h.handleVerificationFailed(c.Request.Context(), webhookEvent)
-	case "verification.review":
-		h.handleVerificationReview(c.Request.Context(), webhookEvent)
-	default:
-		log.Printf("Unhandled event type: %s", webhookEvent.EventType)
-	}
-
-	c.JSON(http.StatusOK, gin.H{"status": "processed"})
-}
-
-func (h *WebhookHandler) handleVerificationCompleted(ctx context.Context, event models.WebhookEvent) {
-	log.Printf("Verification completed for session: %s", event.Data.SessionID)
-
-	// Extract user_id from metadata or user_data
-	userID := h.extractUserID(event.Data)
-	if userID == "" {
-		log.Printf("User ID not found in webhook data")
-		return
-	}
-
-	sessions, err := h.repo.ListVerificationSessionsByUserID(ctx, userID)
-	if err != nil {
-		log.Printf("Error getting sessions for user: %v", err)
-		return
-	}
-
-	if len(sessions) == 0 {
-		log.Printf("No sessions found for user: %s", userID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown provider"})
 		return
 	}
 
-	// Use the most recent session.
-	session := sessions[0]
-
-	// Update using the didit_session_id
-	_, err = h.repo.UpdateDiditSessionID(ctx, session.SessionID, event.Data.SessionID)
-	if err != nil {
-		log.Printf("Error updating didit session data: %v", err)
-		return
+	ctx := c.Request.Context()
+	if slug := c.Param("tenant"); slug != "" {
+		t, err := h.tenants.GetTenantBySlug(ctx, slug)
+		if err != nil {
+			log.Printf("Failed to load tenant %s: %v", slug, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tenant"})
+			return
+		}
+		if t == nil || !t.Active {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown tenant"})
+			return
+		}
+		ctx = tenant.NewContext(ctx, t)
 	}
 
-	emailUser := service.User{
-		Name:  session.UserFirstName,
-		Email: session.UserEmail,
-	}
-	h.email.SendApprovedKycEmailAsync(emailUser)
-	_, err = h.repo.UpdateStatus(ctx, session.SessionID, "approved")
+	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		log.Printf("Error updating session status: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
-	log.Printf("User %s verification approved (session: %s)", session.UserID, session.SessionID)
-}
 
-func (h *WebhookHandler) handleVerificationFailed(ctx context.Context, event models.WebhookEvent) {
-	log.Printf("Verification failed for session: %s", event.Data.SessionID)
-
-	userID := h.extractUserID(event.Data)
-	if userID == "" {
-		log.Printf("User ID not found in webhook data")
+	// Validate signature
+	if !provider.VerifyWebhook(ctx, body, c.Request.Header) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
 		return
 	}
 
-	sessions, err := h.repo.ListVerificationSessionsByUserID(ctx, userID)
+	webhookEvent, err := provider.ParseWebhook(body)
 	if err != nil {
-		log.Printf("Error getting sessions for user: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
 		return
 	}
 
-	if len(sessions) == 0 {
-		log.Printf("No sessions found for user: %s", userID)
-		return
+	// Tenants sharing a Didit account share its webhook endpoint, so the
+	// tenant recorded in the session metadata wins, as long as the signature
+	// also holds under that tenant's secret.
+	if id, ok := metadataTenantID(webhookEvent); ok && id != tenant.ID(ctx) {
+		tenantCtx, err := h.tenants.Context(ctx, id)
+		if err != nil {
+			log.Printf("Failed to load tenant %s of webhook: %v", id, err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown tenant"})
+			return
+		}
+		if t := tenant.FromContext(tenantCtx); t != nil && !t.Active {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown tenant"})
+			return
+		}
+		if !provider.VerifyWebhook(tenantCtx, body, c.Request.Header) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+			return
+		}
+		ctx = tenantCtx
 	}
 
-	session := sessions[0]
+	// The payload carries personal data, so only what identifies the event
+	// is logged.
+	log.Printf("Webhook received from %s: %s for session %s", provider.Name(), webhookEvent.EventType, webhookEvent.Data.SessionID)
 
-	emailUser := service.User{
-		Name:  session.UserFirstName,
-		Email: session.UserEmail,
-	}
-	h.email.SendFailedKycEmailAsync(emailUser)
-	_, err = h.repo.UpdateDiditSessionID(ctx, session.SessionID, event.Data.SessionID)
-	if err != nil {
-		log.Printf("Error updating didit session data: %v", err)
-		return
-	}
-
-	_, err = h.repo.UpdateStatus(ctx, session.SessionID, "failed")
-	if err != nil {
-		log.Printf("Error updating session status: %v", err)
+	// Save webhook to the queue; the provider retries delivery if this fails.
+	if _, err := h.repo.CreateWebhookEvent(ctx, provider.Name(), webhookEvent.EventType, webhookEvent.Data.SessionID, body); err != nil {
+		log.Printf("Failed to save webhook event: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store webhook"})
 		return
 	}
+	h.queue.Notify()
 
-	log.Printf("User %s verification failed (session: %s)", session.UserID, session.SessionID)
+	c.JSON(http.StatusOK, gin.H{"status": "queued"})
 }
 
-func (h *WebhookHandler) handleVerificationReview(ctx context.Context, event models.WebhookEvent) {
-	log.Printf("Verification under review for session: %s", event.Data.SessionID)
-
-	userID := h.extractUserID(event.Data)
-	if userID == "" {
-		log.Printf("User ID not found in webhook data")
-		return
+// metadataTenantID returns the tenant a session was opened for, recorded in
+// its metadata when it was created.
+func metadataTenantID(event *models.WebhookEvent) (uuid.UUID, bool) {
+	value, ok := event.Data.Metadata["tenant_id"].(string)
+	if !ok {
+		return uuid.Nil, false
 	}
-
-	sessions, err := h.repo.ListVerificationSessionsByUserID(ctx, userID)
-	if err != nil {
-		log.Printf("Error getting sessions for user: %v", err)
-		return
-	}
-
-	if len(sessions) == 0 {
-		log.Printf("No sessions found for user: %s", userID)
-		return
-	}
-
-	session := sessions[0]
-
-	_, err = h.repo.UpdateDiditSessionID(ctx, session.SessionID, event.Data.SessionID)
+	id, err := uuid.Parse(value)
 	if err != nil {
-		log.Printf("Error updating didit session data: %v", err)
-		return
-	}
-
-	_, err = h.repo.UpdateStatus(ctx, session.SessionID, "review")
-	if err != nil {
-		log.Printf("Error updating session status: %v", err)
-		return
+		return uuid.Nil, false
 	}
-
-	log.Printf("User %s verification under review (session: %s)", session.UserID, session.SessionID)
+	return id, true
 }
 
-// extractUserID extracts the user_id from the webhook data
-func (h *WebhookHandler) extractUserID(data models.WebhookData) string {
-	// First, try the user_id field directly.
-	if data.UserID != "" {
-		return data.UserID
-	}
-	// Then try the metadata.
-	if data.Metadata != nil {
-		if userID, ok := data.Metadata["user_id"]; ok {
-			if str, ok := userID.(string); ok {
-				return str
-			}
-		}
-		if userID, ok := data.Metadata["internal_user_id"]; ok {
-			if str, ok := userID.(string); ok {
-				return str
-			}
-		}
-	}
-
-	// Finally try the user_data.
-	if data.UserData != nil {
-		if userID, ok := data.UserData["user_id"]; ok {
-			if str, ok := userID.(string); ok {
-				return str
-			}
-		}
-		if userID, ok := data.UserData["id"]; ok {
-			if str, ok := userID.(string); ok {
-				return str
-			}
-		}
-	}
-
-	return ""
-}
-
-// StartVerification initiates the KYC verification process using the fixed link.
+// StartVerification initiates the KYC verification process using the fixed link
+// of the requested level's workflow. See kyc.Starter for how pending sessions,
+// upgrades and the daily cap are handled.
 func (h *WebhookHandler) StartVerification(c *gin.Context) {
 	var req models.VerificationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -244,48 +140,51 @@ func (h *WebhookHandler) StartVerification(c *gin.Context) {
 		return
 	}
 
-	sessionID := uuid.New().String()
-
-	// Create a session in the database.
-	session := &models.VerificationSession{
-		UserID:        req.UserID,
-		SessionID:     sessionID,
-		Status:        "pending",
-		UserEmail:     req.Email,
-		UserFirstName: req.FirstName,
-		UserLastName:  req.LastName,
-	}
-
-	_, err := h.repo.CreateSession(c.Request.Context(), session)
+	response, err := h.starter.Start(c.Request.Context(), req, "client:"+c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		respondStartError(c, err, req.UserID)
 		return
 	}
 
-	// Generate verification URL using Didit's fixed link.
-	verificationURL := h.diditClient.GetVerificationURL(req.UserID, req.Email, req.FirstName, req.LastName)
+	c.JSON(http.StatusOK, response)
+}
 
-	response := models.VerificationResponse{
-		VerificationURL: verificationURL,
-		UserID:          req.UserID,
+// respondStartError maps the errors of kyc.Starter to responses.
+func respondStartError(c *gin.Context, err error, userID string) {
+	var held *kyc.LevelHeldError
+	switch {
+	case errors.As(err, &held):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "User already holds this KYC level",
+			"level":      held.Level,
+			"session_id": held.SessionID,
+		})
+	case errors.Is(err, kyc.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, kyc.ErrCPFInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "CPF is already registered to another user"})
+	case errors.Is(err, kyc.ErrDailyLimit):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Daily verification attempt limit reached"})
+	case errors.Is(err, kyc.ErrProviderFailed):
+		log.Printf("Failed to start verification for user %s: %v", userID, err)
+		status, message := providerErrorStatus(c, err)
+		c.JSON(status, gin.H{"error": message})
+	default:
+		log.Printf("Failed to start verification for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start verification"})
 	}
-
-	log.Printf("Verification session created for user %s: %s", req.UserID, verificationURL)
-
-	c.JSON(http.StatusOK, response)
 }
 
 func (h *WebhookHandler) GetVerificationStatus(c *gin.Context) {
 	sessionID := c.Param("sessionId")
 
 	session, err := h.repo.GetSessionBySessionID(c.Request.Context(), sessionID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
 		return
 	}
-
-	if session == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -305,21 +204,36 @@ func (h *WebhookHandler) GetUserVerifications(c *gin.Context) {
 	c.JSON(http.StatusOK, sessions)
 }
 
-// GetUserVerificationStatus retrieves the status of a user's last verification.
+// GetUserVerificationStatus returns the effective KYC decision of a user: the
+// approval granting the highest level, or the latest finished verification.
+// Other services read it through kycgate.HTTPSource.
 func (h *WebhookHandler) GetUserVerificationStatus(c *gin.Context) {
 	userID := c.Param("userId")
 
-	sessions, err := h.repo.ListVerificationSessionsByUserID(c.Request.Context(), userID)
+	decision, err := h.levels.LatestDecision(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if len(sessions) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No verification sessions found for user"})
+	if decision == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No finished verification found for user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+// GetUserKYCLevel reports the highest KYC level a user holds and the next
+// level they can upgrade to.
+func (h *WebhookHandler) GetUserKYCLevel(c *gin.Context) {
+	userID := c.Param("userId")
+
+	level, err := h.levels.Level(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return to the most recent session
-	c.JSON(http.StatusOK, sessions[0])
+	c.JSON(http.StatusOK, level)
 }
@@ -0,0 +1,62 @@
+package kyb
+
+import "strings"
+
+// NormalizeCNPJ strips the punctuation of a formatted CNPJ
+// (12.ABC.345/01DE-35) and upper-cases it.
+func NormalizeCNPJ(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '/', '-', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(s)))
+}
+
+// ValidCNPJ checks a normalized CNPJ. Since July 2026 the first twelve
+// characters may be letters as well as digits; each counts as its ASCII code
+// minus 48 in the mod 11 check digits, which keeps numeric CNPJs valid.
+func ValidCNPJ(cnpj string) bool {
+	if len(cnpj) != 14 {
+		return false
+	}
+
+	values := make([]int, 14)
+	allSame := true
+	for i := 0; i < 14; i++ {
+		c := cnpj[i]
+		switch {
+		case c >= '0' && c <= '9':
+		case c >= 'A' && c <= 'Z' && i < 12:
+		default:
+			return false
+		}
+		values[i] = int(c) - '0'
+		if c != cnpj[0] {
+			allSame = false
+		}
+	}
+	if allSame {
+		return false
+	}
+
+	return checkDigit(values[:12]) == values[12] && checkDigit(values[:13]) == values[13]
+}
+
+// checkDigit computes the next check digit with weights cycling 2..9 from the
+// right.
+func checkDigit(values []int) int {
+	sum, weight := 0, 2
+	for i := len(values) - 1; i >= 0; i-- {
+		sum += values[i] * weight
+		weight++
+		if weight > 9 {
+			weight = 2
+		}
+	}
+	if r := sum % 11; r >= 2 {
+		return 11 - r
+	}
+	return 0
+}
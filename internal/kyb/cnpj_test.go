@@ -0,0 +1,49 @@
+package kyb
+
+import "testing"
+
+func TestValidCNPJ(t *testing.T) {
+	tests := []struct {
+		name string
+		cnpj string
+		want bool
+	}{
+		{"valid", "11222333000181", true},
+		{"another valid", "11444777000161", true},
+		{"valid alphanumeric", "12ABC34501DE35", true},
+		{"formatted", "11.222.333/0001-81", false},
+		{"bad first check digit", "11222333000191", false},
+		{"bad second check digit", "11222333000182", false},
+		{"bad alphanumeric check digit", "12ABC34501DE36", false},
+		{"repeated zeros with matching check digits", "00000000000000", false},
+		{"repeated digits", "11111111111111", false},
+		{"letter in check digits", "12ABC34501DE3A", false},
+		{"lower case", "12abc34501de35", false},
+		{"too short", "1122233300018", false},
+		{"too long", "112223330001810", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidCNPJ(tt.cnpj); got != tt.want {
+				t.Errorf("ValidCNPJ(%q) = %v, want %v", tt.cnpj, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCNPJ(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"11.222.333/0001-81", "11222333000181"},
+		{" 12.abc.345/01de-35 ", "12ABC34501DE35"},
+		{"11222333000181", "11222333000181"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCNPJ(tt.in); got != tt.want {
+			t.Errorf("NormalizeCNPJ(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,543 @@
+// Package kyb verifies businesses: a company is approved once every required
+// beneficial owner and legal representative holds an approved individual
+// KYC verification.
+package kyb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/pkg/kycgate"
+	"github.com/google/uuid"
+)
+
+// Actor is the audit log actor of derived company status changes.
+const Actor = "system:kyb"
+
+// Aggregate company statuses.
+const (
+	StatusPending        = "pending"
+	StatusReview         = "review"
+	StatusActionRequired = "action_required"
+	StatusApproved       = "approved"
+)
+
+// PersonNotStarted is the status of a person without a verification session.
+const PersonNotStarted = "not_started"
+
+var (
+	ErrInvalidCNPJ      = errors.New("invalid CNPJ")
+	ErrInvalidRequest   = errors.New("invalid company request")
+	ErrCompanyExists    = errors.New("company already registered")
+	ErrCompanyNotFound  = errors.New("company not found")
+	ErrPersonNotFound   = errors.New("company person not found")
+	ErrPersonIsApproved = errors.New("person already verified")
+	// ErrIdentityGone is returned when a person's identity was erased or
+	// deleted with their verification session.
+	ErrIdentityGone = errors.New("person identity no longer stored")
+)
+
+// RegisterRequest registers a company with its people. OwnerUserID is the
+// authenticated user registering it, never read from the body.
+type RegisterRequest struct {
+	CNPJ              string          `json:"cnpj" binding:"required"`
+	LegalName         string          `json:"legal_name" binding:"required"`
+	TradeName         string          `json:"trade_name"`
+	RegisteredAddress models.Address  `json:"registered_address" binding:"required"`
+	OwnerUserID       string          `json:"-"`
+	People            []PersonRequest `json:"people" binding:"required,dive"`
+}
+
+// PersonRequest is a beneficial owner or legal representative to verify.
+// Each person is verified as a new user, except the one marked Self: the
+// company owner, whose own approval is reused. Legal representatives and
+// owners whose stake is unknown or at least the ownership threshold are
+// always required; Required only makes owners below it required too.
+type PersonRequest struct {
+	Role             string   `json:"role" binding:"required"`
+	Self             bool     `json:"self"`
+	Email            string   `json:"email" binding:"required"`
+	FirstName        string   `json:"first_name"`
+	LastName         string   `json:"last_name"`
+	CPF              string   `json:"cpf"`
+	OwnershipPercent *float64 `json:"ownership_percent"`
+	Required         *bool    `json:"required"`
+}
+
+// PersonIdentity is who to send the verification link to.
+type PersonIdentity struct {
+	Email     string
+	FirstName string
+	LastName  string
+	CPF       string
+}
+
+// PersonStatus is a person with the state of their verification.
+type PersonStatus struct {
+	*models.CompanyPerson
+	Status string `json:"status"`
+	// VerificationURL is only set right after a verification is started.
+	VerificationURL string `json:"verification_url,omitempty"`
+}
+
+// CompanyStatus is a company with the aggregate state of its people.
+type CompanyStatus struct {
+	*models.Company
+	RequiredPeople int             `json:"required_people"`
+	ApprovedPeople int             `json:"approved_people"`
+	People         []*PersonStatus `json:"people"`
+}
+
+type Service struct {
+	repo     *repository.CompanyRepository
+	sessions *repository.VerificationRepository
+	audit    *repository.AuditRepository
+	levels   *kyc.Service
+	starter  *kyc.Starter
+
+	// level is the KYC level required of every person.
+	level        kycgate.Level
+	uboThreshold float64
+}
+
+func NewService(cfg *config.Config, repo *repository.CompanyRepository, sessions *repository.VerificationRepository, audit *repository.AuditRepository, levels *kyc.Service, starter *kyc.Starter) *Service {
+	return &Service{
+		repo:         repo,
+		sessions:     sessions,
+		audit:        audit,
+		levels:       levels,
+		starter:      starter,
+		level:        kycgate.Level(cfg.KYBPersonLevel),
+		uboThreshold: cfg.KYBUBOOwnershipThreshold,
+	}
+}
+
+// inTx returns a copy of the service writing through repo's transaction.
+func (s *Service) inTx(repo *repository.VerificationRepository) *Service {
+	tx := *s
+	tx.repo = repository.NewCompanyRepository(repo.Queries())
+	tx.sessions = repo
+	tx.audit = repository.NewAuditRepository(repo.Queries())
+	tx.starter = s.starter.InTx(repo)
+	return &tx
+}
+
+// Register stores a company and starts one individual verification per
+// person, all in one transaction: when any step fails, including a
+// verification that cannot start, nothing is kept and the registration can
+// simply be retried.
+func (s *Service) Register(ctx context.Context, req RegisterRequest, actor string) (*CompanyStatus, error) {
+	cnpj := NormalizeCNPJ(req.CNPJ)
+	if !ValidCNPJ(cnpj) {
+		return nil, ErrInvalidCNPJ
+	}
+	if err := s.validatePeople(req.People); err != nil {
+		return nil, err
+	}
+
+	var status *CompanyStatus
+	err := s.sessions.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		var err error
+		status, err = s.inTx(repo).register(ctx, cnpj, req, actor)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *Service) register(ctx context.Context, cnpj string, req RegisterRequest, actor string) (*CompanyStatus, error) {
+	existing, err := s.repo.GetCompanyByCNPJ(ctx, cnpj)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCompanyExists
+	}
+
+	company, err := s.repo.CreateCompany(ctx, &models.Company{
+		CNPJ:              cnpj,
+		LegalName:         req.LegalName,
+		TradeName:         req.TradeName,
+		RegisteredAddress: req.RegisteredAddress,
+		OwnerUserID:       req.OwnerUserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.audit.Record(ctx, models.AuditEvent{
+		Action:        models.AuditCompanyRegistered,
+		SubjectUserID: company.OwnerUserID,
+		Actor:         actor,
+		EntityType:    "company",
+		EntityID:      company.ID.String(),
+		Details:       map[string]any{"cnpj": cnpj, "people": len(req.People)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to audit registration of company %s: %v", company.ID, err)
+	}
+
+	links := make(map[uuid.UUID]string)
+	for _, p := range req.People {
+		// User ids are never taken from the request, or a company could
+		// claim the approval of anyone by naming them.
+		userID := "kyb:" + uuid.New().String()
+		if p.Self {
+			userID = company.OwnerUserID
+		}
+		person, err := s.repo.CreatePerson(ctx, &models.CompanyPerson{
+			CompanyID:        company.ID,
+			Role:             p.Role,
+			UserID:           userID,
+			OwnershipPercent: p.OwnershipPercent,
+			Required:         s.required(p),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		identity := PersonIdentity{Email: p.Email, FirstName: p.FirstName, LastName: p.LastName, CPF: p.CPF}
+		if links[person.ID], err = s.start(ctx, person, identity, actor); err != nil {
+			log.Printf("Failed to start verification of %s of company %s: %v", person.Role, company.ID, err)
+			return nil, err
+		}
+	}
+
+	status, err := s.Status(ctx, company.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, person := range status.People {
+		person.VerificationURL = links[person.ID]
+	}
+	return status, nil
+}
+
+// OwnedStatus is Status for the owner of the company. Companies of other
+// users are reported as not found.
+func (s *Service) OwnedStatus(ctx context.Context, companyID uuid.UUID, ownerUserID string) (*CompanyStatus, error) {
+	if _, err := s.ownedCompany(ctx, companyID, ownerUserID); err != nil {
+		return nil, err
+	}
+	return s.Status(ctx, companyID)
+}
+
+func (s *Service) ownedCompany(ctx context.Context, companyID uuid.UUID, ownerUserID string) (*models.Company, error) {
+	company, err := s.repo.GetCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company == nil || company.OwnerUserID != ownerUserID {
+		return nil, ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+// StartPersonVerification starts a new verification for a person whose
+// previous one failed or expired, at the request of the company owner. The
+// link goes to the identity given at registration, kept in the sessions
+// linked to the person, so the caller cannot redirect it.
+func (s *Service) StartPersonVerification(ctx context.Context, companyID uuid.UUID, personID uuid.UUID, ownerUserID string, actor string) (*PersonStatus, error) {
+	if _, err := s.ownedCompany(ctx, companyID, ownerUserID); err != nil {
+		return nil, err
+	}
+	person, err := s.repo.GetPerson(ctx, companyID, personID)
+	if err != nil {
+		return nil, err
+	}
+	if person == nil {
+		return nil, ErrPersonNotFound
+	}
+
+	current, err := s.personStatus(ctx, person)
+	if err != nil {
+		return nil, err
+	}
+	if current == StatusApproved {
+		return nil, ErrPersonIsApproved
+	}
+
+	identity, err := s.identity(ctx, person)
+	if err != nil {
+		return nil, err
+	}
+	verificationURL, err := s.start(ctx, person, identity, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.Status(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range status.People {
+		if p.ID == personID {
+			p.VerificationURL = verificationURL
+			return p, nil
+		}
+	}
+	return nil, ErrPersonNotFound
+}
+
+// identity reads who a person is from the session linked to them.
+func (s *Service) identity(ctx context.Context, person *models.CompanyPerson) (PersonIdentity, error) {
+	if person.SessionID == "" {
+		return PersonIdentity{}, ErrIdentityGone
+	}
+	session, err := s.sessions.GetSessionBySessionID(ctx, person.SessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return PersonIdentity{}, ErrIdentityGone
+	}
+	if err != nil {
+		return PersonIdentity{}, err
+	}
+	if session.UserEmail == "" {
+		return PersonIdentity{}, ErrIdentityGone
+	}
+	return PersonIdentity{
+		Email:     session.UserEmail,
+		FirstName: session.UserFirstName,
+		LastName:  session.UserLastName,
+		CPF:       session.UserCPF,
+	}, nil
+}
+
+// start opens a verification for a person through the same path as the
+// public start endpoint and links it. A person already holding the level is
+// linked to that approval and gets no link.
+func (s *Service) start(ctx context.Context, person *models.CompanyPerson, identity PersonIdentity, actor string) (string, error) {
+	response, err := s.starter.Start(ctx, models.VerificationRequest{
+		UserID:    person.UserID,
+		Email:     identity.Email,
+		FirstName: identity.FirstName,
+		LastName:  identity.LastName,
+		Level:     string(s.level),
+		CPF:       identity.CPF,
+	}, actor)
+
+	var held *kyc.LevelHeldError
+	var sessionID, verificationURL string
+	switch {
+	case errors.As(err, &held):
+		sessionID = held.SessionID
+	case err != nil:
+		return "", err
+	default:
+		sessionID, verificationURL = response.SessionID, response.VerificationURL
+	}
+
+	if err := s.repo.SetPersonSession(ctx, person.ID, sessionID); err != nil {
+		return "", err
+	}
+	person.SessionID = sessionID
+	return verificationURL, nil
+}
+
+// Status computes the aggregate status of a company from its people and
+// records it when it changed.
+func (s *Service) Status(ctx context.Context, companyID uuid.UUID) (*CompanyStatus, error) {
+	company, err := s.repo.GetCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company == nil {
+		return nil, ErrCompanyNotFound
+	}
+
+	people, err := s.repo.ListPeople(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CompanyStatus{Company: company, People: make([]*PersonStatus, len(people))}
+	review, actionRequired := false, false
+	for i, person := range people {
+		status, err := s.personStatus(ctx, person)
+		if err != nil {
+			return nil, err
+		}
+		result.People[i] = &PersonStatus{CompanyPerson: person, Status: status}
+
+		if !person.Required {
+			continue
+		}
+		result.RequiredPeople++
+		switch status {
+		case StatusApproved:
+			result.ApprovedPeople++
+		case StatusReview:
+			review = true
+		case "pending":
+		default:
+			actionRequired = true
+		}
+	}
+
+	aggregate := StatusPending
+	switch {
+	case result.RequiredPeople > 0 && result.ApprovedPeople == result.RequiredPeople:
+		aggregate = StatusApproved
+	case actionRequired:
+		aggregate = StatusActionRequired
+	case review:
+		aggregate = StatusReview
+	}
+
+	if aggregate != company.Status {
+		if err := s.recordStatus(ctx, result, aggregate); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) recordStatus(ctx context.Context, result *CompanyStatus, status string) error {
+	previous := result.Status
+	updated, err := s.repo.UpdateCompanyStatus(ctx, result.ID, status)
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		// Another replica recorded it first.
+		result.Status = status
+		return nil
+	}
+	result.Company = updated
+
+	_, err = s.audit.Record(ctx, models.AuditEvent{
+		Action:        models.AuditCompanyStatusChanged,
+		SubjectUserID: updated.OwnerUserID,
+		Actor:         Actor,
+		EntityType:    "company",
+		EntityID:      updated.ID.String(),
+		Details: map[string]any{
+			"from_status":     previous,
+			"to_status":       status,
+			"required_people": result.RequiredPeople,
+			"approved_people": result.ApprovedPeople,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to audit status change of company %s: %v", updated.ID, err)
+	}
+
+	log.Printf("Company %s %s -> %s (%d/%d people approved)", updated.ID, previous, status, result.ApprovedPeople, result.RequiredPeople)
+	return nil
+}
+
+// personStatus is approved when the person holds the required level, even
+// through a verification done outside this company, and otherwise the
+// status of the linked session.
+func (s *Service) personStatus(ctx context.Context, person *models.CompanyPerson) (string, error) {
+	effective, err := s.levels.EffectiveSession(ctx, person.UserID)
+	if err != nil {
+		return "", err
+	}
+	if effective != nil && kyc.Rank(kycgate.Level(effective.Level)) >= kyc.Rank(s.level) {
+		return StatusApproved, nil
+	}
+
+	if person.SessionID == "" {
+		return PersonNotStarted, nil
+	}
+	session, err := s.sessions.GetSessionBySessionID(ctx, person.SessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return PersonNotStarted, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if session.Status == "approved" {
+		// Approved but no longer effective, like an upgrade whose base lapsed.
+		return "expired", nil
+	}
+	return session.Status, nil
+}
+
+// Refresh recomputes the companies a user is linked to after one of their
+// sessions changed status.
+func (s *Service) Refresh(ctx context.Context, userID string) error {
+	ids, err := s.repo.ListCompanyIDsByPerson(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := s.Status(ctx, id); err != nil {
+			return fmt.Errorf("failed to refresh company %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// RefreshAll recomputes every company, catching up with status changes that
+// were never notified, like those made while the listener reconnected.
+// Failures are logged and the sweep goes on.
+func (s *Service) RefreshAll(ctx context.Context) error {
+	ids, err := s.repo.ListCompanyIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.Status(ctx, id); err != nil {
+			log.Printf("Failed to refresh company %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) validatePeople(people []PersonRequest) error {
+	owners, self := 0, 0
+	total := 0.0
+	for _, p := range people {
+		if p.Self {
+			self++
+		}
+		switch p.Role {
+		case models.RoleUBO:
+			owners++
+		case models.RoleLegalRepresentative:
+		default:
+			return fmt.Errorf("%w: unknown role %q", ErrInvalidRequest, p.Role)
+		}
+		if p.OwnershipPercent != nil {
+			if *p.OwnershipPercent <= 0 || *p.OwnershipPercent > 100 {
+				return fmt.Errorf("%w: ownership_percent must be in (0, 100]", ErrInvalidRequest)
+			}
+			total += *p.OwnershipPercent
+		}
+		if p.CPF != "" && !kyc.ValidCPF(kyc.NormalizeCPF(p.CPF)) {
+			return fmt.Errorf("%w: invalid cpf", ErrInvalidRequest)
+		}
+	}
+	if self > 1 {
+		return fmt.Errorf("%w: only one person can be the owner", ErrInvalidRequest)
+	}
+	if owners == 0 {
+		return fmt.Errorf("%w: at least one ultimate beneficial owner is required", ErrInvalidRequest)
+	}
+	if total > 100 {
+		return fmt.Errorf("%w: ownership adds up to more than 100%%", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// required is true for legal representatives and for owners whose stake is
+// unknown or at least the threshold, whatever the request says. Only owners
+// below the threshold follow Required, which defaults to false for them.
+func (s *Service) required(p PersonRequest) bool {
+	if p.Role == models.RoleUBO && p.OwnershipPercent != nil && *p.OwnershipPercent < s.uboThreshold {
+		return p.Required != nil && *p.Required
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package kyc
+
+import "strings"
+
+// NormalizeCPF keeps only the digits of a CPF, so "123.456.789-09" and
+// "12345678909" compare equal.
+func NormalizeCPF(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// ValidCPF checks the length and both mod 11 check digits of a normalized
+// CPF. Sequences of one repeated digit pass the checksum but are never issued.
+func ValidCPF(cpf string) bool {
+	if len(cpf) != 11 {
+		return false
+	}
+
+	digits := make([]int, 11)
+	allSame := true
+	for i := 0; i < 11; i++ {
+		if cpf[i] < '0' || cpf[i] > '9' {
+			return false
+		}
+		digits[i] = int(cpf[i] - '0')
+		if cpf[i] != cpf[0] {
+			allSame = false
+		}
+	}
+	if allSame {
+		return false
+	}
+
+	return cpfCheckDigit(digits[:9]) == digits[9] && cpfCheckDigit(digits[:10]) == digits[10]
+}
+
+// cpfCheckDigit computes the next check digit with weights growing from 2 at
+// the rightmost digit.
+func cpfCheckDigit(digits []int) int {
+	sum := 0
+	for i, d := range digits {
+		sum += d * (len(digits) + 1 - i)
+	}
+	if r := sum % 11; r >= 2 {
+		return 11 - r
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package kyc
+
+import "testing"
+
+func TestValidCPF(t *testing.T) {
+	tests := []struct {
+		name string
+		cpf  string
+		want bool
+	}{
+		{"valid", "52998224725", true},
+		{"another valid", "11144477735", true},
+		{"valid with zero check digit", "12345678909", true},
+		{"formatted", "529.982.247-25", false},
+		{"bad first check digit", "52998224735", false},
+		{"bad second check digit", "52998224726", false},
+		{"repeated digits with matching check digits", "11111111111", false},
+		{"repeated zeros", "00000000000", false},
+		{"letter", "5299822472A", false},
+		{"too short", "5299822472", false},
+		{"too long", "529982247250", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidCPF(tt.cpf); got != tt.want {
+				t.Errorf("ValidCPF(%q) = %v, want %v", tt.cpf, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCPF(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"529.982.247-25", "52998224725"},
+		{" 529 982 247 25 ", "52998224725"},
+		{"52998224725", "52998224725"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCPF(tt.in); got != tt.want {
+			t.Errorf("NormalizeCPF(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
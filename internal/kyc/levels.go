@@ -0,0 +1,171 @@
+package kyc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/pkg/kycgate"
+)
+
+// Levels are the KYC tiers this service verifies, lowest first.
+var Levels = kycgate.DefaultLevels
+
+// ParseLevel validates a requested level. An empty string means basic.
+func ParseLevel(s string) (kycgate.Level, error) {
+	if s == "" {
+		return kycgate.LevelBasic, nil
+	}
+	level := kycgate.Level(s)
+	if !slices.Contains(Levels, level) {
+		return "", fmt.Errorf("unknown KYC level %q", s)
+	}
+	return level, nil
+}
+
+// Rank orders levels; unknown levels rank below basic.
+func Rank(level kycgate.Level) int {
+	return slices.Index(Levels, level)
+}
+
+// EffectiveLevel is the highest KYC level a user currently holds.
+type EffectiveLevel struct {
+	UserID     string        `json:"user_id"`
+	Level      kycgate.Level `json:"level,omitempty"`
+	SessionID  string        `json:"session_id,omitempty"`
+	ApprovedAt *time.Time    `json:"approved_at,omitempty"`
+	NextLevel  kycgate.Level `json:"next_level,omitempty"`
+}
+
+// Service resolves the effective KYC level of users from their sessions.
+type Service struct {
+	repo *repository.VerificationRepository
+}
+
+func NewService(repo *repository.VerificationRepository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// EffectiveSession returns the approved session granting the user's highest
+// level, or nil when nothing was approved. An upgrade only counts while the
+// approval it built on still stands, so revoking a basic approval also
+// revokes the full approval stacked on top of it.
+func (s *Service) EffectiveSession(ctx context.Context, userID string) (*models.VerificationSession, error) {
+	approved, err := s.repo.ListApprovedSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return Effective(approved), nil
+}
+
+// Effective picks the highest valid approval out of a user's approved
+// sessions, preferring the most recent one within a level.
+func Effective(approved []*models.VerificationSession) *models.VerificationSession {
+	bySessionID := make(map[string]*models.VerificationSession, len(approved))
+	for _, session := range approved {
+		bySessionID[session.SessionID] = session
+	}
+
+	valid := func(session *models.VerificationSession) bool {
+		seen := make(map[string]bool)
+		for session.BaseSessionID != "" {
+			if seen[session.SessionID] {
+				return false
+			}
+			seen[session.SessionID] = true
+
+			base, ok := bySessionID[session.BaseSessionID]
+			if !ok {
+				return false
+			}
+			session = base
+		}
+		return true
+	}
+
+	var best *models.VerificationSession
+	for _, session := range approved {
+		if !valid(session) {
+			continue
+		}
+		if best == nil || Rank(kycgate.Level(session.Level)) > Rank(kycgate.Level(best.Level)) {
+			best = session
+		}
+	}
+	return best
+}
+
+// BuildsOn reports whether session is, or was upgraded from, the session
+// with the given ID.
+func BuildsOn(approved []*models.VerificationSession, session *models.VerificationSession, sessionID string) bool {
+	bySessionID := make(map[string]*models.VerificationSession, len(approved))
+	for _, s := range approved {
+		bySessionID[s.SessionID] = s
+	}
+
+	for hops := 0; session != nil && hops <= len(approved); hops++ {
+		if session.SessionID == sessionID {
+			return true
+		}
+		session = bySessionID[session.BaseSessionID]
+	}
+	return false
+}
+
+// Level reports the user's effective level and the next one they can
+// upgrade to.
+func (s *Service) Level(ctx context.Context, userID string) (*EffectiveLevel, error) {
+	session, err := s.EffectiveSession(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EffectiveLevel{UserID: userID}
+	next := 0
+	if session != nil {
+		result.Level = kycgate.Level(session.Level)
+		result.SessionID = session.SessionID
+		result.ApprovedAt = session.CompletedAt
+		next = Rank(result.Level) + 1
+	}
+	if next < len(Levels) {
+		result.NextLevel = Levels[next]
+	}
+	return result, nil
+}
+
+// LatestDecision implements kycgate.Source: the highest valid approval, or
+// the latest terminal session when the user holds no level.
+func (s *Service) LatestDecision(ctx context.Context, userID string) (*kycgate.Decision, error) {
+	session, err := s.EffectiveSession(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		session, err = s.repo.GetLatestTerminalSession(ctx, userID)
+		if err != nil || session == nil {
+			return nil, err
+		}
+	}
+	return Decision(session), nil
+}
+
+// Decision converts a decided session into the kycgate representation.
+func Decision(session *models.VerificationSession) *kycgate.Decision {
+	decision := &kycgate.Decision{
+		UserID:    session.UserID,
+		SessionID: session.SessionID,
+		Status:    session.Status,
+		Level:     kycgate.Level(session.Level),
+		DecidedAt: session.UpdatedAt,
+	}
+	if session.CompletedAt != nil {
+		decision.DecidedAt = *session.CompletedAt
+	}
+	return decision
+}
@@ -0,0 +1,105 @@
+package kyc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/google/uuid"
+)
+
+// MockSignatureHeader carries the HMAC-SHA256 of mock webhooks.
+const MockSignatureHeader = "X-Mock-Signature"
+
+// MockProvider is a provider for development and load tests. Every
+// session it opens reaches the configured decision, and its webhooks use the
+// same JSON as models.WebhookEvent. Sessions are kept in memory.
+type MockProvider struct {
+	name     string
+	baseURL  string
+	secret   string
+	decision string
+	// allowUnsigned accepts webhooks unchecked when there is no secret.
+	allowUnsigned bool
+
+	mu       sync.Mutex
+	sessions map[string]SessionRequest
+}
+
+func NewMockProvider(name string, baseURL string, webhookSecret string, decision string, allowUnsigned bool) *MockProvider {
+	return &MockProvider{
+		name:          name,
+		baseURL:       baseURL,
+		secret:        webhookSecret,
+		decision:      decision,
+		allowUnsigned: allowUnsigned,
+		sessions:      make(map[string]SessionRequest),
+	}
+}
+
+func (m *MockProvider) Name() string {
+	return m.name
+}
+
+func (m *MockProvider) CreateSession(ctx context.Context, req SessionRequest) (*ProviderSession, error) {
+	id := "mock_" + uuid.New().String()
+	m.mu.Lock()
+	m.sessions[id] = req
+	m.mu.Unlock()
+	verificationURL := fmt.Sprintf("%s?session_id=%s&user_id=%s", m.baseURL, url.QueryEscape(id), url.QueryEscape(req.UserID))
+	if req.CallbackURL != "" {
+		verificationURL += "&callback=" + url.QueryEscape(req.CallbackURL)
+	}
+	return &ProviderSession{
+		Provider: m.name,
+		ID:       id,
+		URL:      verificationURL,
+	}, nil
+}
+
+func (m *MockProvider) FetchDecision(ctx context.Context, providerSessionID string) (*models.WebhookEvent, error) {
+	m.mu.Lock()
+	req, ok := m.sessions[providerSessionID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrProviderSessionNotFound
+	}
+
+	return &models.WebhookEvent{
+		EventType: EventTypeForStatus(m.decision),
+		Data: models.WebhookData{
+			SessionID: providerSessionID,
+			Status:    m.decision,
+			UserID:    req.UserID,
+			Metadata:  map[string]interface{}{"session_id": req.SessionID},
+		},
+		Timestamp: time.Now().UTC(),
+	}, nil
+}
+
+// VerifyWebhook checks the hex HMAC-SHA256 of the body. Without a secret
+// every webhook is rejected, unless unsigned webhooks are allowed.
+func (m *MockProvider) VerifyWebhook(ctx context.Context, payload []byte, header http.Header) bool {
+	if m.secret == "" {
+		return m.allowUnsigned
+	}
+	h := hmac.New(sha256.New, []byte(m.secret))
+	h.Write(payload)
+	return hmac.Equal([]byte(header.Get(MockSignatureHeader)), []byte(hex.EncodeToString(h.Sum(nil))))
+}
+
+func (m *MockProvider) ParseWebhook(payload []byte) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse mock webhook: %v", err)
+	}
+	return &event, nil
+}
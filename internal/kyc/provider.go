@@ -0,0 +1,98 @@
+package kyc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+)
+
+var (
+	// ErrProviderSessionNotFound is returned by FetchDecision for a session
+	// the provider does not know.
+	ErrProviderSessionNotFound = errors.New("provider session not found")
+	// ErrLevelNotSupported is returned by CreateSession when the provider
+	// has no workflow for the level. It is a configuration error, not a
+	// provider failure.
+	ErrLevelNotSupported = errors.New("KYC level not supported by provider")
+)
+
+// SessionRequest is what a provider needs to open a verification session.
+type SessionRequest struct {
+	// SessionID is our id of the session, sent along as metadata.
+	SessionID string
+	UserID    string
+	Email     string
+	FirstName string
+	LastName  string
+	Level     string
+	// Upgrade is set when the user already holds a lower level.
+	Upgrade bool
+	// Country is the ISO 3166-1 alpha-2 country of the user, if known.
+	Country string
+	// CallbackURL is where the provider should send the user back to once
+	// they finish, if it supports a per-session redirect.
+	CallbackURL string
+}
+
+// ProviderSession is a session opened at a provider.
+type ProviderSession struct {
+	Provider string
+	// ID is the provider's id of the session. It is empty for fixed links,
+	// whose id is only learned from the first webhook.
+	ID  string
+	URL string
+}
+
+// Provider is a KYC vendor. Webhooks and decisions are normalized to
+// models.WebhookEvent so the processor handles every provider alike.
+type Provider interface {
+	// Name identifies the provider in routing rules, webhook URLs and on
+	// stored sessions.
+	Name() string
+	CreateSession(ctx context.Context, req SessionRequest) (*ProviderSession, error)
+	// FetchDecision returns the current state of a session as an event. The
+	// event type is empty while the decision is not final.
+	FetchDecision(ctx context.Context, providerSessionID string) (*models.WebhookEvent, error)
+	// VerifyWebhook checks the signature of a webhook with the credentials
+	// of the tenant in ctx.
+	VerifyWebhook(ctx context.Context, payload []byte, header http.Header) bool
+	ParseWebhook(payload []byte) (*models.WebhookEvent, error)
+}
+
+// APIStats counts the calls of a provider's API client since startup.
+type APIStats struct {
+	Requests uint64 `json:"requests"`
+	Retries  uint64 `json:"retries"`
+	// Errors counts failed calls by kind: unauthorized, rate_limited,
+	// not_found, server, network, timeout, circuit_open or other.
+	Errors map[string]uint64 `json:"errors"`
+	// Circuit is the state of the circuit breaker: closed, open or
+	// half_open.
+	Circuit   string     `json:"circuit"`
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+}
+
+// StatsReporter is implemented by providers whose API client keeps APIStats.
+type StatsReporter interface {
+	APIStats() APIStats
+}
+
+// EventTypeForStatus maps a final session status to the event type the
+// processor understands. It is empty for statuses that are not final.
+func EventTypeForStatus(status string) string {
+	switch status {
+	case "approved":
+		return "verification.approved"
+	case "failed":
+		return "verification.failed"
+	case "rejected":
+		return "verification.rejected"
+	case "review":
+		return "verification.review"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,320 @@
+package kyc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Failover defaults used when the routing file leaves them out.
+const (
+	defaultFailoverFailures = 3
+	defaultFailoverCooldown = 5 * time.Minute
+)
+
+// ErrUnknownProvider is returned for a provider name that is not registered.
+var ErrUnknownProvider = errors.New("unknown KYC provider")
+
+// Routing is the parsed routing file.
+type Routing struct {
+	// Default verifies sessions no route matches.
+	Default string `yaml:"default" json:"default"`
+	// Fallback lists the providers tried, in order, when the routed one
+	// fails to open a session or is cooling down.
+	Fallback []string `yaml:"fallback" json:"fallback"`
+	Routes   []Route  `yaml:"routes" json:"routes"`
+	Failover Failover `yaml:"failover" json:"failover"`
+}
+
+// Route sends matching sessions to a provider. Empty conditions match
+// everything. Percent routes split users by a stable hash of their id: their
+// shares add up in file order, so 10 then 20 send the first 10% of users to
+// one provider and the next 20% to the other.
+type Route struct {
+	Level    string `yaml:"level" json:"level,omitempty"`
+	Country  string `yaml:"country" json:"country,omitempty"`
+	Percent  int    `yaml:"percent" json:"percent,omitempty"`
+	Provider string `yaml:"provider" json:"provider"`
+}
+
+// Failover takes a provider out of rotation for Cooldown after Failures
+// consecutive failed attempts to open a session.
+type Failover struct {
+	Failures int    `yaml:"failures" json:"failures"`
+	Cooldown string `yaml:"cooldown" json:"cooldown"`
+
+	cooldown time.Duration
+}
+
+// ProviderStatus is the failover state of a provider.
+type ProviderStatus struct {
+	Name                string     `json:"name"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DownUntil           *time.Time `json:"down_until,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	// API is the health of the provider's API client, for providers that
+	// report it.
+	API *APIStats `json:"api,omitempty"`
+}
+
+type providerHealth struct {
+	failures  int
+	downUntil time.Time
+	lastError string
+}
+
+// Router picks the provider of each new session and fails over when a
+// provider keeps failing to open sessions.
+type Router struct {
+	providers map[string]Provider
+	names     []string
+
+	mu      sync.Mutex
+	routing *Routing
+	health  map[string]*providerHealth
+}
+
+// NewRouter registers the providers. Until a routing file is loaded every
+// session goes to the first one.
+func NewRouter(providers ...Provider) *Router {
+	r := &Router{
+		providers: make(map[string]Provider),
+		health:    make(map[string]*providerHealth),
+	}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+		r.names = append(r.names, p.Name())
+		r.health[p.Name()] = &providerHealth{}
+	}
+	r.routing = &Routing{
+		Default:  r.names[0],
+		Failover: Failover{Failures: defaultFailoverFailures, cooldown: defaultFailoverCooldown},
+	}
+	return r
+}
+
+// Load reads and validates a routing file. An empty path keeps routing
+// everything to the first provider.
+func (r *Router) Load(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read KYC routing: %v", err)
+	}
+	routing, err := r.ParseRouting(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.routing = routing
+	r.mu.Unlock()
+	return nil
+}
+
+// ParseRouting parses the YAML of a routing file and checks that every
+// provider it names is registered.
+func (r *Router) ParseRouting(data []byte) (*Routing, error) {
+	var routing Routing
+	if err := yaml.Unmarshal(data, &routing); err != nil {
+		return nil, fmt.Errorf("failed to parse KYC routing: %v", err)
+	}
+
+	if routing.Default == "" {
+		routing.Default = r.names[0]
+	}
+	if _, ok := r.providers[routing.Default]; !ok {
+		return nil, fmt.Errorf("%w: default %q", ErrUnknownProvider, routing.Default)
+	}
+	for _, name := range routing.Fallback {
+		if _, ok := r.providers[name]; !ok {
+			return nil, fmt.Errorf("%w: fallback %q", ErrUnknownProvider, name)
+		}
+	}
+	total := 0
+	for i := range routing.Routes {
+		route := &routing.Routes[i]
+		if _, ok := r.providers[route.Provider]; !ok {
+			return nil, fmt.Errorf("%w: route %d sends to %q", ErrUnknownProvider, i+1, route.Provider)
+		}
+		if route.Level != "" {
+			if _, err := ParseLevel(route.Level); err != nil {
+				return nil, fmt.Errorf("KYC route %d: %v", i+1, err)
+			}
+		}
+		route.Country = strings.ToUpper(route.Country)
+		if route.Percent < 0 || route.Percent > 100 {
+			return nil, fmt.Errorf("KYC route %d: percent must be between 0 and 100", i+1)
+		}
+		total += route.Percent
+	}
+	if total > 100 {
+		return nil, fmt.Errorf("KYC route percentages add up to more than 100")
+	}
+
+	if routing.Failover.Failures <= 0 {
+		routing.Failover.Failures = defaultFailoverFailures
+	}
+	routing.Failover.cooldown = defaultFailoverCooldown
+	if routing.Failover.Cooldown != "" {
+		cooldown, err := time.ParseDuration(routing.Failover.Cooldown)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KYC failover cooldown: %v", err)
+		}
+		routing.Failover.cooldown = cooldown
+	}
+	return &routing, nil
+}
+
+// Routing returns the routing in use.
+func (r *Router) Routing() *Routing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.routing
+}
+
+// Provider returns a registered provider by name.
+func (r *Router) Provider(name string) (Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Route names the provider the routing picks for a request, ignoring
+// failover.
+func (r *Router) Route(req SessionRequest) string {
+	routing := r.Routing()
+
+	bucket := userBucket(req.UserID)
+	share := 0
+	for _, route := range routing.Routes {
+		if route.Level != "" && route.Level != req.Level {
+			continue
+		}
+		if route.Country != "" && route.Country != strings.ToUpper(req.Country) {
+			continue
+		}
+		if route.Percent > 0 {
+			share += route.Percent
+			if bucket >= share {
+				continue
+			}
+		}
+		return route.Provider
+	}
+	return routing.Default
+}
+
+// CreateSession opens a session at the routed provider, trying the fallback
+// providers when it fails. Providers cooling down after repeated failures
+// are skipped, unless every candidate is.
+func (r *Router) CreateSession(ctx context.Context, req SessionRequest) (*ProviderSession, error) {
+	routed := r.Route(req)
+	candidates := []string{routed}
+	for _, name := range r.Routing().Fallback {
+		if !slices.Contains(candidates, name) {
+			candidates = append(candidates, name)
+		}
+	}
+
+	available := make([]string, 0, len(candidates))
+	for _, name := range candidates {
+		if r.available(name) {
+			available = append(available, name)
+		}
+	}
+	if len(available) == 0 {
+		available = candidates[:1]
+	}
+
+	var lastErr error
+	for _, name := range available {
+		session, err := r.providers[name].CreateSession(ctx, req)
+		if err == nil {
+			r.recordSuccess(name)
+			if name != routed {
+				log.Printf("KYC session of user %s failed over from %s to %s", req.UserID, routed, name)
+			}
+			return session, nil
+		}
+		if !errors.Is(err, ErrLevelNotSupported) {
+			r.recordFailure(name, err)
+		}
+		lastErr = fmt.Errorf("provider %s: %w", name, err)
+	}
+	return nil, lastErr
+}
+
+// Status reports the failover state of every provider.
+func (r *Router) Status() []ProviderStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]ProviderStatus, 0, len(r.names))
+	for _, name := range r.names {
+		h := r.health[name]
+		status := ProviderStatus{
+			Name:                name,
+			ConsecutiveFailures: h.failures,
+			LastError:           h.lastError,
+		}
+		if reporter, ok := r.providers[name].(StatsReporter); ok {
+			stats := reporter.APIStats()
+			status.API = &stats
+		}
+		if time.Now().Before(h.downUntil) {
+			downUntil := h.downUntil
+			status.DownUntil = &downUntil
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (r *Router) available(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !time.Now().Before(r.health[name].downUntil)
+}
+
+func (r *Router) recordSuccess(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health[name].failures = 0
+	r.health[name].lastError = ""
+}
+
+func (r *Router) recordFailure(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health[name]
+	h.failures++
+	h.lastError = err.Error()
+	if h.failures >= r.routing.Failover.Failures {
+		h.downUntil = time.Now().Add(r.routing.Failover.cooldown)
+		h.failures = 0
+		log.Printf("KYC provider %s failed to open sessions %d times in a row; out of rotation until %s: %v",
+			name, r.routing.Failover.Failures, h.downUntil.Format(time.RFC3339), err)
+	}
+}
+
+// userBucket places a user in one of 100 buckets, the same on every replica
+// and across restarts.
+func userBucket(userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	return int(h.Sum32() % 100)
+}
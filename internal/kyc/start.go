@@ -0,0 +1,242 @@
+package kyc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/links"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/FelipePn10/crispaybackend/pkg/kycgate"
+	"github.com/google/uuid"
+)
+
+// Errors returned by Start.
+var (
+	ErrInvalidRequest = errors.New("invalid verification request")
+	ErrDailyLimit     = errors.New("daily verification attempt limit reached")
+	ErrCPFInUse       = errors.New("cpf already belongs to another user")
+	// ErrProviderFailed wraps the error of the provider that could not open
+	// the session, which stays reachable with errors.Is and errors.As.
+	ErrProviderFailed = errors.New("failed to open provider session")
+)
+
+// LevelHeldError is returned by Start when the user already holds the
+// requested level or a higher one.
+type LevelHeldError struct {
+	Level     kycgate.Level
+	SessionID string
+}
+
+func (e *LevelHeldError) Error() string {
+	return fmt.Sprintf("user already holds KYC level %s", e.Level)
+}
+
+// Starter opens verification sessions. It backs the public start endpoint
+// and the flows that verify people on someone else's behalf, like KYB.
+type Starter struct {
+	cfg       *config.Config
+	repo      *repository.VerificationRepository
+	levels    *Service
+	providers *Router
+	links     *links.Signer
+}
+
+func NewStarter(cfg *config.Config, repo *repository.VerificationRepository, levels *Service, providers *Router, signer *links.Signer) *Starter {
+	return &Starter{
+		cfg:       cfg,
+		repo:      repo,
+		levels:    levels,
+		providers: providers,
+		links:     signer,
+	}
+}
+
+// InTx returns a copy of the starter writing through repo, so the sessions it
+// creates join the transaction repo is bound to.
+func (s *Starter) InTx(repo *repository.VerificationRepository) *Starter {
+	tx := *s
+	tx.repo = repo
+	return &tx
+}
+
+// Start opens a session at the requested level with the provider the router
+// picks. Users holding a lower level are sent to the upgrade workflow and
+// the new session builds on their approval. A pending session younger than
+// the configured TTL is handed back instead of creating a new one, and users
+// are capped to a number of sessions per day. A CPF, when given, is claimed
+// for the user so no other user can verify with it. The provider's link is
+// never handed out: the response carries a signed link resolving to it.
+func (s *Starter) Start(ctx context.Context, req models.VerificationRequest, actor string) (*models.VerificationResponse, error) {
+	level, err := ParseLevel(req.Level)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	cpf := NormalizeCPF(req.CPF)
+	switch {
+	case cpf == "" && s.cfg.CPFRequired:
+		return nil, fmt.Errorf("%w: cpf is required", ErrInvalidRequest)
+	case req.CPF != "" && !ValidCPF(cpf):
+		return nil, fmt.Errorf("%w: invalid cpf", ErrInvalidRequest)
+	}
+
+	now := time.Now()
+
+	effective, err := s.levels.EffectiveSession(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up KYC level: %v", err)
+	}
+	if effective != nil && Rank(kycgate.Level(effective.Level)) >= Rank(level) {
+		return nil, &LevelHeldError{Level: kycgate.Level(effective.Level), SessionID: effective.SessionID}
+	}
+
+	var baseSessionID string
+	if effective != nil {
+		baseSessionID = effective.SessionID
+	}
+
+	// The pending session lookup, the daily cap and the new session hold a
+	// per-user lock, so concurrent starts neither exceed the cap nor open
+	// two sessions.
+	var response *models.VerificationResponse
+	err = s.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		if err := repo.LockUserSessions(ctx, req.UserID); err != nil {
+			return err
+		}
+		response, err = s.InTx(repo).open(ctx, req, level, cpf, baseSessionID, actor, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// open reuses the user's pending session or, within the daily cap, opens a
+// new one. It runs under the lock Start takes.
+func (s *Starter) open(ctx context.Context, req models.VerificationRequest, level kycgate.Level, cpf string, baseSessionID string, actor string, now time.Time) (*models.VerificationResponse, error) {
+	if s.cfg.VerificationSessionTTL > 0 {
+		pending, err := s.repo.GetLatestPendingSession(ctx, req.UserID, string(level), now.Add(-s.cfg.VerificationSessionTTL))
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up pending session: %v", err)
+		}
+		// A pending session started with another CPF cannot be cross-checked
+		// against this one, so it is not reused.
+		if pending != nil && pending.UserCPF == cpf && pending.VerificationURL != "" {
+			log.Printf("Reusing pending %s verification session %s for user %s", level, pending.SessionID, req.UserID)
+			link, expiresAt := s.links.VerificationLink(pending.SessionID, tenant.ID(ctx))
+			return &models.VerificationResponse{
+				VerificationURL: link,
+				ExpiresAt:       expiresAt,
+				UserID:          req.UserID,
+				SessionID:       pending.SessionID,
+				Level:           pending.Level,
+				BaseSessionID:   pending.BaseSessionID,
+				Reused:          true,
+			}, nil
+		}
+	}
+
+	if s.cfg.VerificationMaxAttemptsPerDay > 0 {
+		attempts, err := s.repo.CountSessionsSince(ctx, req.UserID, now.Add(-24*time.Hour))
+		if err != nil {
+			return nil, fmt.Errorf("failed to count verification attempts: %v", err)
+		}
+		if attempts >= s.cfg.VerificationMaxAttemptsPerDay {
+			log.Printf("User %s reached the daily verification limit (%d)", req.UserID, attempts)
+			return nil, ErrDailyLimit
+		}
+	}
+
+	if cpf != "" {
+		holder, err := s.repo.ClaimCPF(ctx, cpf, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if holder != req.UserID {
+			log.Printf("User %s tried to verify with a CPF held by user %s", req.UserID, holder)
+			return nil, ErrCPFInUse
+		}
+	}
+
+	sessionID := uuid.New().String()
+
+	opened, err := s.providers.CreateSession(ctx, SessionRequest{
+		SessionID:   sessionID,
+		UserID:      req.UserID,
+		Email:       req.Email,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		Level:       string(level),
+		Upgrade:     baseSessionID != "",
+		Country:     req.Country,
+		CallbackURL: s.links.CallbackURL(sessionID, tenant.ID(ctx)),
+	})
+	if errors.Is(err, ErrLevelNotSupported) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderFailed, err)
+	}
+
+	// Create a session in the database.
+	session := &models.VerificationSession{
+		UserID:          req.UserID,
+		SessionID:       sessionID,
+		Provider:        opened.Provider,
+		DiditSessionID:  opened.ID,
+		Status:          "pending",
+		Level:           string(level),
+		BaseSessionID:   baseSessionID,
+		UserEmail:       req.Email,
+		UserFirstName:   req.FirstName,
+		UserLastName:    req.LastName,
+		UserCPF:         cpf,
+		VerificationURL: opened.URL,
+	}
+
+	details := map[string]any{"level": level, "provider": opened.Provider}
+	if baseSessionID != "" {
+		details["base_session_id"] = baseSessionID
+	}
+	// The session and its audit entry are written together, so the audit
+	// log has no gaps.
+	err = s.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		if _, err := repo.CreateSession(ctx, session); err != nil {
+			return fmt.Errorf("failed to create session: %v", err)
+		}
+		_, err := repository.NewAuditRepository(repo.Queries()).Record(ctx, models.AuditEvent{
+			Action:        models.AuditSessionCreated,
+			SubjectUserID: req.UserID,
+			Actor:         actor,
+			EntityType:    "session",
+			EntityID:      sessionID,
+			Details:       details,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to audit session creation: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Verification session %s created for user %s with %s", sessionID, req.UserID, opened.Provider)
+
+	link, expiresAt := s.links.VerificationLink(sessionID, tenant.ID(ctx))
+	return &models.VerificationResponse{
+		VerificationURL: link,
+		ExpiresAt:       expiresAt,
+		UserID:          req.UserID,
+		SessionID:       sessionID,
+		Level:           string(level),
+		BaseSessionID:   baseSessionID,
+	}, nil
+}
@@ -0,0 +1,149 @@
+// Package links signs the short-lived links handed to users for a
+// verification session: the link opening the provider's verification page
+// and the callback the provider redirects the user back to.
+package links
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors returned by Parse.
+var (
+	ErrInvalid = errors.New("invalid link")
+	ErrExpired = errors.New("link expired")
+)
+
+// Purpose tells what a token was signed for, so a token cannot be used for
+// another route.
+type Purpose string
+
+const (
+	PurposeVerification Purpose = "verification"
+	PurposeCallback     Purpose = "callback"
+	PurposeMedia        Purpose = "media"
+)
+
+// Claims are the contents of a signed token. Media links also name the file
+// and the reviewer they were issued to.
+type Claims struct {
+	Purpose   Purpose   `json:"p"`
+	SessionID string    `json:"s"`
+	TenantID  uuid.UUID `json:"t"`
+	ExpiresAt int64     `json:"e"`
+	MediaID   string    `json:"m,omitempty"`
+	Actor     string    `json:"a,omitempty"`
+}
+
+// Signer signs and parses link tokens with an HMAC-SHA256 key. Tokens are
+// the base64url claims followed by a dot and their base64url signature.
+type Signer struct {
+	key         []byte
+	baseURL     string
+	ttl         time.Duration
+	callbackTTL time.Duration
+}
+
+// NewSigner returns a signer for links under baseURL, the public URL of this
+// API. Verification links last ttl; callbacks last callbackTTL, as the user
+// only comes back after finishing the verification.
+func NewSigner(key []byte, baseURL string, ttl time.Duration, callbackTTL time.Duration) *Signer {
+	return &Signer{
+		key:         key,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		ttl:         ttl,
+		callbackTTL: callbackTTL,
+	}
+}
+
+// VerificationLink returns the /v/:token link resolving to the provider's
+// verification page of a session, and when it expires. Without a base URL
+// the link is relative to this API's host.
+func (s *Signer) VerificationLink(sessionID string, tenantID uuid.UUID) (string, time.Time) {
+	expiresAt := time.Now().Add(s.ttl).Truncate(time.Second)
+	token := s.Sign(Claims{
+		Purpose:   PurposeVerification,
+		SessionID: sessionID,
+		TenantID:  tenantID,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	return s.baseURL + "/v/" + token, expiresAt
+}
+
+// CallbackURL returns the URL providers redirect the user to once they
+// finish a session, or "" when no base URL is configured, as providers need
+// an absolute URL.
+func (s *Signer) CallbackURL(sessionID string, tenantID uuid.UUID) string {
+	if s.baseURL == "" {
+		return ""
+	}
+	token := s.Sign(Claims{
+		Purpose:   PurposeCallback,
+		SessionID: sessionID,
+		TenantID:  tenantID,
+		ExpiresAt: time.Now().Add(s.callbackTTL).Unix(),
+	})
+	return s.baseURL + "/api/verification/callback?token=" + url.QueryEscape(token)
+}
+
+// MediaLink returns the /media/:token link serving a verification file to the
+// reviewer actor for ttl, and when it expires.
+func (s *Signer) MediaLink(sessionID string, mediaID string, tenantID uuid.UUID, actor string, ttl time.Duration) (string, time.Time) {
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	token := s.Sign(Claims{
+		Purpose:   PurposeMedia,
+		SessionID: sessionID,
+		TenantID:  tenantID,
+		ExpiresAt: expiresAt.Unix(),
+		MediaID:   mediaID,
+		Actor:     actor,
+	})
+	return s.baseURL + "/media/" + token, expiresAt
+}
+
+// Sign returns the token of claims.
+func (s *Signer) Sign(claims Claims) string {
+	payload, _ := json.Marshal(claims)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded))
+}
+
+// Parse checks the signature, purpose and expiry of a token and returns its
+// claims.
+func (s *Signer) Parse(token string, purpose Purpose) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.mac(encoded)) {
+		return nil, ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Purpose != purpose || claims.SessionID == "" {
+		return nil, ErrInvalid
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return &claims, ErrExpired
+	}
+	return &claims, nil
+}
+
+func (s *Signer) mac(encoded string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(encoded))
+	return h.Sum(nil)
+}
@@ -0,0 +1,95 @@
+package links
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParse(t *testing.T) {
+	signer := NewSigner([]byte("test-key"), "https://api.example.com", time.Hour, time.Hour)
+	other := NewSigner([]byte("other-key"), "https://api.example.com", time.Hour, time.Hour)
+	tenantID := uuid.New()
+	future := time.Now().Add(time.Hour).Unix()
+
+	valid := Claims{Purpose: PurposeVerification, SessionID: "s1", TenantID: tenantID, ExpiresAt: future}
+	token := signer.Sign(valid)
+	encoded, signature, _ := strings.Cut(token, ".")
+
+	notJSON := base64.RawURLEncoding.EncodeToString([]byte("not json"))
+
+	// resign replaces the claims of a token, keeping its signature.
+	resign := func(claims string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(claims)) + "." + signature
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		purpose Purpose
+		wantErr error
+	}{
+		{"valid", token, PurposeVerification, nil},
+		{"media claims", signer.Sign(Claims{Purpose: PurposeMedia, SessionID: "s1", ExpiresAt: future, MediaID: "m1", Actor: "ana"}), PurposeMedia, nil},
+		{"expired", signer.Sign(Claims{Purpose: PurposeVerification, SessionID: "s1", ExpiresAt: time.Now().Add(-time.Second).Unix()}), PurposeVerification, ErrExpired},
+		{"expiring now", signer.Sign(Claims{Purpose: PurposeVerification, SessionID: "s1", ExpiresAt: time.Now().Unix()}), PurposeVerification, ErrExpired},
+		{"other purpose", token, PurposeCallback, ErrInvalid},
+		{"other key", other.Sign(valid), PurposeVerification, ErrInvalid},
+		{"claims changed", resign(`{"p":"verification","s":"s2","e":` + strconv.FormatInt(future, 10) + `}`), PurposeVerification, ErrInvalid},
+		{"signature changed", encoded + "." + base64.RawURLEncoding.EncodeToString([]byte("forged")), PurposeVerification, ErrInvalid},
+		{"signature not base64", encoded + ".***", PurposeVerification, ErrInvalid},
+		{"no signature", encoded, PurposeVerification, ErrInvalid},
+		{"no session", signer.Sign(Claims{Purpose: PurposeVerification, ExpiresAt: future}), PurposeVerification, ErrInvalid},
+		{"claims not json", notJSON + "." + base64.RawURLEncoding.EncodeToString(signer.mac(notJSON)), PurposeVerification, ErrInvalid},
+		{"empty", "", PurposeVerification, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := signer.Parse(tt.token, tt.purpose)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == ErrInvalid && claims != nil {
+				t.Errorf("Parse returned claims %+v for an invalid token", claims)
+			}
+			if tt.wantErr == ErrExpired && claims == nil {
+				t.Error("Parse returned no claims for an expired token")
+			}
+		})
+	}
+
+	claims, err := signer.Parse(token, PurposeVerification)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *claims != valid {
+		t.Errorf("Parse = %+v, want %+v", *claims, valid)
+	}
+}
+
+func TestLinksRoundTrip(t *testing.T) {
+	signer := NewSigner([]byte("test-key"), "https://api.example.com/", time.Hour, 24*time.Hour)
+	tenantID := uuid.New()
+
+	link, expiresAt := signer.VerificationLink("s1", tenantID)
+	token, ok := strings.CutPrefix(link, "https://api.example.com/v/")
+	if !ok {
+		t.Fatalf("VerificationLink = %q", link)
+	}
+	claims, err := signer.Parse(token, PurposeVerification)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SessionID != "s1" || claims.TenantID != tenantID || claims.ExpiresAt != expiresAt.Unix() {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if got := NewSigner([]byte("test-key"), "", time.Hour, time.Hour).CallbackURL("s1", tenantID); got != "" {
+		t.Errorf("CallbackURL without a base URL = %q, want empty", got)
+	}
+}
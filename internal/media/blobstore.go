@@ -0,0 +1,57 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+)
+
+// ErrBlobNotFound is returned by BlobStore.Get for keys that hold nothing.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps media files. FilesystemStore serves a single host;
+// S3Store works with AWS S3 and S3-compatible stores like MinIO. Blobs are
+// encrypted before they reach the store.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	// PutStream stores the blob read from r, holding only part of it in
+	// memory. Nothing is stored when reading r fails.
+	PutStream(ctx context.Context, key string, r io.Reader, contentType string) error
+	// GetStream opens a blob for reading; the caller closes it.
+	GetStream(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// StoreFromConfig builds the BlobStore selected by MEDIA_STORE, or nil when
+// media are not kept.
+func StoreFromConfig(cfg *config.Config) (BlobStore, error) {
+	switch cfg.MediaStore {
+	case "", "none":
+		return nil, nil
+	case "filesystem":
+		if cfg.MediaFilesystemDir == "" {
+			return nil, fmt.Errorf("MEDIA_FILESYSTEM_DIR is required for the filesystem media store")
+		}
+		return NewFilesystemStore(cfg.MediaFilesystemDir)
+	case "s3":
+		if cfg.MediaS3Endpoint == "" || cfg.MediaS3Bucket == "" {
+			return nil, fmt.Errorf("MEDIA_S3_ENDPOINT and MEDIA_S3_BUCKET are required for the s3 media store")
+		}
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.MediaS3Endpoint,
+			Region:    cfg.MediaS3Region,
+			Bucket:    cfg.MediaS3Bucket,
+			AccessKey: cfg.MediaS3AccessKey,
+			SecretKey: cfg.MediaS3SecretKey,
+			PathStyle: cfg.MediaS3PathStyle,
+			Timeout:   time.Minute,
+		})
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORE %q", cfg.MediaStore)
+	}
+}
@@ -0,0 +1,113 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FilesystemStore keeps blobs as files under a directory, readable only by
+// the service's user.
+type FilesystemStore struct {
+	dir string
+}
+
+func NewFilesystemStore(dir string) (*FilesystemStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create media directory: %v", err)
+	}
+	return &FilesystemStore{
+		dir: dir,
+	}, nil
+}
+
+func (s *FilesystemStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	return s.PutStream(ctx, key, bytes.NewReader(data), contentType)
+}
+
+// PutStream writes the blob to a temporary file first, so readers never see
+// a partial file.
+func (s *FilesystemStore) PutStream(_ context.Context, key string, r io.Reader, _ string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create media directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+	return nil
+}
+
+func (s *FilesystemStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+	return data, nil
+}
+
+func (s *FilesystemStore) GetStream(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+	return f, nil
+}
+
+func (s *FilesystemStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %v", err)
+	}
+	return nil
+}
+
+func (s *FilesystemStore) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
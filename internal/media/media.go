@@ -0,0 +1,366 @@
+// Package media keeps the document images, selfies and liveness videos of
+// verifications for the regulatory retention period. Files are downloaded
+// from the provider's decision, encrypted with the field encryption keys and
+// stored in a BlobStore. Reviewers read them through short-lived signed links
+// served by this API, and every access is audited.
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/fieldcrypt"
+	"github.com/FelipePn10/crispaybackend/internal/links"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Actor is the audit log actor of media captured from decisions.
+const Actor = "system:media"
+
+// Kinds of verification media.
+const (
+	KindIDFront       = "id_front"
+	KindIDBack        = "id_back"
+	KindSelfie        = "selfie"
+	KindLivenessVideo = "liveness_video"
+)
+
+// Errors returned by Open.
+var (
+	ErrNotFound  = errors.New("media not found")
+	ErrIntegrity = errors.New("media failed its integrity check")
+)
+
+// decisionMedia are the decision fields holding the provider's short-lived
+// URL of each kind of media.
+var decisionMedia = []struct {
+	kind    string
+	section string
+	field   string
+}{
+	{KindIDFront, "id_verification", "front_image"},
+	{KindIDBack, "id_verification", "back_image"},
+	{KindSelfie, "liveness", "reference_image"},
+	{KindLivenessVideo, "liveness", "video_url"},
+}
+
+// CaptureReport lists the media a capture stored, and why others failed.
+type CaptureReport struct {
+	Stored []string          `json:"stored"`
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// Link is a media file with a signed link to read it.
+type Link struct {
+	*models.VerificationMedia
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Service struct {
+	store    BlobStore
+	repo     *repository.MediaRepository
+	audit    *repository.AuditRepository
+	crypt    *fieldcrypt.Encryptor
+	links    *links.Signer
+	http     *http.Client
+	maxBytes int64
+	urlTTL   time.Duration
+}
+
+// NewService returns the media service. A nil store disables capture.
+func NewService(cfg *config.Config, store BlobStore, repo *repository.MediaRepository, audit *repository.AuditRepository, crypt *fieldcrypt.Encryptor, signer *links.Signer) *Service {
+	return &Service{
+		store:    store,
+		repo:     repo,
+		audit:    audit,
+		crypt:    crypt,
+		links:    signer,
+		http:     &http.Client{Timeout: time.Minute},
+		maxBytes: cfg.MediaMaxBytes,
+		urlTTL:   cfg.MediaURLTTL,
+	}
+}
+
+// Enabled reports whether media are kept.
+func (s *Service) Enabled() bool {
+	return s.store != nil
+}
+
+// Capture downloads the media referenced by a session's decision and keeps
+// those the session does not have yet. A file that cannot be downloaded does
+// not stop the others; it is reported in Failed.
+func (s *Service) Capture(ctx context.Context, session *models.VerificationSession, decision map[string]interface{}) (*CaptureReport, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("no media store configured")
+	}
+
+	existing, err := s.repo.ListSessionMedia(ctx, session.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(existing))
+	for _, m := range existing {
+		stored[m.Kind] = true
+	}
+
+	report := &CaptureReport{Stored: []string{}}
+	for _, dm := range decisionMedia {
+		if stored[dm.kind] {
+			continue
+		}
+		fields, _ := decision[dm.section].(map[string]interface{})
+		source, _ := fields[dm.field].(string)
+		if source == "" {
+			continue
+		}
+
+		if err := s.capture(ctx, session, dm.kind, source); err != nil {
+			log.Printf("Failed to capture %s of session %s: %v", dm.kind, session.SessionID, err)
+			if report.Failed == nil {
+				report.Failed = map[string]string{}
+			}
+			report.Failed[dm.kind] = err.Error()
+			continue
+		}
+		report.Stored = append(report.Stored, dm.kind)
+	}
+	return report, nil
+}
+
+func (s *Service) capture(ctx context.Context, session *models.VerificationSession, kind string, source string) error {
+	data, contentType, err := s.download(ctx, source)
+	if err != nil {
+		return err
+	}
+	blob, err := s.crypt.EncryptBlob(ctx, data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt media: %v", err)
+	}
+
+	key := fmt.Sprintf("%s/%s/%s-%s", session.TenantID, session.SessionID, kind, uuid.New())
+	if err := s.store.Put(ctx, key, blob, "application/octet-stream"); err != nil {
+		return err
+	}
+
+	m, err := s.repo.CreateMedia(ctx, &models.VerificationMedia{
+		SessionID:    session.SessionID,
+		UserID:       session.UserID,
+		Kind:         kind,
+		StorageKey:   key,
+		ContentType:  contentType,
+		SizeBytes:    int64(len(data)),
+		SHA256:       sha256Hex(data),
+		StoredSHA256: sha256Hex(blob),
+	})
+	if err != nil {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete orphan media blob %s: %v", key, err)
+		}
+		return err
+	}
+
+	s.record(ctx, models.AuditMediaCaptured, m, Actor, map[string]any{
+		"kind":   m.Kind,
+		"sha256": m.SHA256,
+		"size":   m.SizeBytes,
+	})
+	return nil
+}
+
+// download fetches a file from the provider, refusing files over the size
+// limit.
+func (s *Service) download(ctx context.Context, source string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid media URL: %v", err)
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download media: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download media: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.maxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download media: %v", err)
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, "", fmt.Errorf("media larger than %d bytes", s.maxBytes)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		contentType = http.DetectContentType(data)
+	}
+	return data, contentType, nil
+}
+
+// Links returns the media of a session with links signed for actor, and
+// audits that they were handed out.
+func (s *Service) Links(ctx context.Context, session *models.VerificationSession, actor string) ([]*Link, error) {
+	media, err := s.repo.ListSessionMedia(ctx, session.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Link, len(media))
+	for i, m := range media {
+		url, expiresAt := s.links.MediaLink(session.SessionID, m.ID.String(), session.TenantID, actor, s.urlTTL)
+		result[i] = &Link{VerificationMedia: m, URL: url, ExpiresAt: expiresAt}
+		s.record(ctx, models.AuditMediaURLIssued, m, actor, map[string]any{
+			"kind":       m.Kind,
+			"expires_at": expiresAt,
+		})
+	}
+	return result, nil
+}
+
+// Open reads and decrypts the file a signed media link points to, checking
+// both hashes, and audits the access under the reviewer the link was issued
+// to.
+func (s *Service) Open(ctx context.Context, token string, clientIP string) (*models.VerificationMedia, []byte, error) {
+	claims, err := s.links.Parse(token, links.PurposeMedia)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := uuid.Parse(claims.MediaID)
+	if err != nil {
+		return nil, nil, links.ErrInvalid
+	}
+
+	m, err := s.repo.GetMedia(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m == nil || m.SessionID != claims.SessionID {
+		return nil, nil, ErrNotFound
+	}
+	if s.store == nil {
+		return nil, nil, fmt.Errorf("no media store configured")
+	}
+
+	blob, err := s.store.Get(ctx, m.StorageKey)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if sha256Hex(blob) != m.StoredSHA256 {
+		log.Printf("Stored media %s does not match its hash", m.ID)
+		return nil, nil, ErrIntegrity
+	}
+	data, err := s.crypt.DecryptBlob(ctx, blob)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sha256Hex(data) != m.SHA256 {
+		log.Printf("Decrypted media %s does not match its hash", m.ID)
+		return nil, nil, ErrIntegrity
+	}
+
+	// A file is only served once its access is on record.
+	err = s.record(ctx, models.AuditMediaAccessed, m, claims.Actor, map[string]any{
+		"kind":      m.Kind,
+		"client_ip": clientIP,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, data, nil
+}
+
+// UserMedia lists the media kept of a user.
+func (s *Service) UserMedia(ctx context.Context, userID string) ([]*models.VerificationMedia, error) {
+	return s.repo.ListUserMedia(ctx, userID)
+}
+
+// EraseUser deletes every media file of a user.
+func (s *Service) EraseUser(ctx context.Context, userID string, actor string) (int, error) {
+	media, err := s.repo.ListUserMedia(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	for i, m := range media {
+		if err := s.delete(ctx, m, actor, "erasure"); err != nil {
+			return i, err
+		}
+	}
+	return len(media), nil
+}
+
+// CountExpired counts the media the retention policy would delete.
+func (s *Service) CountExpired(ctx context.Context, before time.Time) (int, error) {
+	return s.repo.CountExpiredMedia(ctx, before)
+}
+
+// PurgeExpired deletes a batch of media of decided sessions last updated
+// before the cutoff.
+func (s *Service) PurgeExpired(ctx context.Context, before time.Time, batchSize int) (int, error) {
+	media, err := s.repo.ListExpiredMedia(ctx, before, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, m := range media {
+		if err := s.delete(ctx, m, "system:retention", "retention"); err != nil {
+			return i, err
+		}
+	}
+	return len(media), nil
+}
+
+// delete removes the file before its row, so a failure never leaves a file
+// nothing points to.
+func (s *Service) delete(ctx context.Context, m *models.VerificationMedia, actor string, reason string) error {
+	if s.store == nil {
+		return fmt.Errorf("no media store configured")
+	}
+	if err := s.store.Delete(ctx, m.StorageKey); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteMedia(ctx, m.ID); err != nil {
+		return err
+	}
+	s.record(ctx, models.AuditMediaDeleted, m, actor, map[string]any{
+		"kind":   m.Kind,
+		"reason": reason,
+	})
+	return nil
+}
+
+// record audits an action on a media file. Failures are logged and returned.
+func (s *Service) record(ctx context.Context, action string, m *models.VerificationMedia, actor string, details map[string]any) error {
+	details["session_id"] = m.SessionID
+	_, err := s.audit.Record(ctx, models.AuditEvent{
+		Action:        action,
+		SubjectUserID: m.UserID,
+		Actor:         actor,
+		EntityType:    "media",
+		EntityID:      m.ID.String(),
+		Details:       details,
+	})
+	if err != nil {
+		log.Printf("Failed to audit %s of media %s: %v", action, m.ID, err)
+	}
+	return err
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
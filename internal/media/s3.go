@@ -0,0 +1,378 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config locates a bucket of AWS S3 or an S3-compatible store. PathStyle
+// addresses the bucket in the path (http://minio:9000/bucket/key) instead of
+// the host name, as MinIO expects by default.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+	Timeout   time.Duration
+}
+
+// s3PartSize is the size of the parts of streamed uploads, above the 5 MiB
+// minimum of S3. Streams shorter than a part are sent in a single request.
+const s3PartSize = 8 << 20
+
+// S3Store keeps blobs in an S3 bucket, signing requests with AWS Signature
+// Version 4.
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	http     *http.Client
+	// stream reads blobs of any size, bounded by the caller's context only.
+	stream *http.Client
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3Store{
+		cfg:      cfg,
+		endpoint: endpoint,
+		http:     &http.Client{Timeout: cfg.Timeout},
+		stream:   &http.Client{},
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, nil, data, header)
+	if err != nil {
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to store blob: %s", s3Error(resp))
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrBlobNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read blob: %s", s3Error(resp))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+	return data, nil
+}
+
+// Delete removes a blob. S3 answers deletes of missing keys with success too.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete blob: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete blob: %s", s3Error(resp))
+	}
+	return nil
+}
+
+// PutStream uploads the blob in parts of s3PartSize with a multipart upload,
+// which is aborted when reading r or sending a part fails. Each part is
+// signed with the SHA-256 of its content, which S3 checks on arrival.
+func (s *S3Store) PutStream(ctx context.Context, key string, r io.Reader, contentType string) error {
+	buf := make([]byte, s3PartSize)
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return s.Put(ctx, key, buf[:n], contentType)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+
+	uploadID, err := s.createUpload(ctx, key, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+
+	var parts []s3Part
+	for n > 0 {
+		etag, err := s.uploadPart(ctx, key, uploadID, len(parts)+1, buf[:n])
+		if err != nil {
+			s.abortUpload(ctx, key, uploadID)
+			return fmt.Errorf("failed to store blob: %v", err)
+		}
+		parts = append(parts, s3Part{Number: len(parts) + 1, ETag: etag})
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			s.abortUpload(ctx, key, uploadID)
+			return fmt.Errorf("failed to store blob: %v", err)
+		}
+	}
+
+	if err := s.completeUpload(ctx, key, uploadID, parts); err != nil {
+		s.abortUpload(ctx, key, uploadID)
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+	return nil
+}
+
+// GetStream returns the body of the blob as it downloads.
+func (s *S3Store) GetStream(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.request(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+	resp, err := s.stream.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("failed to read blob: %s", s3Error(resp))
+	}
+	return resp.Body, nil
+}
+
+type s3Part struct {
+	Number int    `xml:"PartNumber"`
+	ETag   string `xml:"ETag"`
+}
+
+func (s *S3Store) createUpload(ctx context.Context, key string, contentType string) (string, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to start upload: %s", s3Error(resp))
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("failed to start upload: invalid response")
+	}
+	return result.UploadID, nil
+}
+
+func (s *S3Store) uploadPart(ctx context.Context, key string, uploadID string, number int, data []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+	resp, err := s.do(ctx, http.MethodPut, key, query, data, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to upload part %d: %s", number, s3Error(resp))
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// completeUpload joins the parts. S3 may report a failure in the body of a
+// 200 response, so the body is checked too.
+func (s *S3Store) completeUpload(ctx context.Context, key string, uploadID string, parts []s3Part) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to complete upload: %s", s3Error(resp))
+	}
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to complete upload: invalid response")
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("failed to complete upload: %s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// abortUpload discards the parts of a failed upload. Failing to do so only
+// leaves them for the bucket's lifecycle rules.
+func (s *S3Store) abortUpload(ctx context.Context, key string, uploadID string) {
+	resp, err := s.do(context.WithoutCancel(ctx), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		log.Printf("Failed to abort upload of %s: %v", key, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (s *S3Store) do(ctx context.Context, method string, key string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	req, err := s.request(ctx, method, key, query, body, header)
+	if err != nil {
+		return nil, err
+	}
+	return s.http.Do(req)
+}
+
+func (s *S3Store) request(ctx context.Context, method string, key string, query url.Values, body []byte, header http.Header) (*http.Request, error) {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = uriEncodePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.sign(req, body, time.Now().UTC())
+	return req, nil
+}
+
+// sign adds the AWS Signature Version 4 Authorization header.
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// uriEncodePath encodes every byte of a path but unreserved characters and
+// slashes, as Signature Version 4 requires.
+func uriEncodePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes a query string as Signature Version 4 requires:
+// sorted by key, every byte but unreserved characters escaped, and keys
+// without a value followed by "=".
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func uriEncode(s string) string {
+	return strings.ReplaceAll(uriEncodePath(s), "/", "%2F")
+}
+
+func s3Error(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
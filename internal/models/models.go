@@ -12,11 +12,27 @@ type VerificationRequest struct {
 	Email     string `json:"email" binding:"required"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	// Level is the KYC tier to verify: basic, full or enhanced. Empty means basic.
+	Level string `json:"level"`
+	// CPF is the Brazilian taxpayer number, with or without punctuation.
+	// Required when CPF_REQUIRED is set.
+	CPF string `json:"cpf"`
+	// Country is the ISO 3166-1 alpha-2 country of the user's document, used
+	// to route the verification to a provider.
+	Country string `json:"country"`
 }
 
+// VerificationResponse hands out a signed link to the session's verification
+// page. The link expires at ExpiresAt; starting again while the session is
+// pending returns a new link to it.
 type VerificationResponse struct {
-	VerificationURL string `json:"verification_url"`
-	UserID          string `json:"user_id"`
+	VerificationURL string    `json:"verification_url"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	UserID          string    `json:"user_id"`
+	SessionID       string    `json:"session_id"`
+	Level           string    `json:"level"`
+	BaseSessionID   string    `json:"base_session_id,omitempty"`
+	Reused          bool      `json:"reused"`
 }
 
 type WebhookEvent struct {
@@ -32,28 +48,346 @@ type WebhookData struct {
 	UserID    string                 `json:"user_id,omitempty"`
 	UserData  map[string]interface{} `json:"user_data,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Decision  map[string]interface{} `json:"decision,omitempty"`
 }
 
+// DefaultProvider verifies sessions that do not name a provider, including
+// every session created before providers were introduced.
+const DefaultProvider = "didit"
+
 type VerificationSession struct {
-	ID             uuid.UUID  `json:"id"`
-	UserID         string     `json:"user_id"`
-	SessionID      string     `json:"session_id"`
-	DiditSessionID string     `json:"didit_session_id,omitempty"`
-	Status         string     `json:"status"`
-	UserEmail      string     `json:"user_email"`
-	UserFirstName  string     `json:"user_first_name,omitempty"`
-	UserLastName   string     `json:"user_last_name,omitempty"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
-	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	ID             uuid.UUID `json:"id"`
+	TenantID       uuid.UUID `json:"tenant_id"`
+	UserID         string    `json:"user_id"`
+	SessionID      string    `json:"session_id"`
+	Provider       string    `json:"provider"`
+	DiditSessionID string    `json:"didit_session_id,omitempty"`
+	Status         string    `json:"status"`
+	Level          string    `json:"level"`
+	BaseSessionID  string    `json:"base_session_id,omitempty"`
+	UserEmail      string    `json:"user_email"`
+	UserFirstName  string    `json:"user_first_name,omitempty"`
+	UserLastName   string    `json:"user_last_name,omitempty"`
+	UserCPF        string    `json:"user_cpf,omitempty"`
+	CPFMatch       *bool     `json:"cpf_match,omitempty"`
+	// VerificationURL is the provider's link, handed out only through
+	// signed links.
+	VerificationURL string     `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	NextReviewAt    *time.Time `json:"next_review_at,omitempty"`
+	RemindedAt      *time.Time `json:"reminded_at,omitempty"`
+	ReconciledAt    *time.Time `json:"reconciled_at,omitempty"`
+	ErasedAt        *time.Time `json:"erased_at,omitempty"`
 }
 
 // WebhookEventDB represents the webhook event structure in the database.
 type WebhookEventDB struct {
-	ID        uuid.UUID       `json:"id"`
-	EventType string          `json:"event_type"`
-	SessionID string          `json:"session_id"`
-	Payload   json.RawMessage `json:"payload"`
-	Processed bool            `json:"processed"`
-	CreatedAt time.Time       `json:"created_at"`
+	ID            uuid.UUID       `json:"id"`
+	TenantID      uuid.UUID       `json:"tenant_id"`
+	Provider      string          `json:"provider"`
+	EventType     string          `json:"event_type"`
+	SessionID     string          `json:"session_id"`
+	Payload       json.RawMessage `json:"payload"`
+	Processed     bool            `json:"processed"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ProcessedAt   *time.Time      `json:"processed_at,omitempty"`
+	RedactedAt    *time.Time      `json:"redacted_at,omitempty"`
+}
+
+// Queue states of a stored webhook event.
+const (
+	WebhookEventPending    = "pending"
+	WebhookEventProcessing = "processing"
+	WebhookEventDone       = "done"
+	WebhookEventDead       = "dead"
+)
+
+// Changes queued for the webhook archives: scrubbing the identity fields of
+// events, or dropping events.
+const (
+	ArchivePurgeErase  = "erase"
+	ArchivePurgeDelete = "delete"
+)
+
+// StatusTransition describes a verification session moving between statuses.
+type StatusTransition struct {
+	SessionID      string    `json:"session_id"`
+	TenantID       uuid.UUID `json:"tenant_id"`
+	UserID         string    `json:"user_id"`
+	DiditSessionID string    `json:"didit_session_id,omitempty"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+// WebhookSubscription is an endpoint of another Crispay service that receives
+// outbound KYC events.
+type WebhookSubscription struct {
+	ID                  uuid.UUID  `json:"id"`
+	TenantID            uuid.UUID  `json:"tenant_id"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"secret,omitempty"`
+	EventTypes          []string   `json:"event_types"`
+	Description         string     `json:"description,omitempty"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// WebhookDelivery is one outbound event queued for one subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID                 `json:"id"`
+	SubscriptionID uuid.UUID                 `json:"subscription_id"`
+	EventID        uuid.UUID                 `json:"event_id"`
+	EventType      string                    `json:"event_type"`
+	Payload        json.RawMessage           `json:"payload"`
+	Status         string                    `json:"status"`
+	Attempts       int                       `json:"attempts"`
+	NextAttemptAt  time.Time                 `json:"next_attempt_at"`
+	LastStatusCode int                       `json:"last_status_code,omitempty"`
+	LastError      string                    `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time                `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	AttemptLog     []*WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookDeliveryAttempt records a single HTTP attempt of a delivery.
+type WebhookDeliveryAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// States of an outbound webhook delivery.
+const (
+	DeliveryPending    = "pending"
+	DeliveryDelivering = "delivering"
+	DeliverySucceeded  = "succeeded"
+	DeliveryFailed     = "failed"
+)
+
+// IsTerminalStatus reports whether a verification session status is final.
+func IsTerminalStatus(status string) bool {
+	switch status {
+	case "approved", "rejected", "failed":
+		return true
+	default:
+		return false
+	}
+}
+
+// AuditLogEntry is a row of the append-only, hash-chained audit log.
+type AuditLogEntry struct {
+	ID            uuid.UUID       `json:"id"`
+	Seq           int64           `json:"seq"`
+	Action        string          `json:"action"`
+	SubjectUserID string          `json:"subject_user_id,omitempty"`
+	Actor         string          `json:"actor"`
+	EntityType    string          `json:"entity_type,omitempty"`
+	EntityID      string          `json:"entity_id,omitempty"`
+	Details       json.RawMessage `json:"details"`
+	CreatedAt     time.Time       `json:"created_at"`
+	PrevHash      string          `json:"prev_hash"`
+	Hash          string          `json:"hash"`
+}
+
+// AuditEvent is an action to append to the audit log. Details are stored as
+// JSON.
+type AuditEvent struct {
+	Action        string
+	SubjectUserID string
+	Actor         string
+	EntityType    string
+	EntityID      string
+	Details       any
+}
+
+// Audited actions.
+const (
+	AuditSessionCreated    = "session.created"
+	AuditSessionTransition = "session.status_changed"
+	AuditSessionReviewed   = "session.reviewed"
+	AuditPrivacyExport     = "privacy.export"
+	AuditPrivacyErase      = "privacy.erase"
+	AuditAdminRequest      = "admin.request"
+
+	AuditCompanyRegistered    = "company.registered"
+	AuditCompanyStatusChanged = "company.status_changed"
+
+	AuditMediaCaptured  = "media.captured"
+	AuditMediaURLIssued = "media.url_issued"
+	AuditMediaAccessed  = "media.accessed"
+	AuditMediaDeleted   = "media.deleted"
+)
+
+// VerificationMedia is a file of a verification, like a document image or
+// the liveness video, kept encrypted in the blob store. SHA256 is the hash of
+// the original file, StoredSHA256 that of the encrypted object.
+type VerificationMedia struct {
+	ID           uuid.UUID `json:"id"`
+	SessionID    string    `json:"session_id"`
+	UserID       string    `json:"user_id"`
+	Kind         string    `json:"kind"`
+	StorageKey   string    `json:"-"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	SHA256       string    `json:"sha256"`
+	StoredSHA256 string    `json:"stored_sha256"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// LegalHold blocks retention purges and erasure of a user's data.
+type LegalHold struct {
+	UserID    string    `json:"user_id"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PurgeRun is a stored retention purge report.
+type PurgeRun struct {
+	ID         uuid.UUID       `json:"id"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Report     json.RawMessage `json:"report"`
+}
+
+// ScreeningMatch is a sanctions list entry that matched a verified user.
+type ScreeningMatch struct {
+	ID          uuid.UUID       `json:"id"`
+	SessionID   string          `json:"session_id"`
+	UserID      string          `json:"user_id"`
+	List        string          `json:"list"`
+	EntryID     string          `json:"entry_id"`
+	EntryName   string          `json:"entry_name"`
+	MatchedName string          `json:"matched_name"`
+	Score       float64         `json:"score"`
+	DOBMatch    *bool           `json:"dob_match,omitempty"`
+	Details     json.RawMessage `json:"details"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// IdentityLink ties a session to a session of another user carrying the same
+// identity document.
+type IdentityLink struct {
+	ID              uuid.UUID  `json:"id"`
+	SessionID       string     `json:"session_id"`
+	UserID          string     `json:"user_id"`
+	LinkedSessionID string     `json:"linked_session_id"`
+	LinkedUserID    string     `json:"linked_user_id"`
+	Action          string     `json:"action"`
+	Status          string     `json:"status"`
+	Resolution      string     `json:"resolution,omitempty"`
+	ResolvedBy      string     `json:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// RiskScore is the stored risk evaluation of a terminal decision.
+type RiskScore struct {
+	ID           uuid.UUID       `json:"id"`
+	SessionID    string          `json:"session_id"`
+	UserID       string          `json:"user_id"`
+	Score        float64         `json:"score"`
+	Decision     string          `json:"decision"`
+	DiditStatus  string          `json:"didit_status"`
+	FinalStatus  string          `json:"final_status"`
+	Enforced     bool            `json:"enforced"`
+	RulesVersion string          `json:"rules_version,omitempty"`
+	Signals      json.RawMessage `json:"signals"`
+	Reasons      json.RawMessage `json:"reasons"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// Address is a postal address.
+type Address struct {
+	Street     string `json:"street" binding:"required"`
+	Number     string `json:"number"`
+	Complement string `json:"complement,omitempty"`
+	District   string `json:"district,omitempty"`
+	City       string `json:"city" binding:"required"`
+	State      string `json:"state" binding:"required"`
+	PostalCode string `json:"postal_code" binding:"required"`
+	Country    string `json:"country"`
+}
+
+// Company is a business going through KYB.
+type Company struct {
+	ID                uuid.UUID  `json:"id"`
+	CNPJ              string     `json:"cnpj"`
+	LegalName         string     `json:"legal_name"`
+	TradeName         string     `json:"trade_name,omitempty"`
+	RegisteredAddress Address    `json:"registered_address"`
+	OwnerUserID       string     `json:"owner_user_id"`
+	Status            string     `json:"status"`
+	ApprovedAt        *time.Time `json:"approved_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Roles of the people linked to a company.
+const (
+	RoleUBO                 = "ubo"
+	RoleLegalRepresentative = "legal_representative"
+)
+
+// CompanyPerson is an ultimate beneficial owner or legal representative of a
+// company, verified as an individual under UserID.
+type CompanyPerson struct {
+	ID               uuid.UUID `json:"id"`
+	CompanyID        uuid.UUID `json:"company_id"`
+	Role             string    `json:"role"`
+	UserID           string    `json:"user_id"`
+	OwnershipPercent *float64  `json:"ownership_percent,omitempty"`
+	Required         bool      `json:"required"`
+	SessionID        string    `json:"session_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Tenant is a partner verifying its own users through this service. Empty
+// Didit and email settings fall back to the environment's.
+type Tenant struct {
+	ID                 uuid.UUID `json:"id"`
+	Slug               string    `json:"slug"`
+	Name               string    `json:"name"`
+	Active             bool      `json:"active"`
+	DiditAPIKey        string    `json:"-"`
+	DiditWorkflowID    string    `json:"didit_workflow_id,omitempty"`
+	DiditWorkflowURL   string    `json:"didit_workflow_url,omitempty"`
+	DiditWebhookSecret string    `json:"-"`
+	SenderName         string    `json:"sender_name,omitempty"`
+	SenderEmail        string    `json:"sender_email,omitempty"`
+	LogoURL            string    `json:"logo_url,omitempty"`
+	PrimaryColor       string    `json:"primary_color,omitempty"`
+	SupportURL         string    `json:"support_url,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// TenantRequest creates or updates a tenant. On update, omitted fields are
+// kept and empty strings clear a setting.
+type TenantRequest struct {
+	Slug               *string `json:"slug"`
+	Name               *string `json:"name"`
+	Active             *bool   `json:"active"`
+	DiditAPIKey        *string `json:"didit_api_key"`
+	DiditWorkflowID    *string `json:"didit_workflow_id"`
+	DiditWorkflowURL   *string `json:"didit_workflow_url"`
+	DiditWebhookSecret *string `json:"didit_webhook_secret"`
+	SenderName         *string `json:"sender_name"`
+	SenderEmail        *string `json:"sender_email"`
+	LogoURL            *string `json:"logo_url"`
+	PrimaryColor       *string `json:"primary_color"`
+	SupportURL         *string `json:"support_url"`
 }
@@ -0,0 +1,377 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/email/service"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/links"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/FelipePn10/crispaybackend/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+// Actor is the audit log actor of scheduler-driven changes.
+const Actor = "system:monitoring"
+
+// Reasons a re-verification is started.
+const (
+	ReasonPeriodicReview = "periodic_review"
+	ReasonExpired        = "expired"
+	ReasonExpiryReminder = "expiry_reminder"
+)
+
+// batchSize bounds the sessions handled per step and run; the rest wait for
+// the next run.
+const batchSize = 100
+
+// deadlineLayout formats dates in the emails.
+const deadlineLayout = "02/01/2006"
+
+// errStale is returned when a session changed since it was listed, so the
+// step no longer applies to it.
+var errStale = errors.New("session changed since it was listed")
+
+// Report is the outcome of a monitoring run.
+type Report struct {
+	StartedAt      time.Time `json:"started_at"`
+	ReviewsStarted int       `json:"reviews_started"`
+	Expired        int       `json:"expired"`
+	Reminded       int       `json:"reminded"`
+	Errors         []string  `json:"errors,omitempty"`
+}
+
+// Scheduler keeps approvals current: it starts re-verification when the
+// periodic review is due or the identity document expired, and reminds users
+// ahead of the expiry.
+type Scheduler struct {
+	repo      *repository.VerificationRepository
+	processor *webhooks.Processor
+	email     *service.EmailService
+	links     *links.Signer
+	providers *kyc.Router
+	tenants   *repository.TenantRepository
+
+	interval time.Duration
+	grace    time.Duration
+	reminder time.Duration
+}
+
+func NewScheduler(cfg *config.Config, repo *repository.VerificationRepository, processor *webhooks.Processor, emailService *service.EmailService, signer *links.Signer, providers *kyc.Router, tenants *repository.TenantRepository) *Scheduler {
+	return &Scheduler{
+		repo:      repo,
+		processor: processor,
+		email:     emailService,
+		links:     signer,
+		providers: providers,
+		tenants:   tenants,
+		interval:  cfg.KYCMonitorInterval,
+		grace:     time.Duration(cfg.KYCReviewGraceDays) * 24 * time.Hour,
+		reminder:  time.Duration(cfg.KYCExpiryReminderDays) * 24 * time.Hour,
+	}
+}
+
+// RunOnce handles the due reviews, expirations and reminders of every tenant.
+// Failures on a session are reported and do not stop the others.
+func (s *Scheduler) RunOnce(ctx context.Context) (*Report, error) {
+	report := &Report{StartedAt: time.Now().UTC()}
+	fail := func(session *models.VerificationSession, err error) {
+		report.Errors = append(report.Errors, fmt.Sprintf("session %s: %v", session.SessionID, err))
+	}
+	// step handles a session acting for its tenant.
+	step := func(session *models.VerificationSession, handle func(context.Context, *models.VerificationSession) error) error {
+		tenantCtx, err := s.tenants.Context(ctx, session.TenantID)
+		if err != nil {
+			return err
+		}
+		return handle(tenantCtx, session)
+	}
+
+	due, err := s.repo.ListSessionsDueForReview(ctx, batchSize)
+	if err != nil {
+		return report, err
+	}
+	for _, session := range due {
+		if err := step(session, s.startReview); errors.Is(err, errStale) {
+			continue
+		} else if err != nil {
+			fail(session, err)
+			continue
+		}
+		report.ReviewsStarted++
+	}
+
+	expired, err := s.repo.ListExpiredApprovedSessions(ctx, batchSize)
+	if err != nil {
+		return report, err
+	}
+	for _, session := range expired {
+		if err := step(session, s.expire); errors.Is(err, errStale) {
+			continue
+		} else if err != nil {
+			fail(session, err)
+			continue
+		}
+		report.Expired++
+	}
+
+	if s.reminder > 0 {
+		expiring, err := s.repo.ListSessionsDueForReminder(ctx, time.Now().Add(s.reminder), batchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, session := range expiring {
+			if err := step(session, s.remind); err != nil {
+				fail(session, err)
+				continue
+			}
+			report.Reminded++
+		}
+	}
+
+	return report, nil
+}
+
+// startReview asks the user to verify again and lets the approval lapse at
+// the end of the grace period. The re-verification session is opened before
+// the review is recorded: when opening it fails the review stays due for the
+// next run, and a review that fails to record reuses the pending session.
+func (s *Scheduler) startReview(ctx context.Context, session *models.VerificationSession) error {
+	level, err := s.reverificationLevel(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	reverification := *session
+	reverification.Level = level
+	var verificationURL string
+	// Erased users can no longer be contacted, but their review still runs.
+	if session.ErasedAt == nil {
+		verificationURL, err = s.verificationLink(ctx, &reverification, ReasonPeriodicReview)
+		if err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(s.grace)
+	err = s.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		locked, err := repo.LockSession(ctx, session.SessionID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != "approved" || locked.NextReviewAt == nil || locked.NextReviewAt.After(time.Now()) {
+			return errStale
+		}
+		if err := repo.StartSessionReview(ctx, locked.ID, deadline); err != nil {
+			return err
+		}
+		if locked.ExpiresAt != nil && locked.ExpiresAt.Before(deadline) {
+			deadline = *locked.ExpiresAt
+		}
+		return nil
+	})
+	if err != nil || verificationURL == "" {
+		return err
+	}
+
+	s.sendReverification(ctx, &reverification, verificationURL, ReasonPeriodicReview, deadline.Format(deadlineLayout))
+	return nil
+}
+
+func (s *Scheduler) expire(ctx context.Context, session *models.VerificationSession) error {
+	// The level to restore is read before the approval disappears.
+	level, err := s.reverificationLevel(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.processor.Expire(ctx, session, Actor, ReasonExpired); errors.Is(err, webhooks.ErrNotExpired) {
+		return errStale
+	} else if err != nil {
+		return err
+	}
+
+	session.Level = level
+	return s.reverify(ctx, session, ReasonExpired, "")
+}
+
+func (s *Scheduler) remind(ctx context.Context, session *models.VerificationSession) error {
+	level, err := s.reverificationLevel(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	reverification := *session
+	reverification.Level = level
+	verificationURL, err := s.verificationLink(ctx, &reverification, ReasonExpiryReminder)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.MarkSessionReminded(ctx, session.ID); err != nil {
+		return err
+	}
+
+	s.email.SendExpiryReminderEmailAsync(service.Reverification{
+		Name:            session.UserFirstName,
+		Email:           session.UserEmail,
+		VerificationURL: verificationURL,
+		Deadline:        session.ExpiresAt.Format(deadlineLayout),
+		Branding:        tenant.EmailBranding(ctx),
+	})
+	return nil
+}
+
+// reverify opens a pending session at the session's level, unless one was
+// opened recently, and emails the user the link.
+func (s *Scheduler) reverify(ctx context.Context, session *models.VerificationSession, reason string, deadline string) error {
+	// Erased users can no longer be contacted.
+	if session.ErasedAt != nil {
+		return nil
+	}
+
+	verificationURL, err := s.verificationLink(ctx, session, reason)
+	if err != nil {
+		return err
+	}
+
+	s.sendReverification(ctx, session, verificationURL, reason, deadline)
+	return nil
+}
+
+// sendReverification emails the user the link to verify again.
+func (s *Scheduler) sendReverification(ctx context.Context, session *models.VerificationSession, verificationURL string, reason string, deadline string) {
+	s.email.SendReverificationEmailAsync(service.Reverification{
+		Name:            session.UserFirstName,
+		Email:           session.UserEmail,
+		VerificationURL: verificationURL,
+		Deadline:        deadline,
+		Branding:        tenant.EmailBranding(ctx),
+	})
+
+	log.Printf("Re-verification of user %s at level %s started (%s)", session.UserID, session.Level, reason)
+}
+
+// verificationLink returns a signed link to a pending session verifying the
+// user again at the session's level, opening one unless one was opened
+// recently. Like the sessions of the start endpoint, the provider calls back
+// when the user finishes.
+func (s *Scheduler) verificationLink(ctx context.Context, session *models.VerificationSession, reason string) (string, error) {
+	pending, err := s.repo.GetLatestPendingSession(ctx, session.UserID, session.Level, time.Now().Add(-s.grace))
+	if err != nil {
+		return "", err
+	}
+	if pending != nil && pending.VerificationURL != "" {
+		link, _ := s.links.VerificationLink(pending.SessionID, tenant.ID(ctx))
+		return link, nil
+	}
+
+	sessionID := uuid.New().String()
+	opened, err := s.providers.CreateSession(ctx, kyc.SessionRequest{
+		SessionID:   sessionID,
+		UserID:      session.UserID,
+		Email:       session.UserEmail,
+		FirstName:   session.UserFirstName,
+		LastName:    session.UserLastName,
+		Level:       session.Level,
+		CallbackURL: s.links.CallbackURL(sessionID, tenant.ID(ctx)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Without its audit entry the session is not created either.
+	err = s.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		_, err := repo.CreateSession(ctx, &models.VerificationSession{
+			UserID:          session.UserID,
+			SessionID:       sessionID,
+			Provider:        opened.Provider,
+			DiditSessionID:  opened.ID,
+			Status:          "pending",
+			Level:           session.Level,
+			UserEmail:       session.UserEmail,
+			UserFirstName:   session.UserFirstName,
+			UserLastName:    session.UserLastName,
+			UserCPF:         session.UserCPF,
+			VerificationURL: opened.URL,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = repository.NewAuditRepository(repo.Queries()).Record(ctx, models.AuditEvent{
+			Action:        models.AuditSessionCreated,
+			SubjectUserID: session.UserID,
+			Actor:         Actor,
+			EntityType:    "session",
+			EntityID:      sessionID,
+			Details: map[string]any{
+				"level":               session.Level,
+				"provider":            opened.Provider,
+				"reason":              reason,
+				"previous_session_id": session.SessionID,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to audit re-verification session: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	link, _ := s.links.VerificationLink(sessionID, tenant.ID(ctx))
+	return link, nil
+}
+
+// reverificationLevel is the highest level resting on the session, so users
+// who upgraded on top of an expiring approval verify at the level they hold.
+func (s *Scheduler) reverificationLevel(ctx context.Context, session *models.VerificationSession) (string, error) {
+	approved, err := s.repo.ListApprovedSessions(ctx, session.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	effective := kyc.Effective(approved)
+	if effective != nil && kyc.BuildsOn(approved, effective, session.SessionID) {
+		return effective.Level, nil
+	}
+	return session.Level, nil
+}
+
+// Run checks on every interval until ctx is cancelled. A zero interval
+// disables the monitoring.
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	log.Printf("Starting KYC monitoring every %s", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.RunOnce(ctx)
+			if err != nil {
+				log.Printf("KYC monitoring failed: %v", err)
+				continue
+			}
+			log.Printf("KYC monitoring: reviews=%d expired=%d reminded=%d errors=%d",
+				report.ReviewsStarted, report.Expired, report.Reminded, len(report.Errors))
+			for _, e := range report.Errors {
+				log.Printf("KYC monitoring error: %s", e)
+			}
+		}
+	}
+}
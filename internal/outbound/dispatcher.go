@@ -0,0 +1,291 @@
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Event is the JSON body delivered to subscribers.
+type Event struct {
+	ID        uuid.UUID               `json:"id"`
+	Type      string                  `json:"type"`
+	CreatedAt time.Time               `json:"created_at"`
+	Data      models.StatusTransition `json:"data"`
+}
+
+// EventType returns the outbound event type for a session status, e.g.
+// "verification.approved".
+func EventType(status string) string {
+	return "verification." + status
+}
+
+// Dispatcher fans status transitions out to the registered subscriptions and
+// delivers them with retries.
+type Dispatcher struct {
+	repo         *repository.SubscriptionRepository
+	client       *http.Client
+	workers      int
+	maxAttempts  int
+	disableAfter int
+	pollInterval time.Duration
+	backoff      time.Duration
+	wake         chan struct{}
+}
+
+func NewDispatcher(cfg *config.Config, repo *repository.SubscriptionRepository) *Dispatcher {
+	workers := cfg.OutboundWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	return &Dispatcher{
+		repo:         repo,
+		client:       &http.Client{Timeout: cfg.OutboundTimeout},
+		workers:      workers,
+		maxAttempts:  cfg.OutboundMaxAttempts,
+		disableAfter: cfg.OutboundDisableAfter,
+		pollInterval: cfg.OutboundPollInterval,
+		backoff:      cfg.OutboundRetryBackoff,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Publish queues a delivery of the transition for every active subscription
+// whose filter matches it.
+func (d *Dispatcher) Publish(ctx context.Context, transition models.StatusTransition) error {
+	queued, err := d.Enqueue(ctx, transition)
+	if err != nil {
+		return err
+	}
+	if queued {
+		d.Notify()
+	}
+	return nil
+}
+
+// WithRepository returns a dispatcher queuing deliveries through repo, such
+// as one bound to a transaction. It shares the delivery workers of d.
+func (d *Dispatcher) WithRepository(repo *repository.SubscriptionRepository) *Dispatcher {
+	bound := *d
+	bound.repo = repo
+	return &bound
+}
+
+// Enqueue queues the deliveries of the transition to the subscriptions of
+// its tenant like Publish, without waking the workers, and reports whether
+// any was queued. Inside a transaction the workers are woken with Notify
+// once it commits, as they cannot see the deliveries before.
+func (d *Dispatcher) Enqueue(ctx context.Context, transition models.StatusTransition) (bool, error) {
+	subscriptions, err := d.repo.ListActiveSubscriptions(ctx, transition.TenantID)
+	if err != nil {
+		return false, err
+	}
+
+	event := Event{
+		ID:        uuid.New(),
+		Type:      EventType(transition.Status),
+		CreatedAt: transition.OccurredAt,
+		Data:      transition,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode outbound event: %w", err)
+	}
+
+	queued := 0
+	for _, sub := range subscriptions {
+		if !matches(sub.EventTypes, event.Type) {
+			continue
+		}
+		if _, err := d.repo.CreateDelivery(ctx, sub.ID, event.ID, event.Type, payload); err != nil {
+			return false, err
+		}
+		queued++
+	}
+	return queued > 0, nil
+}
+
+// Redeliver queues a fresh copy of an existing delivery, keeping the original
+// and its attempt log intact.
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	original, err := d.repo.GetDelivery(ctx, deliveryID)
+	if err != nil || original == nil {
+		return nil, err
+	}
+
+	delivery, err := d.repo.CreateDelivery(ctx, original.SubscriptionID, original.EventID, original.EventType, original.Payload)
+	if err != nil {
+		return nil, err
+	}
+	d.Notify()
+
+	return delivery, nil
+}
+
+func matches(filter []string, eventType string) bool {
+	return len(filter) == 0 || slices.Contains(filter, "*") || slices.Contains(filter, eventType)
+}
+
+// Notify wakes an idle delivery worker.
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the delivery workers and blocks until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Printf("Starting outbound webhook dispatcher with %d workers", d.workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			claimed, err := d.deliverNext(ctx)
+			if err != nil {
+				log.Printf("Outbound webhook dispatcher error: %v", err)
+				break
+			}
+			if !claimed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverNext(ctx context.Context) (bool, error) {
+	// A delivery stuck in "delivering" for longer than a few request timeouts
+	// belonged to a worker that died mid-request.
+	delivery, err := d.repo.ClaimNextDelivery(ctx, time.Now().Add(-5*d.client.Timeout))
+	if err != nil {
+		return false, err
+	}
+	if delivery == nil {
+		return false, nil
+	}
+
+	sub, err := d.repo.GetDeliverySubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return true, err
+	}
+	if sub == nil {
+		// The subscription was deleted since; nobody is left to deliver to.
+		return true, d.repo.FailDelivery(ctx, delivery.ID, 0, "subscription deleted")
+	}
+
+	attempt := d.send(ctx, sub, delivery)
+	if err := d.repo.RecordAttempt(ctx, delivery.ID, attempt); err != nil {
+		log.Printf("Failed to record delivery attempt: %v", err)
+	}
+
+	if attempt.Error == "" {
+		if err := d.repo.RecordSuccess(ctx, sub.ID); err != nil {
+			log.Printf("Failed to reset subscription failures: %v", err)
+		}
+		return true, d.repo.CompleteDelivery(ctx, delivery.ID, attempt.StatusCode)
+	}
+
+	updated, err := d.repo.RecordFailure(ctx, sub.ID, d.disableAfter)
+	if err != nil {
+		log.Printf("Failed to record subscription failure: %v", err)
+	} else if sub.Active && !updated.Active {
+		log.Printf("Webhook subscription %s disabled after %d consecutive failures", sub.ID, updated.ConsecutiveFailures)
+	}
+
+	if delivery.Attempts >= d.maxAttempts {
+		log.Printf("Delivery %s to %s failed permanently: %s", delivery.ID, sub.URL, attempt.Error)
+		return true, d.repo.FailDelivery(ctx, delivery.ID, attempt.StatusCode, attempt.Error)
+	}
+
+	delay := d.retryDelay(delivery.Attempts)
+	return true, d.repo.RetryDelivery(ctx, delivery.ID, time.Now().Add(delay), attempt.StatusCode, attempt.Error)
+}
+
+// send performs one signed POST of the delivery payload.
+func (d *Dispatcher) send(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery) *models.WebhookDeliveryAttempt {
+	attempt := &models.WebhookDeliveryAttempt{Attempt: delivery.Attempts}
+	start := time.Now()
+	defer func() {
+		attempt.DurationMs = int(time.Since(start).Milliseconds())
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Crispay-Webhooks/1.0")
+	req.Header.Set("X-Crispay-Event", delivery.EventType)
+	req.Header.Set("X-Crispay-Delivery", delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, time.Now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+func (d *Dispatcher) retryDelay(attempt int) time.Duration {
+	const maxDelay = 6 * time.Hour
+
+	delay := d.backoff << min(attempt-1, 20)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay/2 + mathrand.N(delay/2+1)
+}
+
+// NewSecret generates a random signing secret for a subscription.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,60 @@
+package outbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the HMAC of an outbound delivery in the form
+// "t=<unix seconds>,v1=<hex hmac-sha256(secret, t + "." + body)>".
+const SignatureHeader = "X-Crispay-Signature"
+
+// Sign returns the X-Crispay-Signature value for body at time ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, computeSignature(secret, t, body))
+}
+
+// VerifySignature checks an X-Crispay-Signature header on the receiving side.
+// Signatures older than tolerance are rejected to prevent replays.
+func VerifySignature(secret string, header string, body []byte, tolerance time.Duration) bool {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			v1 = value
+		}
+	}
+	if t == "" || v1 == "" {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return false
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)).Abs() > tolerance {
+		return false
+	}
+
+	return hmac.Equal([]byte(v1), []byte(computeSignature(secret, t, body)))
+}
+
+func computeSignature(secret string, t string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
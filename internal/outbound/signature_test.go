@@ -0,0 +1,67 @@
+package outbound
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"type":"verification.approved","data":{"session_id":"s1"}}`)
+	now := time.Now()
+	tolerance := 5 * time.Minute
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		want      bool
+	}{
+		{"valid", secret, Sign(secret, now, body), body, tolerance, true},
+		{"valid within tolerance", secret, Sign(secret, now.Add(-4*time.Minute), body), body, tolerance, true},
+		{"spaces after comma", secret, strings.Replace(Sign(secret, now, body), ",", ", ", 1), body, tolerance, true},
+		{"expired timestamp", secret, Sign(secret, now.Add(-6*time.Minute), body), body, tolerance, false},
+		{"timestamp in the future", secret, Sign(secret, now.Add(6*time.Minute), body), body, tolerance, false},
+		{"old timestamp without tolerance", secret, Sign(secret, now.Add(-24*time.Hour), body), body, 0, true},
+		{"wrong secret", "whsec_other", Sign(secret, now, body), body, tolerance, false},
+		{"tampered body", secret, Sign(secret, now, body), []byte(`{"type":"verification.failed"}`), tolerance, false},
+		{"timestamp swapped", secret, swapTimestamp(Sign(secret, now, body), now.Add(-time.Minute)), body, tolerance, false},
+		{"missing v1", secret, "t=" + timestamp(now), body, tolerance, false},
+		{"missing t", secret, strings.SplitN(Sign(secret, now, body), ",", 2)[1], body, tolerance, false},
+		{"bad timestamp", secret, "t=abc,v1=00", body, tolerance, false},
+		{"empty", secret, "", body, tolerance, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.header, tt.body, tt.tolerance); got != tt.want {
+				t.Errorf("VerifySignature(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignFormat(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	got := Sign("secret", ts, []byte("{}"))
+	if !strings.HasPrefix(got, "t=1700000000,v1=") || len(got) != len("t=1700000000,v1=")+64 {
+		t.Errorf("Sign = %q", got)
+	}
+	if Sign("secret", ts, []byte("{}")) != got {
+		t.Error("Sign is not deterministic")
+	}
+}
+
+func timestamp(ts time.Time) string {
+	return strconv.FormatInt(ts.Unix(), 10)
+}
+
+// swapTimestamp replaces the timestamp of a signature header, keeping its
+// HMAC.
+func swapTimestamp(header string, ts time.Time) string {
+	_, v1, _ := strings.Cut(header, ",")
+	return "t=" + timestamp(ts) + "," + v1
+}
@@ -0,0 +1,41 @@
+// Package pii identifies personal data inside the identity payloads Didit
+// sends us.
+package pii
+
+import "strings"
+
+// identityFields are the keys whose values identify a person. They are matched
+// case-insensitively at any depth of a payload.
+var identityFields = map[string]bool{
+	"first_name":        true,
+	"last_name":         true,
+	"full_name":         true,
+	"name":              true,
+	"email":             true,
+	"phone":             true,
+	"phone_number":      true,
+	"date_of_birth":     true,
+	"birth_date":        true,
+	"dob":               true,
+	"place_of_birth":    true,
+	"gender":            true,
+	"nationality":       true,
+	"address":           true,
+	"formatted_address": true,
+	"document_number":   true,
+	"personal_number":   true,
+	"id_number":         true,
+	"tax_id":            true,
+	"cpf":               true,
+	"ip_address":        true,
+	"portrait":          true,
+	"selfie":            true,
+	"video":             true,
+}
+
+// IsIdentityField reports whether values under key identify a person.
+func IsIdentityField(key string) bool {
+	key = strings.ToLower(key)
+	// Links to document photos, selfies and liveness videos.
+	return identityFields[key] || strings.HasSuffix(key, "_image") || strings.HasSuffix(key, "_url")
+}
@@ -0,0 +1,58 @@
+package privacy
+
+import (
+	"encoding/json"
+
+	"github.com/FelipePn10/crispaybackend/internal/pii"
+)
+
+// Redacted replaces erased identity values inside stored payloads.
+const Redacted = "[erased]"
+
+// ScrubPayload returns a copy of a JSON document with every identity field
+// replaced by Redacted. Identifiers, statuses, event types and timestamps are
+// kept so the decision trail survives the erasure. It also reports whether
+// anything was changed.
+func ScrubPayload(payload []byte) ([]byte, bool, error) {
+	var doc any
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, false, err
+	}
+
+	doc, changed := scrub(doc)
+	if !changed {
+		return payload, false, nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+func scrub(v any) (any, bool) {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if pii.IsIdentityField(key) {
+				if value != nil && value != Redacted {
+					v[key] = Redacted
+					changed = true
+				}
+				continue
+			}
+			var c bool
+			v[key], c = scrub(value)
+			changed = changed || c
+		}
+	case []any:
+		for i, value := range v {
+			var c bool
+			v[i], c = scrub(value)
+			changed = changed || c
+		}
+	}
+	return v, changed
+}
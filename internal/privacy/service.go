@@ -0,0 +1,240 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/media"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+)
+
+// ErrLegalHold is returned when erasing the data of a user under legal hold.
+var ErrLegalHold = errors.New("user is under legal hold")
+
+// Export is everything stored about a user.
+type Export struct {
+	UserID        string                        `json:"user_id"`
+	GeneratedAt   time.Time                     `json:"generated_at"`
+	Sessions      []*models.VerificationSession `json:"sessions"`
+	WebhookEvents []*models.WebhookEventDB      `json:"webhook_events"`
+	Deliveries    []*models.WebhookDelivery     `json:"deliveries"`
+	AuditLog      []*models.AuditLogEntry       `json:"audit_log"`
+	// Media lists the verification files kept, without their contents.
+	Media []*models.VerificationMedia `json:"media"`
+}
+
+// ErasureReport summarizes what an erasure changed.
+type ErasureReport struct {
+	UserID           string    `json:"user_id"`
+	SessionsErased   int       `json:"sessions_erased"`
+	WebhooksScrubbed int       `json:"webhooks_scrubbed"`
+	CPFsReleased     int       `json:"cpfs_released"`
+	MediaDeleted     int       `json:"media_deleted"`
+	ErasedAt         time.Time `json:"erased_at"`
+}
+
+// Service implements the LGPD/GDPR access and erasure rights over KYC data.
+type Service struct {
+	repo    *repository.VerificationRepository
+	subRepo *repository.SubscriptionRepository
+	audit   *repository.AuditRepository
+	holds   *repository.RetentionRepository
+	media   *media.Service
+}
+
+func NewService(repo *repository.VerificationRepository, subRepo *repository.SubscriptionRepository, audit *repository.AuditRepository, holds *repository.RetentionRepository, mediaService *media.Service) *Service {
+	return &Service{
+		repo:    repo,
+		subRepo: subRepo,
+		audit:   audit,
+		holds:   holds,
+		media:   mediaService,
+	}
+}
+
+// Export collects the user's sessions, webhook events, outbound deliveries,
+// audit trail and the list of media kept, and audits the export itself.
+func (s *Service) Export(ctx context.Context, userID string, actor string, format string) (*Export, error) {
+	sessions, err := s.repo.ListVerificationSessionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.ListWebhookEventsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := s.subRepo.ListDeliveriesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	auditLog, err := s.audit.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	userMedia, err := s.media.UserMedia(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{
+		UserID:        userID,
+		GeneratedAt:   time.Now().UTC(),
+		Sessions:      sessions,
+		WebhookEvents: events,
+		Deliveries:    deliveries,
+		AuditLog:      auditLog,
+		Media:         userMedia,
+	}
+
+	_, err = s.audit.Record(ctx, models.AuditEvent{
+		Action:        models.AuditPrivacyExport,
+		SubjectUserID: userID,
+		Actor:         actor,
+		EntityType:    "user",
+		EntityID:      userID,
+		Details: map[string]any{
+			"format":         format,
+			"sessions":       len(sessions),
+			"webhook_events": len(events),
+			"deliveries":     len(deliveries),
+			"media":          len(userMedia),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// Erase pseudonymizes the user's sessions and scrubs identity fields from
+// the stored and archived webhook payloads, and deletes their verification
+// media. Session ids, statuses, event types and timestamps are kept because
+// AML rules require the verification trail to be retained. Users under legal
+// hold cannot be erased.
+//
+// The media files go first, each with its own audit entry, since a deleted
+// file cannot be rolled back. Everything else and the audit of the erasure
+// commit together, so a failure leaves nothing half erased and erasing again
+// finishes the job.
+func (s *Service) Erase(ctx context.Context, userID string, actor string) (*ErasureReport, error) {
+	hold, err := s.holds.GetLegalHold(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if hold != nil {
+		return nil, ErrLegalHold
+	}
+
+	report := &ErasureReport{UserID: userID}
+	report.MediaDeleted, err = s.media.EraseUser(ctx, userID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		// A hold placed while the media were deleted still stops the rest.
+		hold, err := repository.NewRetentionRepository(repo.Queries()).GetLegalHold(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if hold != nil {
+			return ErrLegalHold
+		}
+
+		events, err := repo.ListWebhookEventsByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			payload, changed, err := ScrubPayload(event.Payload)
+			if err != nil {
+				log.Printf("Skipping unreadable payload of webhook event %s: %v", event.ID, err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if err := repo.UpdateWebhookEventPayload(ctx, event.ID, payload); err != nil {
+				return err
+			}
+			report.WebhooksScrubbed++
+		}
+
+		// Archived events are scrubbed on the next partition maintenance.
+		sessions, err := repo.ListVerificationSessionsByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		var diditSessionIDs []string
+		for _, session := range sessions {
+			if session.DiditSessionID != "" {
+				diditSessionIDs = append(diditSessionIDs, session.DiditSessionID)
+			}
+		}
+		if err := repo.QueueArchiveErasure(ctx, userID, diditSessionIDs); err != nil {
+			return err
+		}
+
+		report.SessionsErased, err = repo.EraseUserSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		report.CPFsReleased, err = repo.ReleaseUserCPFs(ctx, userID)
+		if err != nil {
+			return err
+		}
+		report.ErasedAt = time.Now().UTC()
+
+		_, err = repository.NewAuditRepository(repo.Queries()).Record(ctx, models.AuditEvent{
+			Action:        models.AuditPrivacyErase,
+			SubjectUserID: userID,
+			Actor:         actor,
+			EntityType:    "user",
+			EntityID:      userID,
+			Details:       report,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// WriteZip writes the export as a ZIP archive with one JSON file per section.
+func WriteZip(w io.Writer, export *Export) error {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"export.json", map[string]any{"user_id": export.UserID, "generated_at": export.GeneratedAt}},
+		{"sessions.json", export.Sessions},
+		{"webhook_events.json", export.WebhookEvents},
+		{"deliveries.json", export.Deliveries},
+		{"audit_log.json", export.AuditLog},
+		{"media.json", export.Media},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to export: %v", file.name, err)
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return fmt.Errorf("failed to write %s to export: %v", file.name, err)
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) TakeAll(_ context.Context, takes []Take) ([]Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	buckets := make([]*bucket, len(takes))
+	allowed := true
+	for i, t := range takes {
+		b, ok := s.buckets[t.Key]
+		if !ok {
+			b = &bucket{tokens: float64(t.Rate.Burst), updated: now}
+			s.buckets[t.Key] = b
+		}
+		b.tokens = min(float64(t.Rate.Burst), b.tokens+now.Sub(b.updated).Seconds()*t.Rate.Limit)
+		b.updated = now
+		buckets[i] = b
+		allowed = allowed && b.tokens >= 1
+	}
+
+	decisions := make([]Decision, len(takes))
+	for i, b := range buckets {
+		if allowed {
+			b.tokens--
+			decisions[i] = decide(true, b.tokens, takes[i].Rate)
+		} else {
+			decisions[i] = decide(b.tokens >= 1, b.tokens, takes[i].Rate)
+		}
+	}
+	return decisions, nil
+}
+
+func (s *MemoryStore) Prune(_ context.Context, idleSince time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if b.updated.Before(idleSince) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// Policy limits requests sharing the same key. Key returning "" skips the
+// policy for that request.
+type Policy struct {
+	Name string
+	Rate Rate
+	Key  func(c *gin.Context) string
+}
+
+// Limiter applies policies against a Store.
+type Limiter struct {
+	store Store
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+	}
+}
+
+// Middleware rejects requests with 429 once any policy's bucket is empty.
+// Every policy is checked before a token is taken from any of them, so a
+// request rejected by one policy does not spend the others. Buckets belong to
+// the request's tenant. When the store is unavailable the request is let
+// through: an outage of the limiter must not take the API down with it.
+func (l *Limiter) Middleware(policies ...Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		prefix := tenant.ID(c.Request.Context()).String() + ":"
+		var applied []Policy
+		var takes []Take
+		for _, p := range policies {
+			if p.Rate.Disabled() {
+				continue
+			}
+			key := p.Key(c)
+			if key == "" {
+				continue
+			}
+			applied = append(applied, p)
+			takes = append(takes, Take{Key: prefix + p.Name + ":" + key, Rate: p.Rate})
+		}
+		if len(takes) == 0 {
+			c.Next()
+			return
+		}
+
+		decisions, err := l.store.TakeAll(c.Request.Context(), takes)
+		if err != nil {
+			log.Printf("Rate limiter unavailable: %v", err)
+			c.Next()
+			return
+		}
+
+		for i, decision := range decisions {
+			if !decision.Allowed {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error": "Rate limit exceeded",
+					"scope": applied[i].Name,
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Run prunes idle buckets periodically until ctx is cancelled.
+func (l *Limiter) Run(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.store.Prune(ctx, time.Now().Add(-24*time.Hour)); err != nil {
+				log.Printf("Failed to prune rate limit buckets: %v", err)
+			}
+		}
+	}
+}
+
+// GlobalKey puts every request in the same bucket.
+func GlobalKey(*gin.Context) string {
+	return "all"
+}
+
+// ClientIPKey buckets requests by client IP.
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// JSONFieldKey buckets requests by a top-level string field of the JSON body.
+// The body is restored so the handler can still bind it.
+func JSONFieldKey(field string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return ""
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var fields map[string]any
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return ""
+		}
+		value, _ := fields[field].(string)
+		return value
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every API
+// replica enforces the same limits.
+type PostgresStore struct {
+	pool    *pgxpool.Pool
+	queries *sqlc.Queries
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{
+		pool:    pool,
+		queries: sqlc.New(pool),
+	}
+}
+
+// TakeAll refills and locks every bucket in one transaction and spends their
+// tokens only once all of them have one. Buckets are locked in key order so
+// concurrent requests sharing buckets cannot deadlock.
+func (s *PostgresStore) TakeAll(ctx context.Context, takes []Take) ([]Decision, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin rate limit transaction: %v", err)
+	}
+	// Rolling back a committed transaction does nothing.
+	defer tx.Rollback(context.WithoutCancel(ctx))
+	queries := s.queries.WithTx(tx)
+
+	order := make([]int, len(takes))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return strings.Compare(takes[a].Key, takes[b].Key)
+	})
+
+	tokens := make([]float64, len(takes))
+	allowed := true
+	for _, i := range order {
+		tokens[i], err = queries.RefillRateLimitBucket(ctx, sqlc.RefillRateLimitBucketParams{
+			Key:   takes[i].Key,
+			Burst: float64(takes[i].Rate.Burst),
+			Rate:  takes[i].Rate.Limit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to refill rate limit bucket: %v", err)
+		}
+		allowed = allowed && tokens[i] >= 1
+	}
+
+	decisions := make([]Decision, len(takes))
+	for _, i := range order {
+		if !allowed {
+			decisions[i] = decide(tokens[i] >= 1, tokens[i], takes[i].Rate)
+			continue
+		}
+		if err := queries.SpendRateLimitToken(ctx, takes[i].Key); err != nil {
+			return nil, fmt.Errorf("failed to take rate limit token: %v", err)
+		}
+		decisions[i] = decide(true, tokens[i]-1, takes[i].Rate)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit rate limit transaction: %v", err)
+	}
+	return decisions, nil
+}
+
+func (s *PostgresStore) Prune(ctx context.Context, idleSince time.Time) error {
+	if _, err := s.queries.DeleteIdleRateLimitBuckets(ctx, idleSince); err != nil {
+		return fmt.Errorf("failed to prune rate limit buckets: %v", err)
+	}
+	return nil
+}
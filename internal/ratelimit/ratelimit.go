@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate is a token bucket refilled at Limit tokens per second holding at most
+// Burst tokens.
+type Rate struct {
+	Limit float64
+	Burst int
+}
+
+// Disabled reports whether the rate imposes no limit.
+func (r Rate) Disabled() bool {
+	return r.Burst <= 0 || r.Limit <= 0
+}
+
+// ParseRate parses rates written as "<requests>/<period>", e.g. "10/1h" or
+// "100/1m". The bucket allows a burst of the full request count. An empty
+// string or "0" disables the limit.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Rate{}, nil
+	}
+
+	countStr, periodStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q: expected <requests>/<period>", s)
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count < 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: bad request count", s)
+	}
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: bad period", s)
+	}
+
+	return Rate{Limit: float64(count) / period.Seconds(), Burst: count}, nil
+}
+
+// Decision is the result of taking a token from a bucket.
+type Decision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Take names a bucket and the rate it is refilled at.
+type Take struct {
+	Key  string
+	Rate Rate
+}
+
+// Store keeps token buckets. MemoryStore serves a single replica;
+// PostgresStore shares buckets between replicas.
+type Store interface {
+	// TakeAll takes one token from every bucket, or none of them when any
+	// bucket is empty, and returns the decision of each bucket in order.
+	TakeAll(ctx context.Context, takes []Take) ([]Decision, error)
+	Prune(ctx context.Context, idleSince time.Time) error
+}
+
+// decide builds a Decision from the bucket level left after a take.
+func decide(allowed bool, tokens float64, rate Rate) Decision {
+	d := Decision{
+		Allowed:   allowed,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+	}
+	if !allowed {
+		missing := 1 - tokens
+		d.RetryAfter = time.Duration(math.Ceil(missing/rate.Limit)) * time.Second
+	}
+	return d
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type AuditRepository struct {
+	queries *sqlc.Queries
+}
+
+func NewAuditRepository(queries *sqlc.Queries) *AuditRepository {
+	return &AuditRepository{
+		queries: queries,
+	}
+}
+
+// Record appends an entry to the audit log. The database assigns its
+// sequence number and chains it to the previous entry under a global lock
+// held until the transaction ends, so inside WithTx it belongs at the end,
+// after any slow work.
+func (r *AuditRepository) Record(ctx context.Context, event models.AuditEvent) (*models.AuditLogEntry, error) {
+	details := event.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+	payload, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit details: %v", err)
+	}
+
+	result, err := r.queries.CreateAuditLogEntry(ctx, sqlc.CreateAuditLogEntryParams{
+		Action:        event.Action,
+		SubjectUserID: event.SubjectUserID,
+		Actor:         event.Actor,
+		EntityType:    event.EntityType,
+		EntityID:      event.EntityID,
+		Details:       payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit log entry: %v", err)
+	}
+
+	return r.toDomainModel(result), nil
+}
+
+func (r *AuditRepository) ListByUserID(ctx context.Context, userID string) ([]*models.AuditLogEntry, error) {
+	results, err := r.queries.ListAuditLogEntriesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log entries: %v", err)
+	}
+
+	return r.toDomainModels(results), nil
+}
+
+// AuditFilter selects audit log entries. Zero-valued fields are not applied.
+type AuditFilter struct {
+	SubjectUserID string
+	Action        string
+	EntityID      string
+	Since         time.Time
+	BeforeSeq     int64
+	Limit         int
+}
+
+// List returns the entries matching the filter, newest first. Pass the
+// lowest seq of a page as BeforeSeq to get the next one.
+func (r *AuditRepository) List(ctx context.Context, filter AuditFilter) ([]*models.AuditLogEntry, error) {
+	results, err := r.queries.ListAuditLogEntries(ctx, sqlc.ListAuditLogEntriesParams{
+		SubjectUserID: pgtype.Text{String: filter.SubjectUserID, Valid: filter.SubjectUserID != ""},
+		Action:        pgtype.Text{String: filter.Action, Valid: filter.Action != ""},
+		EntityID:      pgtype.Text{String: filter.EntityID, Valid: filter.EntityID != ""},
+		Since:         pgtype.Timestamptz{Time: filter.Since, Valid: !filter.Since.IsZero()},
+		BeforeSeq:     pgtype.Int8{Int64: filter.BeforeSeq, Valid: filter.BeforeSeq > 0},
+		MaxRows:       int32(filter.Limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log entries: %v", err)
+	}
+
+	return r.toDomainModels(results), nil
+}
+
+// AuditVerifyReport is the result of checking the audit log hash chain.
+type AuditVerifyReport struct {
+	Checked   int    `json:"checked"`
+	Valid     bool   `json:"valid"`
+	BrokenSeq int64  `json:"broken_seq,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// VerifyChain recomputes the hash of every entry in order and checks that
+// each one links to its predecessor and that no sequence number is missing.
+func (r *AuditRepository) VerifyChain(ctx context.Context, batchSize int) (*AuditVerifyReport, error) {
+	report := &AuditVerifyReport{Valid: true}
+
+	var (
+		lastSeq  int64
+		lastHash string
+	)
+	for {
+		results, err := r.queries.ListAuditLogEntriesAfterSeq(ctx, sqlc.ListAuditLogEntriesAfterSeqParams{
+			Seq:   lastSeq,
+			Limit: int32(batchSize),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list audit log entries: %v", err)
+		}
+
+		for _, result := range results {
+			entry := r.toDomainModel(result)
+			report.Checked++
+
+			switch {
+			case entry.Seq != lastSeq+1:
+				report.Reason = fmt.Sprintf("expected seq %d, found %d", lastSeq+1, entry.Seq)
+			case entry.PrevHash != lastHash:
+				report.Reason = "prev_hash does not match the previous entry"
+			case entry.Hash != AuditHash(entry):
+				report.Reason = "hash does not match the entry contents"
+			}
+			if report.Reason != "" {
+				report.Valid = false
+				report.BrokenSeq = entry.Seq
+				return report, nil
+			}
+
+			lastSeq = entry.Seq
+			lastHash = entry.Hash
+		}
+		if len(results) < batchSize {
+			return report, nil
+		}
+	}
+}
+
+// AuditHash computes the chained hash of an entry. It mirrors the
+// audit_log_hash SQL function.
+func AuditHash(entry *models.AuditLogEntry) string {
+	fields := []string{
+		strconv.FormatInt(entry.Seq, 10),
+		entry.PrevHash,
+		entry.Action,
+		entry.SubjectUserID,
+		entry.Actor,
+		entry.EntityType,
+		entry.EntityID,
+		string(entry.Details),
+		strconv.FormatInt(entry.CreatedAt.UnixMicro(), 10),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *AuditRepository) toDomainModels(results []sqlc.AuditLog) []*models.AuditLogEntry {
+	entries := make([]*models.AuditLogEntry, len(results))
+	for i, result := range results {
+		entries[i] = r.toDomainModel(result)
+	}
+	return entries
+}
+
+func (r *AuditRepository) toDomainModel(dbEntry sqlc.AuditLog) *models.AuditLogEntry {
+	return &models.AuditLogEntry{
+		ID:            dbEntry.ID,
+		Seq:           dbEntry.Seq,
+		Action:        dbEntry.Action,
+		SubjectUserID: dbEntry.SubjectUserID,
+		Actor:         dbEntry.Actor,
+		EntityType:    dbEntry.EntityType,
+		EntityID:      dbEntry.EntityID,
+		Details:       json.RawMessage(dbEntry.Details),
+		CreatedAt:     dbEntry.CreatedAt,
+		PrevHash:      dbEntry.PrevHash,
+		Hash:          dbEntry.Hash,
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestAuditHash pins the fields and format hashed, so a change to AuditHash
+// that the audit_log_hash SQL function does not follow breaks here.
+func TestAuditHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry models.AuditLogEntry
+		want  string
+	}{
+		{
+			name: "first entry",
+			entry: models.AuditLogEntry{
+				Seq:           1,
+				Action:        "session.created",
+				SubjectUserID: "user-1",
+				Actor:         "system",
+				EntityType:    "session",
+				EntityID:      "s1",
+				Details:       json.RawMessage(`{"level": "basic"}`),
+				CreatedAt:     time.UnixMicro(1700000000123456),
+			},
+			want: "5587c35e71f47ee91693cf1f9ebf79fa614857846e0c887327cf834ae8a3137c",
+		},
+		{
+			name: "chained entry with accents",
+			entry: models.AuditLogEntry{
+				Seq:           2,
+				PrevHash:      "abc",
+				Action:        "privacy.erase",
+				SubjectUserID: "joão",
+				Actor:         "admin:ana",
+				EntityType:    "user",
+				EntityID:      "joão",
+				Details:       json.RawMessage(`{}`),
+				CreatedAt:     time.UnixMicro(1700000000000000),
+			},
+			want: "95d0ae5cb2c667d9ba5622a1673e6065d8af356e0762e0a7b3dbe05e030c61b5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AuditHash(&tt.entry); got != tt.want {
+				t.Errorf("AuditHash = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAuditHashMatchesTrigger appends entries through the audit_log_chain
+// trigger, in a transaction that is rolled back, and checks AuditHash agrees
+// with the hash the database computed. It needs a migrated database:
+//
+//	DATABASE_URL=postgres://... go test -run AuditHashMatchesTrigger ./internal/repository
+func TestAuditHashMatchesTrigger(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Skipf("database unavailable: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	audit := NewAuditRepository(sqlc.New(tx))
+
+	tests := []struct {
+		name  string
+		event models.AuditEvent
+	}{
+		{"empty fields", models.AuditEvent{Action: "test.empty", Actor: "test"}},
+		{"every field", models.AuditEvent{
+			Action:        "session.created",
+			SubjectUserID: "user-1",
+			Actor:         "client:127.0.0.1",
+			EntityType:    "session",
+			EntityID:      "s1",
+			Details:       map[string]any{"level": "basic", "provider": "didit"},
+		}},
+		{"unicode and nesting", models.AuditEvent{
+			Action:        "privacy.erase",
+			SubjectUserID: "joão",
+			Actor:         "admin:ana|ops",
+			EntityType:    "user",
+			EntityID:      "joão",
+			Details:       map[string]any{"name": "São Paulo", "nested": map[string]any{"n": 1.5, "list": []any{"a", nil, true}}},
+		}},
+	}
+	var prev string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := audit.Record(ctx, tt.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prev != "" && entry.PrevHash != prev {
+				t.Errorf("prev_hash = %s, want %s", entry.PrevHash, prev)
+			}
+			prev = entry.Hash
+			if got := AuditHash(entry); got != entry.Hash {
+				t.Errorf("AuditHash = %s, trigger computed %s", got, entry.Hash)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type CompanyRepository struct {
+	queries *sqlc.Queries
+}
+
+func NewCompanyRepository(queries *sqlc.Queries) *CompanyRepository {
+	return &CompanyRepository{
+		queries: queries,
+	}
+}
+
+func (r *CompanyRepository) CreateCompany(ctx context.Context, company *models.Company) (*models.Company, error) {
+	address, err := json.Marshal(company.RegisteredAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode registered address: %v", err)
+	}
+
+	result, err := r.queries.CreateCompany(ctx, sqlc.CreateCompanyParams{
+		Cnpj:              company.CNPJ,
+		LegalName:         company.LegalName,
+		TradeName:         pgtype.Text{String: company.TradeName, Valid: company.TradeName != ""},
+		RegisteredAddress: address,
+		OwnerUserID:       company.OwnerUserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create company: %v", err)
+	}
+	return companyToDomainModel(result)
+}
+
+func (r *CompanyRepository) GetCompany(ctx context.Context, id uuid.UUID) (*models.Company, error) {
+	result, err := r.queries.GetCompany(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get company: %v", err)
+	}
+	return companyToDomainModel(result)
+}
+
+func (r *CompanyRepository) GetCompanyByCNPJ(ctx context.Context, cnpj string) (*models.Company, error) {
+	result, err := r.queries.GetCompanyByCNPJ(ctx, cnpj)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get company: %v", err)
+	}
+	return companyToDomainModel(result)
+}
+
+func (r *CompanyRepository) ListCompaniesByOwner(ctx context.Context, ownerUserID string) ([]*models.Company, error) {
+	results, err := r.queries.ListCompaniesByOwnerUserID(ctx, ownerUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list companies: %v", err)
+	}
+
+	companies := make([]*models.Company, len(results))
+	for i, result := range results {
+		if companies[i], err = companyToDomainModel(result); err != nil {
+			return nil, err
+		}
+	}
+	return companies, nil
+}
+
+// ListCompanyIDs returns every company, oldest first.
+func (r *CompanyRepository) ListCompanyIDs(ctx context.Context) ([]uuid.UUID, error) {
+	ids, err := r.queries.ListCompanyIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list companies: %v", err)
+	}
+	return ids, nil
+}
+
+// UpdateCompanyStatus sets the aggregate status. It returns nil when the
+// company already had that status.
+func (r *CompanyRepository) UpdateCompanyStatus(ctx context.Context, id uuid.UUID, status string) (*models.Company, error) {
+	result, err := r.queries.UpdateCompanyStatus(ctx, sqlc.UpdateCompanyStatusParams{
+		ID:     id,
+		Status: status,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to update company status: %v", err)
+	}
+	return companyToDomainModel(result)
+}
+
+func (r *CompanyRepository) CreatePerson(ctx context.Context, person *models.CompanyPerson) (*models.CompanyPerson, error) {
+	ownership := pgtype.Float8{}
+	if person.OwnershipPercent != nil {
+		ownership = pgtype.Float8{Float64: *person.OwnershipPercent, Valid: true}
+	}
+
+	result, err := r.queries.CreateCompanyPerson(ctx, sqlc.CreateCompanyPersonParams{
+		CompanyID:        person.CompanyID,
+		Role:             person.Role,
+		UserID:           person.UserID,
+		OwnershipPercent: ownership,
+		Required:         person.Required,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create company person: %v", err)
+	}
+	return companyPersonToDomainModel(result), nil
+}
+
+func (r *CompanyRepository) GetPerson(ctx context.Context, companyID uuid.UUID, id uuid.UUID) (*models.CompanyPerson, error) {
+	result, err := r.queries.GetCompanyPerson(ctx, sqlc.GetCompanyPersonParams{
+		ID:        id,
+		CompanyID: companyID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get company person: %v", err)
+	}
+	return companyPersonToDomainModel(result), nil
+}
+
+func (r *CompanyRepository) ListPeople(ctx context.Context, companyID uuid.UUID) ([]*models.CompanyPerson, error) {
+	results, err := r.queries.ListCompanyPeople(ctx, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list company people: %v", err)
+	}
+
+	people := make([]*models.CompanyPerson, len(results))
+	for i, result := range results {
+		people[i] = companyPersonToDomainModel(result)
+	}
+	return people, nil
+}
+
+// SetPersonSession links a person to the verification session that covers
+// them.
+func (r *CompanyRepository) SetPersonSession(ctx context.Context, id uuid.UUID, sessionID string) error {
+	err := r.queries.SetCompanyPersonSession(ctx, sqlc.SetCompanyPersonSessionParams{
+		ID:        id,
+		SessionID: pgtype.Text{String: sessionID, Valid: sessionID != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to link company person session: %v", err)
+	}
+	return nil
+}
+
+// ListCompanyIDsByPerson returns the companies a user is linked to.
+func (r *CompanyRepository) ListCompanyIDsByPerson(ctx context.Context, userID string) ([]uuid.UUID, error) {
+	ids, err := r.queries.ListCompanyIDsByPersonUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list companies of person: %v", err)
+	}
+	return ids, nil
+}
+
+func companyToDomainModel(dbCompany sqlc.Company) (*models.Company, error) {
+	company := &models.Company{
+		ID:          dbCompany.ID,
+		CNPJ:        dbCompany.Cnpj,
+		LegalName:   dbCompany.LegalName,
+		TradeName:   dbCompany.TradeName.String,
+		OwnerUserID: dbCompany.OwnerUserID,
+		Status:      dbCompany.Status,
+		CreatedAt:   dbCompany.CreatedAt,
+		UpdatedAt:   dbCompany.UpdatedAt,
+	}
+	if err := json.Unmarshal(dbCompany.RegisteredAddress, &company.RegisteredAddress); err != nil {
+		return nil, fmt.Errorf("failed to decode registered address: %v", err)
+	}
+	if dbCompany.ApprovedAt.Valid {
+		company.ApprovedAt = &dbCompany.ApprovedAt.Time
+	}
+	return company, nil
+}
+
+func companyPersonToDomainModel(dbPerson sqlc.CompanyPeople) *models.CompanyPerson {
+	person := &models.CompanyPerson{
+		ID:        dbPerson.ID,
+		CompanyID: dbPerson.CompanyID,
+		Role:      dbPerson.Role,
+		UserID:    dbPerson.UserID,
+		Required:  dbPerson.Required,
+		SessionID: dbPerson.SessionID.String,
+		CreatedAt: dbPerson.CreatedAt,
+	}
+	if dbPerson.OwnershipPercent.Valid {
+		person.OwnershipPercent = &dbPerson.OwnershipPercent.Float64
+	}
+	return person
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ClaimCPF records userID as the holder of a normalized CPF unless another
+// user of the tenant already claimed it, and returns the holder.
+func (r *VerificationRepository) ClaimCPF(ctx context.Context, cpf string, userID string) (string, error) {
+	index, err := r.crypt.CPFIndex(ctx, cpf)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute cpf index: %v", err)
+	}
+
+	holder, err := r.queries.ClaimCPF(ctx, sqlc.ClaimCPFParams{
+		CpfIndex: index,
+		UserID:   userID,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to claim cpf: %v", err)
+	}
+	return holder, nil
+}
+
+// ReleaseUserCPFs drops the CPF claims of a user, used when their data is
+// erased.
+func (r *VerificationRepository) ReleaseUserCPFs(ctx context.Context, userID string) (int, error) {
+	n, err := r.queries.DeleteUserCPFsByUserID(ctx, sqlc.DeleteUserCPFsByUserIDParams{
+		UserID:   userID,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to release user cpfs: %v", err)
+	}
+	return int(n), nil
+}
+
+func (r *VerificationRepository) SetSessionCPFMatch(ctx context.Context, sessionID string, match bool) error {
+	err := r.queries.SetVerificationSessionCPFMatch(ctx, sqlc.SetVerificationSessionCPFMatchParams{
+		SessionID: sessionID,
+		CpfMatch:  pgtype.Bool{Bool: match, Valid: true},
+		TenantID:  tenant.ID(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set session cpf match: %v", err)
+	}
+	return nil
+}
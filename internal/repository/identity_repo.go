@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// IdentityFingerprint hashes the identity document of a session with the
+// blind index key. It is empty when a part is missing.
+func (r *VerificationRepository) IdentityFingerprint(ctx context.Context, documentNumber string, country string, dateOfBirth string) (string, error) {
+	fingerprint, err := r.crypt.IdentityFingerprint(ctx, documentNumber, country, dateOfBirth)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute identity fingerprint: %v", err)
+	}
+	return fingerprint, nil
+}
+
+func (r *VerificationRepository) SetSessionFingerprint(ctx context.Context, sessionID string, fingerprint string) error {
+	err := r.queries.SetVerificationSessionFingerprint(ctx, sqlc.SetVerificationSessionFingerprintParams{
+		SessionID:           sessionID,
+		IdentityFingerprint: pgtype.Text{String: fingerprint, Valid: fingerprint != ""},
+		TenantID:            tenant.ID(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set session fingerprint: %v", err)
+	}
+	return nil
+}
+
+// ListDuplicateIdentities returns the latest approved session of every other
+// user of the tenant whose verified document has the same fingerprint.
+func (r *VerificationRepository) ListDuplicateIdentities(ctx context.Context, fingerprint string, userID string) ([]*models.VerificationSession, error) {
+	results, err := r.queries.ListOtherUserSessionsByFingerprint(ctx, sqlc.ListOtherUserSessionsByFingerprintParams{
+		IdentityFingerprint: fingerprint,
+		TenantID:            tenant.ID(ctx),
+		UserID:              userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions by fingerprint: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var latest []sqlc.VerificationSession
+	for _, result := range results {
+		if !seen[result.UserID] {
+			seen[result.UserID] = true
+			latest = append(latest, result)
+		}
+	}
+	return r.toDomainModels(ctx, latest)
+}
+
+type IdentityRepository struct {
+	queries *sqlc.Queries
+}
+
+func NewIdentityRepository(queries *sqlc.Queries) *IdentityRepository {
+	return &IdentityRepository{
+		queries: queries,
+	}
+}
+
+// CreateLink links a session to the session of another user with the same
+// identity. Linking the same pair again only updates the action taken.
+func (r *IdentityRepository) CreateLink(ctx context.Context, session *models.VerificationSession, linked *models.VerificationSession, action string) (*models.IdentityLink, error) {
+	result, err := r.queries.CreateIdentityLink(ctx, sqlc.CreateIdentityLinkParams{
+		SessionID:       session.SessionID,
+		UserID:          session.UserID,
+		LinkedSessionID: linked.SessionID,
+		LinkedUserID:    linked.UserID,
+		Action:          action,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity link: %v", err)
+	}
+	return identityLinkToDomainModel(result), nil
+}
+
+func (r *IdentityRepository) GetLink(ctx context.Context, id uuid.UUID) (*models.IdentityLink, error) {
+	result, err := r.queries.GetIdentityLink(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get identity link: %v", err)
+	}
+	return identityLinkToDomainModel(result), nil
+}
+
+func (r *IdentityRepository) ListByStatus(ctx context.Context, status string, limit int) ([]*models.IdentityLink, error) {
+	results, err := r.queries.ListIdentityLinksByStatus(ctx, sqlc.ListIdentityLinksByStatusParams{
+		Status: status,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identity links: %v", err)
+	}
+	return identityLinksToDomainModels(results), nil
+}
+
+// ListByUserID returns the links on either side of a user.
+func (r *IdentityRepository) ListByUserID(ctx context.Context, userID string) ([]*models.IdentityLink, error) {
+	results, err := r.queries.ListIdentityLinksByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identity links: %v", err)
+	}
+	return identityLinksToDomainModels(results), nil
+}
+
+// ResolveLink closes an open link. It returns nil when the link does not
+// exist or was already resolved.
+func (r *IdentityRepository) ResolveLink(ctx context.Context, id uuid.UUID, status string, resolution string, resolvedBy string) (*models.IdentityLink, error) {
+	result, err := r.queries.ResolveIdentityLink(ctx, sqlc.ResolveIdentityLinkParams{
+		ID:         id,
+		Status:     status,
+		Resolution: pgtype.Text{String: resolution, Valid: resolution != ""},
+		ResolvedBy: pgtype.Text{String: resolvedBy, Valid: resolvedBy != ""},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to resolve identity link: %v", err)
+	}
+	return identityLinkToDomainModel(result), nil
+}
+
+func identityLinksToDomainModels(results []sqlc.IdentityLink) []*models.IdentityLink {
+	links := make([]*models.IdentityLink, len(results))
+	for i, result := range results {
+		links[i] = identityLinkToDomainModel(result)
+	}
+	return links
+}
+
+func identityLinkToDomainModel(dbLink sqlc.IdentityLink) *models.IdentityLink {
+	link := &models.IdentityLink{
+		ID:              dbLink.ID,
+		SessionID:       dbLink.SessionID,
+		UserID:          dbLink.UserID,
+		LinkedSessionID: dbLink.LinkedSessionID,
+		LinkedUserID:    dbLink.LinkedUserID,
+		Action:          dbLink.Action,
+		Status:          dbLink.Status,
+		Resolution:      dbLink.Resolution.String,
+		ResolvedBy:      dbLink.ResolvedBy.String,
+		CreatedAt:       dbLink.CreatedAt,
+	}
+	if dbLink.ResolvedAt.Valid {
+		link.ResolvedAt = &dbLink.ResolvedAt.Time
+	}
+	return link
+}
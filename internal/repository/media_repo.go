@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// MediaRepository stores where the verification media of each session are
+// kept. The files themselves live in the blob store.
+type MediaRepository struct {
+	queries *sqlc.Queries
+}
+
+func NewMediaRepository(queries *sqlc.Queries) *MediaRepository {
+	return &MediaRepository{
+		queries: queries,
+	}
+}
+
+func (r *MediaRepository) CreateMedia(ctx context.Context, m *models.VerificationMedia) (*models.VerificationMedia, error) {
+	result, err := r.queries.CreateVerificationMedia(ctx, sqlc.CreateVerificationMediaParams{
+		SessionID:    m.SessionID,
+		UserID:       m.UserID,
+		Kind:         m.Kind,
+		StorageKey:   m.StorageKey,
+		ContentType:  m.ContentType,
+		SizeBytes:    m.SizeBytes,
+		Sha256:       m.SHA256,
+		StoredSha256: m.StoredSHA256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verification media: %v", err)
+	}
+	return toMedia(result), nil
+}
+
+func (r *MediaRepository) GetMedia(ctx context.Context, id uuid.UUID) (*models.VerificationMedia, error) {
+	result, err := r.queries.GetVerificationMedia(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get verification media: %v", err)
+	}
+	return toMedia(result), nil
+}
+
+func (r *MediaRepository) ListSessionMedia(ctx context.Context, sessionID string) ([]*models.VerificationMedia, error) {
+	results, err := r.queries.ListVerificationMediaBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session media: %v", err)
+	}
+	return toMediaList(results), nil
+}
+
+func (r *MediaRepository) ListUserMedia(ctx context.Context, userID string) ([]*models.VerificationMedia, error) {
+	results, err := r.queries.ListVerificationMediaByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user media: %v", err)
+	}
+	return toMediaList(results), nil
+}
+
+func (r *MediaRepository) DeleteMedia(ctx context.Context, id uuid.UUID) error {
+	if err := r.queries.DeleteVerificationMedia(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete verification media: %v", err)
+	}
+	return nil
+}
+
+// CountExpiredMedia counts the media of decided sessions last updated before
+// the cutoff, skipping users under legal hold.
+func (r *MediaRepository) CountExpiredMedia(ctx context.Context, before time.Time) (int, error) {
+	n, err := r.queries.CountExpiredVerificationMedia(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expired media: %v", err)
+	}
+	return int(n), nil
+}
+
+func (r *MediaRepository) ListExpiredMedia(ctx context.Context, before time.Time, limit int) ([]*models.VerificationMedia, error) {
+	results, err := r.queries.ListExpiredVerificationMedia(ctx, sqlc.ListExpiredVerificationMediaParams{
+		Before:    before,
+		BatchSize: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired media: %v", err)
+	}
+	return toMediaList(results), nil
+}
+
+func toMediaList(results []sqlc.VerificationMedia) []*models.VerificationMedia {
+	media := make([]*models.VerificationMedia, len(results))
+	for i, result := range results {
+		media[i] = toMedia(result)
+	}
+	return media
+}
+
+func toMedia(m sqlc.VerificationMedia) *models.VerificationMedia {
+	return &models.VerificationMedia{
+		ID:           m.ID,
+		SessionID:    m.SessionID,
+		UserID:       m.UserID,
+		Kind:         m.Kind,
+		StorageKey:   m.StorageKey,
+		ContentType:  m.ContentType,
+		SizeBytes:    m.SizeBytes,
+		SHA256:       m.Sha256,
+		StoredSHA256: m.StoredSha256,
+		CreatedAt:    m.CreatedAt,
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// SetSessionExpiry records when the identity document behind a session
+// expires and resets the reminder.
+func (r *VerificationRepository) SetSessionExpiry(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	err := r.queries.SetVerificationSessionExpiry(ctx, sqlc.SetVerificationSessionExpiryParams{
+		SessionID: sessionID,
+		ExpiresAt: expiresAt,
+		TenantID:  tenant.ID(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set verification session expiry: %v", err)
+	}
+	return nil
+}
+
+// SetSessionNextReview schedules the next periodic re-screening of a session.
+func (r *VerificationRepository) SetSessionNextReview(ctx context.Context, sessionID string, nextReviewAt time.Time) error {
+	err := r.queries.SetVerificationSessionNextReview(ctx, sqlc.SetVerificationSessionNextReviewParams{
+		SessionID:    sessionID,
+		NextReviewAt: nextReviewAt,
+		TenantID:     tenant.ID(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set verification session next review: %v", err)
+	}
+	return nil
+}
+
+// ListSessionsDueForReminder returns approved sessions expiring before
+// expiresBefore whose user was not reminded yet.
+func (r *VerificationRepository) ListSessionsDueForReminder(ctx context.Context, expiresBefore time.Time, batchSize int) ([]*models.VerificationSession, error) {
+	results, err := r.queries.ListSessionsDueForReminder(ctx, sqlc.ListSessionsDueForReminderParams{
+		ExpiresBefore: expiresBefore,
+		BatchSize:     int32(batchSize),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions due for reminder: %v", err)
+	}
+	return r.toDomainModels(ctx, results)
+}
+
+// MarkSessionReminded records that the expiry reminder was sent.
+func (r *VerificationRepository) MarkSessionReminded(ctx context.Context, id uuid.UUID) error {
+	err := r.queries.MarkVerificationSessionReminded(ctx, sqlc.MarkVerificationSessionRemindedParams{
+		ID:       id,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark verification session reminded: %v", err)
+	}
+	return nil
+}
+
+// ListExpiredApprovedSessions returns approved sessions past their expiry.
+func (r *VerificationRepository) ListExpiredApprovedSessions(ctx context.Context, batchSize int) ([]*models.VerificationSession, error) {
+	results, err := r.queries.ListExpiredApprovedSessions(ctx, int32(batchSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired sessions: %v", err)
+	}
+	return r.toDomainModels(ctx, results)
+}
+
+// ListSessionsDueForReview returns approved sessions whose periodic
+// re-screening is due.
+func (r *VerificationRepository) ListSessionsDueForReview(ctx context.Context, batchSize int) ([]*models.VerificationSession, error) {
+	results, err := r.queries.ListSessionsDueForReview(ctx, int32(batchSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions due for review: %v", err)
+	}
+	return r.toDomainModels(ctx, results)
+}
+
+// StartSessionReview clears the due review of a session and makes it expire
+// no later than deadline.
+func (r *VerificationRepository) StartSessionReview(ctx context.Context, id uuid.UUID, deadline time.Time) error {
+	err := r.queries.StartVerificationSessionReview(ctx, sqlc.StartVerificationSessionReviewParams{
+		ID:        id,
+		ExpiresAt: deadline,
+		TenantID:  tenant.ID(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start verification session review: %v", err)
+	}
+	return nil
+}
+
+func (r *VerificationRepository) toDomainModels(ctx context.Context, results []sqlc.VerificationSession) ([]*models.VerificationSession, error) {
+	sessions := make([]*models.VerificationSession, len(results))
+	for i, result := range results {
+		session, err := r.toDomainModel(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+		sessions[i] = session
+	}
+	return sessions, nil
+}
@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// encryptedPII holds the stored form of a session's PII columns.
+type encryptedPII struct {
+	UserEmail       string
+	UserFirstName   pgtype.Text
+	UserLastName    pgtype.Text
+	UserEmailIndex  pgtype.Text
+	UserCPF         pgtype.Text
+	UserCPFIndex    pgtype.Text
+	VerificationURL pgtype.Text
+}
+
+// encryptPII encrypts the PII columns of a session. The verification URL
+// counts as PII: fixed links carry the user's email and name.
+func (r *VerificationRepository) encryptPII(ctx context.Context, session *models.VerificationSession) (*encryptedPII, error) {
+	var (
+		out encryptedPII
+		err error
+	)
+
+	if out.UserEmail, err = r.crypt.EncryptString(ctx, session.UserEmail); err != nil {
+		return nil, fmt.Errorf("failed to encrypt email: %v", err)
+	}
+	if session.UserFirstName != "" {
+		enc, err := r.crypt.EncryptString(ctx, session.UserFirstName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt first name: %v", err)
+		}
+		out.UserFirstName = pgtype.Text{String: enc, Valid: true}
+	}
+	if session.UserLastName != "" {
+		enc, err := r.crypt.EncryptString(ctx, session.UserLastName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt last name: %v", err)
+		}
+		out.UserLastName = pgtype.Text{String: enc, Valid: true}
+	}
+	if session.UserEmail != "" {
+		index, err := r.crypt.BlindIndex(ctx, session.UserEmail)
+		if err != nil {
+			return nil, err
+		}
+		out.UserEmailIndex = pgtype.Text{String: index, Valid: true}
+	}
+	if session.UserCPF != "" {
+		enc, err := r.crypt.EncryptString(ctx, session.UserCPF)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt cpf: %v", err)
+		}
+		out.UserCPF = pgtype.Text{String: enc, Valid: true}
+
+		index, err := r.crypt.CPFIndex(ctx, session.UserCPF)
+		if err != nil {
+			return nil, err
+		}
+		out.UserCPFIndex = pgtype.Text{String: index, Valid: true}
+	}
+	if session.VerificationURL != "" {
+		enc, err := r.crypt.EncryptString(ctx, session.VerificationURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt verification url: %v", err)
+		}
+		out.VerificationURL = pgtype.Text{String: enc, Valid: true}
+	}
+
+	return &out, nil
+}
+
+// ListVerificationSessionsByEmail finds sessions through the email blind
+// index, so it works while emails are encrypted.
+func (r *VerificationRepository) ListVerificationSessionsByEmail(ctx context.Context, email string) ([]*models.VerificationSession, error) {
+	index, err := r.crypt.BlindIndex(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := r.queries.ListVerificationSessionsByEmailIndex(ctx, sqlc.ListVerificationSessionsByEmailIndexParams{
+		UserEmailIndex: pgtype.Text{String: index, Valid: true},
+		TenantID:       tenant.ID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list verification sessions by email: %v", err)
+	}
+
+	sessions := make([]*models.VerificationSession, len(results))
+	for i, result := range results {
+		sessions[i], err = r.toDomainModel(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+// ReencryptReport counts the rows rewritten by a re-encryption pass.
+type ReencryptReport struct {
+	TenantsScanned         int `json:"tenants_scanned"`
+	TenantsRewritten       int `json:"tenants_rewritten"`
+	SessionsScanned        int `json:"sessions_scanned"`
+	SessionsRewritten      int `json:"sessions_rewritten"`
+	WebhookEventsScanned   int `json:"webhook_events_scanned"`
+	WebhookEventsRewritten int `json:"webhook_events_rewritten"`
+}
+
+// Reencrypt walks every tenant, session and webhook event in batches and
+// rewrites the ones stored in plaintext or under a key other than the current
+// one. Blind indexes are recomputed along the way.
+func (r *VerificationRepository) Reencrypt(ctx context.Context, batchSize int) (*ReencryptReport, error) {
+	report := &ReencryptReport{}
+
+	tenants, err := r.queries.ListTenants(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to list tenants: %v", err)
+	}
+
+	for _, t := range tenants {
+		report.TenantsScanned++
+		rewritten, err := r.reencryptTenant(ctx, t)
+		if err != nil {
+			return report, err
+		}
+		if rewritten {
+			report.TenantsRewritten++
+		}
+
+		tenantCtx := tenant.NewContext(ctx, &models.Tenant{ID: t.ID})
+		if err := r.reencryptSessions(tenantCtx, batchSize, report); err != nil {
+			return report, err
+		}
+		if err := r.reencryptWebhookEvents(tenantCtx, batchSize, report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+func (r *VerificationRepository) reencryptSessions(ctx context.Context, batchSize int, report *ReencryptReport) error {
+	after := uuid.Nil
+	for {
+		results, err := r.queries.ListVerificationSessionsAfterID(ctx, sqlc.ListVerificationSessionsAfterIDParams{
+			ID:       after,
+			Limit:    int32(batchSize),
+			TenantID: tenant.ID(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list verification sessions: %v", err)
+		}
+
+		for _, result := range results {
+			report.SessionsScanned++
+			after = result.ID
+
+			rewritten, err := r.reencryptSession(ctx, result)
+			if err != nil {
+				return err
+			}
+			if rewritten {
+				report.SessionsRewritten++
+			}
+		}
+		if len(results) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (r *VerificationRepository) reencryptWebhookEvents(ctx context.Context, batchSize int, report *ReencryptReport) error {
+	after := uuid.Nil
+	for {
+		results, err := r.queries.ListWebhookEventsAfterID(ctx, sqlc.ListWebhookEventsAfterIDParams{
+			ID:       after,
+			Limit:    int32(batchSize),
+			TenantID: tenant.ID(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list webhook events: %v", err)
+		}
+
+		for _, result := range results {
+			report.WebhookEventsScanned++
+			after = result.ID
+
+			if !r.crypt.PayloadNeedsRotation(result.Payload) {
+				continue
+			}
+			payload, err := r.crypt.DecryptPayload(ctx, result.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt webhook event %s: %v", result.ID, err)
+			}
+			if err := r.UpdateWebhookEventPayload(ctx, result.ID, payload); err != nil {
+				return err
+			}
+			report.WebhookEventsRewritten++
+		}
+		if len(results) < batchSize {
+			return nil
+		}
+	}
+}
+
+// reencryptTenant rewrites the Didit credentials of a tenant under the
+// current key.
+func (r *VerificationRepository) reencryptTenant(ctx context.Context, dbTenant sqlc.Tenant) (bool, error) {
+	if !r.crypt.NeedsRotation(dbTenant.DiditApiKey.String) && !r.crypt.NeedsRotation(dbTenant.DiditWebhookSecret.String) {
+		return false, nil
+	}
+
+	t, err := toTenant(ctx, r.crypt, dbTenant)
+	if err != nil {
+		return false, err
+	}
+	if _, err := updateTenant(ctx, r.queries, r.crypt, t); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *VerificationRepository) reencryptSession(ctx context.Context, dbSession sqlc.VerificationSession) (bool, error) {
+	session, err := r.toDomainModel(ctx, dbSession)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt verification session %s: %v", dbSession.ID, err)
+	}
+
+	index := pgtype.Text{}
+	if session.UserEmail != "" {
+		index.String, err = r.crypt.BlindIndex(ctx, session.UserEmail)
+		if err != nil {
+			return false, err
+		}
+		index.Valid = true
+	}
+	cpfIndex := pgtype.Text{}
+	if session.UserCPF != "" {
+		cpfIndex.String, err = r.crypt.CPFIndex(ctx, session.UserCPF)
+		if err != nil {
+			return false, err
+		}
+		cpfIndex.Valid = true
+	}
+
+	stale := r.crypt.NeedsRotation(dbSession.UserEmail) ||
+		r.crypt.NeedsRotation(dbSession.UserFirstName.String) ||
+		r.crypt.NeedsRotation(dbSession.UserLastName.String) ||
+		r.crypt.NeedsRotation(dbSession.UserCpf.String) ||
+		r.crypt.NeedsRotation(dbSession.VerificationUrl.String) ||
+		index != dbSession.UserEmailIndex ||
+		cpfIndex != dbSession.UserCpfIndex
+	if !stale {
+		return false, nil
+	}
+
+	pii, err := r.encryptPII(ctx, session)
+	if err != nil {
+		return false, err
+	}
+
+	// Carry the CPF claim over to the recomputed index.
+	if cpfIndex.Valid && cpfIndex != dbSession.UserCpfIndex {
+		if _, err := r.ClaimCPF(ctx, session.UserCPF, session.UserID); err != nil {
+			return false, err
+		}
+	}
+
+	err = r.queries.UpdateVerificationSessionPII(ctx, sqlc.UpdateVerificationSessionPIIParams{
+		ID:              dbSession.ID,
+		UserEmail:       pii.UserEmail,
+		UserFirstName:   pii.UserFirstName,
+		UserLastName:    pii.UserLastName,
+		UserEmailIndex:  pii.UserEmailIndex,
+		UserCpf:         pii.UserCPF,
+		UserCpfIndex:    pii.UserCPFIndex,
+		VerificationUrl: pii.VerificationURL,
+		TenantID:        dbSession.TenantID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to update verification session pii: %v", err)
+	}
+	return true, nil
+}
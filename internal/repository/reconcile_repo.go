@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+)
+
+// ListSessionsToReconcile returns pending sessions opened at a provider
+// before createdBefore and not checked since checkedBefore, oldest first.
+func (r *VerificationRepository) ListSessionsToReconcile(ctx context.Context, createdBefore time.Time, checkedBefore time.Time, limit int) ([]*models.VerificationSession, error) {
+	results, err := r.queries.ListSessionsToReconcile(ctx, sqlc.ListSessionsToReconcileParams{
+		CreatedBefore: createdBefore,
+		CheckedBefore: checkedBefore,
+		MaxRows:       int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions to reconcile: %v", err)
+	}
+	return r.toDomainModels(ctx, results)
+}
+
+func (r *VerificationRepository) MarkSessionReconciled(ctx context.Context, sessionID string) error {
+	if err := r.queries.MarkVerificationSessionReconciled(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to mark session reconciled: %v", err)
+	}
+	return nil
+}
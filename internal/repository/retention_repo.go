@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+type RetentionRepository struct {
+	queries *sqlc.Queries
+}
+
+func NewRetentionRepository(queries *sqlc.Queries) *RetentionRepository {
+	return &RetentionRepository{
+		queries: queries,
+	}
+}
+
+// CreateLegalHold places a user under legal hold, replacing the reason of an
+// existing hold.
+func (r *RetentionRepository) CreateLegalHold(ctx context.Context, userID string, reason string, createdBy string) (*models.LegalHold, error) {
+	result, err := r.queries.CreateLegalHold(ctx, sqlc.CreateLegalHoldParams{
+		UserID:    userID,
+		Reason:    reason,
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create legal hold: %v", err)
+	}
+	return legalHoldToDomainModel(result), nil
+}
+
+func (r *RetentionRepository) GetLegalHold(ctx context.Context, userID string) (*models.LegalHold, error) {
+	result, err := r.queries.GetLegalHold(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get legal hold: %v", err)
+	}
+	return legalHoldToDomainModel(result), nil
+}
+
+func (r *RetentionRepository) ListLegalHolds(ctx context.Context) ([]*models.LegalHold, error) {
+	results, err := r.queries.ListLegalHolds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %v", err)
+	}
+
+	holds := make([]*models.LegalHold, len(results))
+	for i, result := range results {
+		holds[i] = legalHoldToDomainModel(result)
+	}
+	return holds, nil
+}
+
+// DeleteLegalHold releases a hold. It reports false when the user had none.
+// Archives that kept events of users under hold go through the payload
+// retention again on the next partition maintenance.
+func (r *RetentionRepository) DeleteLegalHold(ctx context.Context, userID string) (bool, error) {
+	n, err := r.queries.DeleteLegalHold(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete legal hold: %v", err)
+	}
+	if n > 0 {
+		if err := r.queries.ResetWebhookEventArchiveRedaction(ctx); err != nil {
+			return true, fmt.Errorf("failed to reset archive redaction: %v", err)
+		}
+	}
+	return n > 0, nil
+}
+
+func (r *RetentionRepository) CountExpiredWebhookPayloads(ctx context.Context, before time.Time) (int, error) {
+	n, err := r.queries.CountExpiredWebhookPayloads(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expired webhook payloads: %v", err)
+	}
+	return int(n), nil
+}
+
+func (r *RetentionRepository) RedactExpiredWebhookPayloads(ctx context.Context, before time.Time, batchSize int) (int, error) {
+	n, err := r.queries.RedactExpiredWebhookPayloads(ctx, sqlc.RedactExpiredWebhookPayloadsParams{
+		Before:    before,
+		BatchSize: int32(batchSize),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to redact expired webhook payloads: %v", err)
+	}
+	return int(n), nil
+}
+
+func (r *RetentionRepository) CountAbandonedSessions(ctx context.Context, before time.Time) (int, error) {
+	n, err := r.queries.CountAbandonedSessions(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count abandoned sessions: %v", err)
+	}
+	return int(n), nil
+}
+
+func (r *RetentionRepository) DeleteAbandonedSessions(ctx context.Context, before time.Time, batchSize int) (int, error) {
+	n, err := r.queries.DeleteAbandonedSessions(ctx, sqlc.DeleteAbandonedSessionsParams{
+		Before:    before,
+		BatchSize: int32(batchSize),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete abandoned sessions: %v", err)
+	}
+	return int(n), nil
+}
+
+func (r *RetentionRepository) CountExpiredDecisions(ctx context.Context, before time.Time) (int, error) {
+	n, err := r.queries.CountExpiredDecisions(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expired decisions: %v", err)
+	}
+	return int(n), nil
+}
+
+// DeleteExpiredDecisions deletes a batch of expired decided sessions together
+// with their webhook events. The returned count is the number of sessions.
+func (r *RetentionRepository) DeleteExpiredDecisions(ctx context.Context, before time.Time, batchSize int) (int, error) {
+	n, err := r.queries.DeleteExpiredDecisions(ctx, sqlc.DeleteExpiredDecisionsParams{
+		Before:    before,
+		BatchSize: int32(batchSize),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired decisions: %v", err)
+	}
+	return int(n), nil
+}
+
+func (r *RetentionRepository) CreatePurgeRun(ctx context.Context, startedAt time.Time, finishedAt time.Time, report any) (*models.PurgeRun, error) {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode purge report: %v", err)
+	}
+
+	result, err := r.queries.CreatePurgeRun(ctx, sqlc.CreatePurgeRunParams{
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Report:     payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create purge run: %v", err)
+	}
+	return purgeRunToDomainModel(result), nil
+}
+
+func (r *RetentionRepository) ListPurgeRuns(ctx context.Context, limit int) ([]*models.PurgeRun, error) {
+	results, err := r.queries.ListPurgeRuns(ctx, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purge runs: %v", err)
+	}
+
+	runs := make([]*models.PurgeRun, len(results))
+	for i, result := range results {
+		runs[i] = purgeRunToDomainModel(result)
+	}
+	return runs, nil
+}
+
+func legalHoldToDomainModel(dbHold sqlc.LegalHold) *models.LegalHold {
+	return &models.LegalHold{
+		UserID:    dbHold.UserID,
+		Reason:    dbHold.Reason,
+		CreatedBy: dbHold.CreatedBy,
+		CreatedAt: dbHold.CreatedAt,
+	}
+}
+
+func purgeRunToDomainModel(dbRun sqlc.PurgeRun) *models.PurgeRun {
+	return &models.PurgeRun{
+		ID:         dbRun.ID,
+		StartedAt:  dbRun.StartedAt,
+		FinishedAt: dbRun.FinishedAt,
+		Report:     json.RawMessage(dbRun.Report),
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/risk"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type RiskRepository struct {
+	queries *sqlc.Queries
+}
+
+func NewRiskRepository(queries *sqlc.Queries) *RiskRepository {
+	return &RiskRepository{
+		queries: queries,
+	}
+}
+
+// CreateScore stores the risk evaluation of a session with the status Didit
+// decided and the one the session ended with.
+func (r *RiskRepository) CreateScore(ctx context.Context, session *models.VerificationSession, result *risk.Result, diditStatus string, finalStatus string, enforced bool) (*models.RiskScore, error) {
+	signals, err := json.Marshal(result.Signals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode risk signals: %v", err)
+	}
+	reasons, err := json.Marshal(result.Reasons)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode risk reasons: %v", err)
+	}
+
+	created, err := r.queries.CreateRiskScore(ctx, sqlc.CreateRiskScoreParams{
+		SessionID:    session.SessionID,
+		UserID:       session.UserID,
+		Score:        result.Score,
+		Decision:     result.Decision,
+		DiditStatus:  diditStatus,
+		FinalStatus:  finalStatus,
+		Enforced:     enforced,
+		RulesVersion: pgtype.Text{String: result.RulesVersion, Valid: result.RulesVersion != ""},
+		Signals:      signals,
+		Reasons:      reasons,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create risk score: %v", err)
+	}
+	return riskScoreToDomainModel(created), nil
+}
+
+func (r *RiskRepository) ListBySessionID(ctx context.Context, sessionID string) ([]*models.RiskScore, error) {
+	results, err := r.queries.ListRiskScoresBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list risk scores: %v", err)
+	}
+	return riskScoresToDomainModels(results), nil
+}
+
+// ListSince returns up to limit scores created since a time, oldest first.
+func (r *RiskRepository) ListSince(ctx context.Context, since time.Time, limit int) ([]*models.RiskScore, error) {
+	results, err := r.queries.ListRiskScoresSince(ctx, sqlc.ListRiskScoresSinceParams{
+		Since:   since,
+		MaxRows: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list risk scores: %v", err)
+	}
+	return riskScoresToDomainModels(results), nil
+}
+
+func riskScoresToDomainModels(results []sqlc.RiskScore) []*models.RiskScore {
+	scores := make([]*models.RiskScore, len(results))
+	for i, result := range results {
+		scores[i] = riskScoreToDomainModel(result)
+	}
+	return scores
+}
+
+func riskScoreToDomainModel(dbScore sqlc.RiskScore) *models.RiskScore {
+	return &models.RiskScore{
+		ID:           dbScore.ID,
+		SessionID:    dbScore.SessionID,
+		UserID:       dbScore.UserID,
+		Score:        dbScore.Score,
+		Decision:     dbScore.Decision,
+		DiditStatus:  dbScore.DiditStatus,
+		FinalStatus:  dbScore.FinalStatus,
+		Enforced:     dbScore.Enforced,
+		RulesVersion: dbScore.RulesVersion.String,
+		Signals:      dbScore.Signals,
+		Reasons:      dbScore.Reasons,
+		CreatedAt:    dbScore.CreatedAt,
+	}
+}
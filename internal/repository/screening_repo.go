@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/sanctions"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ScreeningRepository struct {
+	queries *sqlc.Queries
+}
+
+func NewScreeningRepository(queries *sqlc.Queries) *ScreeningRepository {
+	return &ScreeningRepository{
+		queries: queries,
+	}
+}
+
+// CreateMatches stores the sanctions hits of a session.
+func (r *ScreeningRepository) CreateMatches(ctx context.Context, sessionID string, userID string, matches []sanctions.Match) error {
+	for _, match := range matches {
+		details, err := json.Marshal(map[string]any{
+			"dates_of_birth": match.DatesOfBirth,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode screening match details: %v", err)
+		}
+
+		dobMatch := pgtype.Bool{}
+		if match.DOBMatch != nil {
+			dobMatch = pgtype.Bool{Bool: *match.DOBMatch, Valid: true}
+		}
+
+		_, err = r.queries.CreateScreeningMatch(ctx, sqlc.CreateScreeningMatchParams{
+			SessionID:   sessionID,
+			UserID:      userID,
+			List:        match.List,
+			EntryID:     match.EntryID,
+			EntryName:   match.EntryName,
+			MatchedName: match.MatchedName,
+			Score:       match.Score,
+			DobMatch:    dobMatch,
+			Details:     details,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create screening match: %v", err)
+		}
+	}
+	return nil
+}
+
+func (r *ScreeningRepository) ListBySessionID(ctx context.Context, sessionID string) ([]*models.ScreeningMatch, error) {
+	results, err := r.queries.ListScreeningMatchesBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list screening matches: %v", err)
+	}
+
+	matches := make([]*models.ScreeningMatch, len(results))
+	for i, result := range results {
+		matches[i] = screeningMatchToDomainModel(result)
+	}
+	return matches, nil
+}
+
+func (r *ScreeningRepository) ListByUserID(ctx context.Context, userID string) ([]*models.ScreeningMatch, error) {
+	results, err := r.queries.ListScreeningMatchesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list screening matches: %v", err)
+	}
+
+	matches := make([]*models.ScreeningMatch, len(results))
+	for i, result := range results {
+		matches[i] = screeningMatchToDomainModel(result)
+	}
+	return matches, nil
+}
+
+func screeningMatchToDomainModel(dbMatch sqlc.ScreeningMatch) *models.ScreeningMatch {
+	match := &models.ScreeningMatch{
+		ID:          dbMatch.ID,
+		SessionID:   dbMatch.SessionID,
+		UserID:      dbMatch.UserID,
+		List:        dbMatch.List,
+		EntryID:     dbMatch.EntryID,
+		EntryName:   dbMatch.EntryName,
+		MatchedName: dbMatch.MatchedName,
+		Score:       dbMatch.Score,
+		Details:     dbMatch.Details,
+		CreatedAt:   dbMatch.CreatedAt,
+	}
+	if dbMatch.DobMatch.Valid {
+		match.DOBMatch = &dbMatch.DobMatch.Bool
+	}
+	return match
+}
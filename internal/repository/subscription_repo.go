@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type SubscriptionRepository struct {
+	queries *sqlc.Queries
+}
+
+func NewSubscriptionRepository(queries *sqlc.Queries) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		queries: queries,
+	}
+}
+
+func (r *SubscriptionRepository) CreateSubscription(ctx context.Context, params *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	eventTypes, err := json.Marshal(params.EventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event types: %v", err)
+	}
+
+	result, err := r.queries.CreateWebhookSubscription(ctx, sqlc.CreateWebhookSubscriptionParams{
+		Url:         params.URL,
+		Secret:      params.Secret,
+		EventTypes:  eventTypes,
+		Description: pgtype.Text{String: params.Description, Valid: params.Description != ""},
+		TenantID:    tenant.ID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %v", err)
+	}
+
+	return r.toDomainModel(result), nil
+}
+
+func (r *SubscriptionRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	result, err := r.queries.GetWebhookSubscription(ctx, sqlc.GetWebhookSubscriptionParams{
+		ID:       id,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %v", err)
+	}
+	return r.toDomainModel(result), nil
+}
+
+// GetDeliverySubscription returns the subscription a delivery goes to,
+// whatever its tenant, for the delivery worker.
+func (r *SubscriptionRepository) GetDeliverySubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	result, err := r.queries.GetDeliveryWebhookSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %v", err)
+	}
+	return r.toDomainModel(result), nil
+}
+
+func (r *SubscriptionRepository) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	results, err := r.queries.ListWebhookSubscriptions(ctx, tenant.ID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %v", err)
+	}
+	return r.toDomainModels(results), nil
+}
+
+// ListActiveSubscriptions returns the active subscriptions of a tenant.
+func (r *SubscriptionRepository) ListActiveSubscriptions(ctx context.Context, tenantID uuid.UUID) ([]*models.WebhookSubscription, error) {
+	results, err := r.queries.ListActiveWebhookSubscriptions(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active webhook subscriptions: %v", err)
+	}
+	return r.toDomainModels(results), nil
+}
+
+// DeleteSubscription removes a subscription and its delivery log. It reports
+// whether the subscription existed.
+func (r *SubscriptionRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) (bool, error) {
+	rows, err := r.queries.DeleteWebhookSubscription(ctx, sqlc.DeleteWebhookSubscriptionParams{
+		ID:       id,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook subscription: %v", err)
+	}
+	return rows > 0, nil
+}
+
+func (r *SubscriptionRepository) EnableSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	result, err := r.queries.EnableWebhookSubscription(ctx, sqlc.EnableWebhookSubscriptionParams{
+		ID:       id,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to enable webhook subscription: %v", err)
+	}
+	return r.toDomainModel(result), nil
+}
+
+func (r *SubscriptionRepository) RecordSuccess(ctx context.Context, id uuid.UUID) error {
+	if err := r.queries.ResetWebhookSubscriptionFailures(ctx, id); err != nil {
+		return fmt.Errorf("failed to reset webhook subscription failures: %v", err)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed delivery attempt against the subscription and
+// disables it once disableAfter consecutive failures are reached.
+func (r *SubscriptionRepository) RecordFailure(ctx context.Context, id uuid.UUID, disableAfter int) (*models.WebhookSubscription, error) {
+	result, err := r.queries.RecordWebhookSubscriptionFailure(ctx, sqlc.RecordWebhookSubscriptionFailureParams{
+		DisableAfter: int32(disableAfter),
+		ID:           id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook subscription failure: %v", err)
+	}
+	return r.toDomainModel(result), nil
+}
+
+func (r *SubscriptionRepository) CreateDelivery(ctx context.Context, subscriptionID uuid.UUID, eventID uuid.UUID, eventType string, payload []byte) (*models.WebhookDelivery, error) {
+	result, err := r.queries.CreateWebhookDelivery(ctx, sqlc.CreateWebhookDeliveryParams{
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %v", err)
+	}
+	return r.deliveryToDomainModel(result), nil
+}
+
+func (r *SubscriptionRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	result, err := r.queries.GetWebhookDelivery(ctx, sqlc.GetWebhookDeliveryParams{
+		ID:       id,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %v", err)
+	}
+	return r.deliveryToDomainModel(result), nil
+}
+
+func (r *SubscriptionRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	results, err := r.queries.ListWebhookDeliveriesBySubscription(ctx, sqlc.ListWebhookDeliveriesBySubscriptionParams{
+		SubscriptionID: subscriptionID,
+		Limit:          int32(limit),
+		TenantID:       tenant.ID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %v", err)
+	}
+
+	deliveries := make([]*models.WebhookDelivery, len(results))
+	for i, result := range results {
+		deliveries[i] = r.deliveryToDomainModel(result)
+	}
+
+	return deliveries, nil
+}
+
+// ListDeliveriesByUserID returns the outbound deliveries about the user's
+// sessions sent to the tenant's subscriptions, oldest first.
+func (r *SubscriptionRepository) ListDeliveriesByUserID(ctx context.Context, userID string) ([]*models.WebhookDelivery, error) {
+	results, err := r.queries.ListWebhookDeliveriesByUserID(ctx, sqlc.ListWebhookDeliveriesByUserIDParams{
+		UserID:   userID,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries for user: %v", err)
+	}
+
+	deliveries := make([]*models.WebhookDelivery, len(results))
+	for i, result := range results {
+		deliveries[i] = r.deliveryToDomainModel(result)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimNextDelivery locks the next due delivery of an active subscription. It
+// returns nil when nothing is due.
+func (r *SubscriptionRepository) ClaimNextDelivery(ctx context.Context, staleBefore time.Time) (*models.WebhookDelivery, error) {
+	result, err := r.queries.ClaimNextWebhookDelivery(ctx, staleBefore)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim webhook delivery: %v", err)
+	}
+	return r.deliveryToDomainModel(result), nil
+}
+
+func (r *SubscriptionRepository) CompleteDelivery(ctx context.Context, id uuid.UUID, statusCode int) error {
+	err := r.queries.CompleteWebhookDelivery(ctx, sqlc.CompleteWebhookDeliveryParams{
+		ID:             id,
+		LastStatusCode: nullInt32(statusCode),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete webhook delivery: %v", err)
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) RetryDelivery(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time, statusCode int, lastError string) error {
+	err := r.queries.RetryWebhookDelivery(ctx, sqlc.RetryWebhookDeliveryParams{
+		ID:             id,
+		NextAttemptAt:  nextAttemptAt,
+		LastStatusCode: nullInt32(statusCode),
+		LastError:      pgtype.Text{String: lastError, Valid: lastError != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule webhook delivery retry: %v", err)
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) FailDelivery(ctx context.Context, id uuid.UUID, statusCode int, lastError string) error {
+	err := r.queries.FailWebhookDelivery(ctx, sqlc.FailWebhookDeliveryParams{
+		ID:             id,
+		LastStatusCode: nullInt32(statusCode),
+		LastError:      pgtype.Text{String: lastError, Valid: lastError != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fail webhook delivery: %v", err)
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) RecordAttempt(ctx context.Context, deliveryID uuid.UUID, attempt *models.WebhookDeliveryAttempt) error {
+	err := r.queries.CreateWebhookDeliveryAttempt(ctx, sqlc.CreateWebhookDeliveryAttemptParams{
+		DeliveryID: deliveryID,
+		Attempt:    int32(attempt.Attempt),
+		StatusCode: nullInt32(attempt.StatusCode),
+		Error:      pgtype.Text{String: attempt.Error, Valid: attempt.Error != ""},
+		DurationMs: int32(attempt.DurationMs),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %v", err)
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]*models.WebhookDeliveryAttempt, error) {
+	results, err := r.queries.ListWebhookDeliveryAttempts(ctx, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery attempts: %v", err)
+	}
+
+	attempts := make([]*models.WebhookDeliveryAttempt, len(results))
+	for i, result := range results {
+		attempts[i] = &models.WebhookDeliveryAttempt{
+			Attempt:    int(result.Attempt),
+			StatusCode: int(result.StatusCode.Int32),
+			Error:      result.Error.String,
+			DurationMs: int(result.DurationMs),
+			CreatedAt:  result.CreatedAt,
+		}
+	}
+
+	return attempts, nil
+}
+
+func (r *SubscriptionRepository) toDomainModels(results []sqlc.WebhookSubscription) []*models.WebhookSubscription {
+	subscriptions := make([]*models.WebhookSubscription, len(results))
+	for i, result := range results {
+		subscriptions[i] = r.toDomainModel(result)
+	}
+	return subscriptions
+}
+
+func (r *SubscriptionRepository) toDomainModel(dbSub sqlc.WebhookSubscription) *models.WebhookSubscription {
+	sub := &models.WebhookSubscription{
+		ID:                  dbSub.ID,
+		TenantID:            dbSub.TenantID,
+		URL:                 dbSub.Url,
+		Secret:              dbSub.Secret,
+		EventTypes:          []string{},
+		Active:              dbSub.Active,
+		ConsecutiveFailures: int(dbSub.ConsecutiveFailures),
+		CreatedAt:           dbSub.CreatedAt,
+		UpdatedAt:           dbSub.UpdatedAt,
+	}
+
+	// A malformed filter is treated as "all events" rather than hiding the row.
+	_ = json.Unmarshal(dbSub.EventTypes, &sub.EventTypes)
+
+	if dbSub.Description.Valid {
+		sub.Description = dbSub.Description.String
+	}
+	if dbSub.DisabledAt.Valid {
+		sub.DisabledAt = &dbSub.DisabledAt.Time
+	}
+
+	return sub
+}
+
+func (r *SubscriptionRepository) deliveryToDomainModel(dbDelivery sqlc.WebhookDelivery) *models.WebhookDelivery {
+	delivery := &models.WebhookDelivery{
+		ID:             dbDelivery.ID,
+		SubscriptionID: dbDelivery.SubscriptionID,
+		EventID:        dbDelivery.EventID,
+		EventType:      dbDelivery.EventType,
+		Payload:        dbDelivery.Payload,
+		Status:         dbDelivery.Status,
+		Attempts:       int(dbDelivery.Attempts),
+		NextAttemptAt:  dbDelivery.NextAttemptAt,
+		CreatedAt:      dbDelivery.CreatedAt,
+	}
+
+	if dbDelivery.LastStatusCode.Valid {
+		delivery.LastStatusCode = int(dbDelivery.LastStatusCode.Int32)
+	}
+	if dbDelivery.LastError.Valid {
+		delivery.LastError = dbDelivery.LastError.String
+	}
+	if dbDelivery.DeliveredAt.Valid {
+		delivery.DeliveredAt = &dbDelivery.DeliveredAt.Time
+	}
+
+	return delivery
+}
+
+func nullInt32(n int) pgtype.Int4 {
+	return pgtype.Int4{Int32: int32(n), Valid: n != 0}
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/fieldcrypt"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TenantRepository stores tenants. Their Didit API keys and webhook secrets
+// are encrypted like session PII.
+type TenantRepository struct {
+	queries *sqlc.Queries
+	crypt   *fieldcrypt.Encryptor
+}
+
+func NewTenantRepository(queries *sqlc.Queries, crypt *fieldcrypt.Encryptor) *TenantRepository {
+	return &TenantRepository{
+		queries: queries,
+		crypt:   crypt,
+	}
+}
+
+// CreateTenant stores a tenant authenticated by the API key hashing to
+// apiKeyHash.
+func (r *TenantRepository) CreateTenant(ctx context.Context, t *models.Tenant, apiKeyHash string) (*models.Tenant, error) {
+	apiKey, err := encryptSecret(ctx, r.crypt, t.DiditAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	webhookSecret, err := encryptSecret(ctx, r.crypt, t.DiditWebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.queries.CreateTenant(ctx, sqlc.CreateTenantParams{
+		Slug:               t.Slug,
+		Name:               t.Name,
+		ApiKeyHash:         pgtype.Text{String: apiKeyHash, Valid: apiKeyHash != ""},
+		DiditApiKey:        apiKey,
+		DiditWorkflowID:    nullString(t.DiditWorkflowID),
+		DiditWorkflowUrl:   nullString(t.DiditWorkflowURL),
+		DiditWebhookSecret: webhookSecret,
+		SenderName:         nullString(t.SenderName),
+		SenderEmail:        nullString(t.SenderEmail),
+		LogoUrl:            nullString(t.LogoURL),
+		PrimaryColor:       nullString(t.PrimaryColor),
+		SupportUrl:         nullString(t.SupportURL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %v", err)
+	}
+
+	return toTenant(ctx, r.crypt, result)
+}
+
+func (r *TenantRepository) GetTenant(ctx context.Context, id uuid.UUID) (*models.Tenant, error) {
+	result, err := r.queries.GetTenant(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tenant: %v", err)
+	}
+	return toTenant(ctx, r.crypt, result)
+}
+
+func (r *TenantRepository) GetTenantBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	result, err := r.queries.GetTenantBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tenant by slug: %v", err)
+	}
+	return toTenant(ctx, r.crypt, result)
+}
+
+// GetTenantByAPIKey returns the tenant an API key belongs to, or nil when no
+// tenant has it.
+func (r *TenantRepository) GetTenantByAPIKey(ctx context.Context, apiKey string) (*models.Tenant, error) {
+	result, err := r.queries.GetTenantByAPIKeyHash(ctx, pgtype.Text{String: tenant.HashAPIKey(apiKey), Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tenant by api key: %v", err)
+	}
+	return toTenant(ctx, r.crypt, result)
+}
+
+func (r *TenantRepository) ListTenants(ctx context.Context) ([]*models.Tenant, error) {
+	results, err := r.queries.ListTenants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %v", err)
+	}
+
+	tenants := make([]*models.Tenant, len(results))
+	for i, result := range results {
+		tenants[i], err = toTenant(ctx, r.crypt, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tenants, nil
+}
+
+// UpdateTenant saves every setting of t except its slug and API key.
+func (r *TenantRepository) UpdateTenant(ctx context.Context, t *models.Tenant) (*models.Tenant, error) {
+	return updateTenant(ctx, r.queries, r.crypt, t)
+}
+
+// SetAPIKeyHash replaces the API key of a tenant. The previous key stops
+// working at once.
+func (r *TenantRepository) SetAPIKeyHash(ctx context.Context, id uuid.UUID, apiKeyHash string) (*models.Tenant, error) {
+	result, err := r.queries.SetTenantAPIKeyHash(ctx, sqlc.SetTenantAPIKeyHashParams{
+		ID:         id,
+		ApiKeyHash: pgtype.Text{String: apiKeyHash, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set tenant api key: %v", err)
+	}
+	return toTenant(ctx, r.crypt, result)
+}
+
+// Context returns a copy of ctx acting for the tenant with the given id, for
+// jobs that pick up sessions and events of every tenant.
+func (r *TenantRepository) Context(ctx context.Context, id uuid.UUID) (context.Context, error) {
+	if id == tenant.DefaultID {
+		return tenant.NewContext(ctx, nil), nil
+	}
+
+	t, err := r.GetTenant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, fmt.Errorf("tenant %s not found", id)
+	}
+	return tenant.NewContext(ctx, t), nil
+}
+
+func updateTenant(ctx context.Context, queries *sqlc.Queries, crypt *fieldcrypt.Encryptor, t *models.Tenant) (*models.Tenant, error) {
+	apiKey, err := encryptSecret(ctx, crypt, t.DiditAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	webhookSecret, err := encryptSecret(ctx, crypt, t.DiditWebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := queries.UpdateTenant(ctx, sqlc.UpdateTenantParams{
+		ID:                 t.ID,
+		Name:               t.Name,
+		Active:             t.Active,
+		DiditApiKey:        apiKey,
+		DiditWorkflowID:    nullString(t.DiditWorkflowID),
+		DiditWorkflowUrl:   nullString(t.DiditWorkflowURL),
+		DiditWebhookSecret: webhookSecret,
+		SenderName:         nullString(t.SenderName),
+		SenderEmail:        nullString(t.SenderEmail),
+		LogoUrl:            nullString(t.LogoURL),
+		PrimaryColor:       nullString(t.PrimaryColor),
+		SupportUrl:         nullString(t.SupportURL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tenant: %v", err)
+	}
+	return toTenant(ctx, crypt, result)
+}
+
+func toTenant(ctx context.Context, crypt *fieldcrypt.Encryptor, dbTenant sqlc.Tenant) (*models.Tenant, error) {
+	t := &models.Tenant{
+		ID:               dbTenant.ID,
+		Slug:             dbTenant.Slug,
+		Name:             dbTenant.Name,
+		Active:           dbTenant.Active,
+		DiditWorkflowID:  dbTenant.DiditWorkflowID.String,
+		DiditWorkflowURL: dbTenant.DiditWorkflowUrl.String,
+		SenderName:       dbTenant.SenderName.String,
+		SenderEmail:      dbTenant.SenderEmail.String,
+		LogoURL:          dbTenant.LogoUrl.String,
+		PrimaryColor:     dbTenant.PrimaryColor.String,
+		SupportURL:       dbTenant.SupportUrl.String,
+		CreatedAt:        dbTenant.CreatedAt,
+		UpdatedAt:        dbTenant.UpdatedAt,
+	}
+
+	var err error
+	if dbTenant.DiditApiKey.Valid {
+		t.DiditAPIKey, err = crypt.DecryptString(ctx, dbTenant.DiditApiKey.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt tenant %s: %v", dbTenant.Slug, err)
+		}
+	}
+	if dbTenant.DiditWebhookSecret.Valid {
+		t.DiditWebhookSecret, err = crypt.DecryptString(ctx, dbTenant.DiditWebhookSecret.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt tenant %s: %v", dbTenant.Slug, err)
+		}
+	}
+	return t, nil
+}
+
+func encryptSecret(ctx context.Context, crypt *fieldcrypt.Encryptor, secret string) (pgtype.Text, error) {
+	if secret == "" {
+		return pgtype.Text{}, nil
+	}
+	enc, err := crypt.EncryptString(ctx, secret)
+	if err != nil {
+		return pgtype.Text{}, fmt.Errorf("failed to encrypt tenant secret: %v", err)
+	}
+	return pgtype.Text{String: enc, Valid: true}, nil
+}
+
+func nullString(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
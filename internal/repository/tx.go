@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/jackc/pgx/v5"
+)
+
+// TxBeginner starts transactions; *pgxpool.Pool is one.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// unitOfWork collects what a transaction defers until it commits.
+type unitOfWork struct {
+	afterCommit []func()
+}
+
+// WithTx runs fn as a single transaction. Every write made through the
+// repository fn receives, and through repositories built on its Queries,
+// commits together, or is rolled back when fn returns an error. Called on a
+// repository already inside a transaction, fn joins that transaction.
+func (r *VerificationRepository) WithTx(ctx context.Context, fn func(repo *VerificationRepository) error) error {
+	if r.uow != nil {
+		return fn(r)
+	}
+	if r.db == nil {
+		return fmt.Errorf("failed to begin transaction: repository has no database")
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	// Rolling back a committed transaction does nothing.
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	uow := &unitOfWork{}
+	repo := &VerificationRepository{
+		db:      r.db,
+		queries: r.queries.WithTx(tx),
+		crypt:   r.crypt,
+		uow:     uow,
+	}
+	if err := fn(repo); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	for _, hook := range uow.afterCommit {
+		hook()
+	}
+	return nil
+}
+
+// AfterCommit defers fn until the transaction of the repository commits, and
+// drops it on rollback. It is meant for side effects that cannot be undone,
+// such as emails. Outside a transaction fn runs right away.
+func (r *VerificationRepository) AfterCommit(fn func()) {
+	if r.uow == nil {
+		fn()
+		return
+	}
+	r.uow.afterCommit = append(r.uow.afterCommit, fn)
+}
+
+// Queries returns the queries of the repository, bound to its transaction
+// inside WithTx, so other repositories can take part in it.
+func (r *VerificationRepository) Queries() *sqlc.Queries {
+	return r.queries
+}
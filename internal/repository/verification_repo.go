@@ -2,36 +2,71 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/fieldcrypt"
 	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// ErrNotFound is returned by the lookups of a single session when the
+// tenant has no such session.
+var ErrNotFound = errors.New("not found")
+
+// VerificationRepository stores sessions and webhook events. PII columns and
+// the identity fields of webhook payloads are encrypted on the way in and
+// decrypted on the way out, so callers only ever see plaintext. Every query is
+// scoped to the tenant of the context.
 type VerificationRepository struct {
+	db      TxBeginner
 	queries *sqlc.Queries
+	crypt   *fieldcrypt.Encryptor
+	// uow is set on the copy of the repository bound to a transaction.
+	uow *unitOfWork
 }
 
-func NewVerificationRepository(queries *sqlc.Queries) *VerificationRepository {
+func NewVerificationRepository(db TxBeginner, queries *sqlc.Queries, crypt *fieldcrypt.Encryptor) *VerificationRepository {
 	return &VerificationRepository{
+		db:      db,
 		queries: queries,
+		crypt:   crypt,
 	}
 }
 
 func (r *VerificationRepository) CreateSession(ctx context.Context, params *models.VerificationSession) (*models.VerificationSession, error) {
+	provider := params.Provider
+	if provider == "" {
+		provider = models.DefaultProvider
+	}
+
+	pii, err := r.encryptPII(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
 	dbParams := sqlc.CreateVerificationSessionParams{
-		UserID:         params.UserID,
-		SessionID:      params.SessionID,
-		DiditSessionID: sql.NullString{String: params.DiditSessionID, Valid: params.DiditSessionID != ""},
-		UserEmail:      params.UserEmail,
-		UserFirstName:  sql.NullString{String: params.UserFirstName, Valid: params.UserFirstName != ""},
-		UserLastName:   sql.NullString{String: params.UserLastName, Valid: params.UserLastName != ""},
-		Status:         params.Status,
+		UserID:          params.UserID,
+		SessionID:       params.SessionID,
+		DiditSessionID:  pgtype.Text{String: params.DiditSessionID, Valid: params.DiditSessionID != ""},
+		UserEmail:       pii.UserEmail,
+		UserFirstName:   pii.UserFirstName,
+		UserLastName:    pii.UserLastName,
+		Status:          params.Status,
+		UserEmailIndex:  pii.UserEmailIndex,
+		Level:           params.Level,
+		BaseSessionID:   pgtype.Text{String: params.BaseSessionID, Valid: params.BaseSessionID != ""},
+		UserCpf:         pii.UserCPF,
+		UserCpfIndex:    pii.UserCPFIndex,
+		Provider:        provider,
+		VerificationUrl: pii.VerificationURL,
+		TenantID:        tenant.ID(ctx),
 	}
 
 	result, err := r.queries.CreateVerificationSession(ctx, dbParams)
@@ -39,117 +74,292 @@ func (r *VerificationRepository) CreateSession(ctx context.Context, params *mode
 		return nil, fmt.Errorf("failed to create verification session: %v", err)
 	}
 
-	return r.toDomainModel(result), nil
+	return r.toDomainModel(ctx, result)
 }
 
 func (r *VerificationRepository) GetSessionByID(ctx context.Context, id uuid.UUID) (*models.VerificationSession, error) {
-	result, err := r.queries.GetVerificationSessionByID(ctx, id)
+	result, err := r.queries.GetVerificationSessionByID(ctx, sqlc.GetVerificationSessionByIDParams{
+		ID:       id,
+		TenantID: tenant.ID(ctx),
+	})
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get verification session: %v", err)
 	}
 
-	return r.toDomainModel(result), nil
+	return r.toDomainModel(ctx, result)
 }
 
 func (r *VerificationRepository) GetSessionBySessionID(ctx context.Context, sessionID string) (*models.VerificationSession, error) {
-	result, err := r.queries.GetVerificationSessionBySessionID(ctx, sessionID)
+	result, err := r.queries.GetVerificationSessionBySessionID(ctx, sqlc.GetVerificationSessionBySessionIDParams{
+		SessionID: sessionID,
+		TenantID:  tenant.ID(ctx),
+	})
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get verification session: %v", err)
 	}
-	return r.toDomainModel(result), nil
+	return r.toDomainModel(ctx, result)
 }
 
 func (r *VerificationRepository) GetSessionByDiditSessionID(ctx context.Context, diditSessionID string) (*models.VerificationSession, error) {
-	nullDiditSessionID := sql.NullString{String: diditSessionID, Valid: diditSessionID != ""}
-
-	result, err := r.queries.GetVerificationSessionByDiditSessionID(ctx, nullDiditSessionID)
+	result, err := r.queries.GetVerificationSessionByDiditSessionID(ctx, sqlc.GetVerificationSessionByDiditSessionIDParams{
+		DiditSessionID: pgtype.Text{String: diditSessionID, Valid: diditSessionID != ""},
+		TenantID:       tenant.ID(ctx),
+	})
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get verification session by didit session id: %v", err)
 	}
 
-	return r.toDomainModel(result), nil
+	return r.toDomainModel(ctx, result)
+}
+
+// LockSession reads a session and locks its row until the transaction ends,
+// so concurrent transitions of the same session run one after the other. It
+// only holds the lock inside WithTx.
+func (r *VerificationRepository) LockSession(ctx context.Context, sessionID string) (*models.VerificationSession, error) {
+	result, err := r.queries.LockVerificationSession(ctx, sqlc.LockVerificationSessionParams{
+		SessionID: sessionID,
+		TenantID:  tenant.ID(ctx),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock verification session: %v", err)
+	}
+	return r.toDomainModel(ctx, result)
 }
 
 func (r *VerificationRepository) UpdateStatus(ctx context.Context, sessionID string, status string) (*models.VerificationSession, error) {
 	result, err := r.queries.UpdateVerificationSessionStatus(ctx, sqlc.UpdateVerificationSessionStatusParams{
 		SessionID: sessionID,
 		Status:    status,
+		TenantID:  tenant.ID(ctx),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update verification session status: %v", err)
 	}
-	return r.toDomainModel(result), nil
+	return r.toDomainModel(ctx, result)
 }
 
 func (r *VerificationRepository) UpdateDiditSessionID(ctx context.Context, sessionID string, diditSessionID string) (*models.VerificationSession, error) {
 	result, err := r.queries.UpdateDiditSessionID(ctx, sqlc.UpdateDiditSessionIDParams{
 		SessionID:      sessionID,
-		DiditSessionID: sql.NullString{String: diditSessionID, Valid: diditSessionID != ""},
+		DiditSessionID: pgtype.Text{String: diditSessionID, Valid: diditSessionID != ""},
+		TenantID:       tenant.ID(ctx),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update didit session id: %v", err)
 	}
-	return r.toDomainModel(result), nil
+	return r.toDomainModel(ctx, result)
 }
 
 func (r *VerificationRepository) ListVerificationSessionsByUserID(ctx context.Context, userID string) ([]*models.VerificationSession, error) {
-	results, err := r.queries.ListVerificationSessionsByUserID(ctx, userID)
+	results, err := r.queries.ListVerificationSessionsByUserID(ctx, sqlc.ListVerificationSessionsByUserIDParams{
+		UserID:   userID,
+		TenantID: tenant.ID(ctx),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list verification sessions: %v", err)
 	}
 
 	sessions := make([]*models.VerificationSession, len(results))
 	for i, result := range results {
-		sessions[i] = r.toDomainModel(result)
+		sessions[i], err = r.toDomainModel(ctx, result)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return sessions, nil
 }
 
+// GetLatestPendingSession returns the user's most recent pending session of
+// the given level created after createdAfter, or nil when there is none.
+func (r *VerificationRepository) GetLatestPendingSession(ctx context.Context, userID, level string, createdAfter time.Time) (*models.VerificationSession, error) {
+	result, err := r.queries.GetLatestPendingSessionByUserID(ctx, sqlc.GetLatestPendingSessionByUserIDParams{
+		TenantID:     tenant.ID(ctx),
+		UserID:       userID,
+		Level:        level,
+		CreatedAfter: createdAfter,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get pending verification session: %v", err)
+	}
+
+	return r.toDomainModel(ctx, result)
+}
+
+// GetLatestTerminalSession returns the user's most recently decided session,
+// or nil when no verification has finished yet.
+func (r *VerificationRepository) GetLatestTerminalSession(ctx context.Context, userID string) (*models.VerificationSession, error) {
+	result, err := r.queries.GetLatestTerminalSessionByUserID(ctx, sqlc.GetLatestTerminalSessionByUserIDParams{
+		UserID:   userID,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest decided verification session: %v", err)
+	}
+
+	return r.toDomainModel(ctx, result)
+}
+
+// ListApprovedSessions returns the user's approved sessions, most recently
+// decided first.
+func (r *VerificationRepository) ListApprovedSessions(ctx context.Context, userID string) ([]*models.VerificationSession, error) {
+	results, err := r.queries.ListApprovedSessionsByUserID(ctx, sqlc.ListApprovedSessionsByUserIDParams{
+		UserID:   userID,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approved verification sessions: %v", err)
+	}
+
+	sessions := make([]*models.VerificationSession, len(results))
+	for i, result := range results {
+		sessions[i], err = r.toDomainModel(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+// LockUserSessions serializes the session starts of a user in the tenant. Like
+// LockSession, it only holds the lock inside WithTx.
+func (r *VerificationRepository) LockUserSessions(ctx context.Context, userID string) error {
+	err := r.queries.LockUserVerificationSessions(ctx, sqlc.LockUserVerificationSessionsParams{
+		TenantID: tenant.ID(ctx).String(),
+		UserID:   userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to lock user verification sessions: %v", err)
+	}
+	return nil
+}
+
+// CountSessionsSince counts the sessions a user started after since.
+func (r *VerificationRepository) CountSessionsSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	count, err := r.queries.CountVerificationSessionsByUserIDSince(ctx, sqlc.CountVerificationSessionsByUserIDSinceParams{
+		TenantID:     tenant.ID(ctx),
+		UserID:       userID,
+		CreatedAfter: since,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count verification sessions: %v", err)
+	}
+
+	return int(count), nil
+}
+
 func (r *VerificationRepository) ListVerificationSessionsByStatus(ctx context.Context, status string) ([]*models.VerificationSession, error) {
-	results, err := r.queries.ListVerificationSessionsByStatus(ctx, status)
+	results, err := r.queries.ListVerificationSessionsByStatus(ctx, sqlc.ListVerificationSessionsByStatusParams{
+		Status:   status,
+		TenantID: tenant.ID(ctx),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list verification sessions by status: %v", err)
 	}
 
 	sessions := make([]*models.VerificationSession, len(results))
 	for i, result := range results {
-		sessions[i] = r.toDomainModel(result)
+		sessions[i], err = r.toDomainModel(ctx, result)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return sessions, nil
 }
 
-func (r *VerificationRepository) CreateWebhookEvent(ctx context.Context, eventType string, sessionID string, payload []byte) error {
-	_, err := r.queries.CreateWebhookEvent(ctx, sqlc.CreateWebhookEventParams{
+func (r *VerificationRepository) CreateWebhookEvent(ctx context.Context, provider string, eventType string, sessionID string, payload []byte) (uuid.UUID, error) {
+	payload, err := r.crypt.EncryptPayload(ctx, payload)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to encrypt webhook payload: %v", err)
+	}
+
+	result, err := r.queries.CreateWebhookEvent(ctx, sqlc.CreateWebhookEventParams{
 		EventType: eventType,
 		SessionID: sessionID,
 		Payload:   payload,
+		Provider:  provider,
+		TenantID:  tenant.ID(ctx),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create webhook event: %v", err)
+		return uuid.Nil, fmt.Errorf("failed to create webhook event: %v", err)
+	}
+	return result.ID, nil
+}
+
+// WebhookReplayFilter selects stored webhook events for reprocessing.
+// Zero-valued fields are not applied.
+type WebhookReplayFilter struct {
+	SessionID       string
+	Since           time.Time
+	OnlyUnprocessed bool
+}
+
+// ListWebhookEventsForReplay returns the stored webhook events matching the
+// filter, oldest first, so they can be re-run in their original order.
+func (r *VerificationRepository) ListWebhookEventsForReplay(ctx context.Context, filter WebhookReplayFilter) ([]*models.WebhookEventDB, error) {
+	results, err := r.queries.ListWebhookEventsForReplay(ctx, sqlc.ListWebhookEventsForReplayParams{
+		TenantID:        tenant.ID(ctx),
+		SessionID:       pgtype.Text{String: filter.SessionID, Valid: filter.SessionID != ""},
+		Since:           pgtype.Timestamptz{Time: filter.Since, Valid: !filter.Since.IsZero()},
+		OnlyUnprocessed: filter.OnlyUnprocessed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook events for replay: %v", err)
+	}
+
+	events := make([]*models.WebhookEventDB, len(results))
+	for i, result := range results {
+		events[i], err = r.webhookEventDBToDomainModel(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+func (r *VerificationRepository) MarkWebhookEventProcessed(ctx context.Context, id uuid.UUID) error {
+	if err := r.queries.MarkWebhookEventProcessed(ctx, sqlc.MarkWebhookEventProcessedParams{
+		ID:       id,
+		TenantID: tenant.ID(ctx),
+	}); err != nil {
+		return fmt.Errorf("failed to mark webhook event as processed: %v", err)
 	}
 	return nil
 }
 
 func (r *VerificationRepository) GetWebhookEventsBySessionID(ctx context.Context, sessionID string) ([]*models.WebhookEvent, error) {
-	results, err := r.queries.GetWebhookEventsBySessionID(ctx, sessionID)
+	results, err := r.queries.GetWebhookEventsBySessionID(ctx, sqlc.GetWebhookEventsBySessionIDParams{
+		SessionID: sessionID,
+		TenantID:  tenant.ID(ctx),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get webhook events: %v", err)
 	}
 
 	events := make([]*models.WebhookEvent, len(results))
 	for i, result := range results {
-		event, err := r.webhookEventToDomainModel(result)
+		event, err := r.webhookEventToDomainModel(ctx, result)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert webhook event: %v", err)
 		}
@@ -159,13 +369,101 @@ func (r *VerificationRepository) GetWebhookEventsBySessionID(ctx context.Context
 	return events, nil
 }
 
-func (r *VerificationRepository) toDomainModel(dbSession sqlc.VerificationSession) *models.VerificationSession {
+// ListWebhookEventsByUserID returns every stored webhook event that belongs to
+// the user, oldest first.
+func (r *VerificationRepository) ListWebhookEventsByUserID(ctx context.Context, userID string) ([]*models.WebhookEventDB, error) {
+	results, err := r.queries.ListWebhookEventsByUserID(ctx, sqlc.ListWebhookEventsByUserIDParams{
+		TenantID: tenant.ID(ctx),
+		UserID:   userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook events for user: %v", err)
+	}
+
+	events := make([]*models.WebhookEventDB, len(results))
+	for i, result := range results {
+		events[i], err = r.webhookEventDBToDomainModel(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+func (r *VerificationRepository) UpdateWebhookEventPayload(ctx context.Context, id uuid.UUID, payload []byte) error {
+	payload, err := r.crypt.EncryptPayload(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook payload: %v", err)
+	}
+
+	err = r.queries.UpdateWebhookEventPayload(ctx, sqlc.UpdateWebhookEventPayloadParams{
+		ID:       id,
+		Payload:  payload,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update webhook event payload: %v", err)
+	}
+	return nil
+}
+
+// QueueArchiveErasure has the partition manager scrub the user's events from
+// the webhook archives, matched like ListWebhookEventsByUserID: by user id
+// in the payload or by the user's Didit sessions.
+func (r *VerificationRepository) QueueArchiveErasure(ctx context.Context, userID string, diditSessionIDs []string) error {
+	tenantID := uuid.NullUUID{UUID: tenant.ID(ctx), Valid: true}
+	return r.WithTx(ctx, func(repo *VerificationRepository) error {
+		err := repo.queries.CreateWebhookArchivePurge(ctx, sqlc.CreateWebhookArchivePurgeParams{
+			Action:   models.ArchivePurgeErase,
+			TenantID: tenantID,
+			UserID:   pgtype.Text{String: userID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to queue archive erasure: %v", err)
+		}
+		for _, sessionID := range diditSessionIDs {
+			err := repo.queries.CreateWebhookArchivePurge(ctx, sqlc.CreateWebhookArchivePurgeParams{
+				Action:    models.ArchivePurgeErase,
+				TenantID:  tenantID,
+				SessionID: pgtype.Text{String: sessionID, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to queue archive erasure: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// EraseUserSessions pseudonymizes the PII columns of the user's sessions and
+// returns how many sessions were erased.
+func (r *VerificationRepository) EraseUserSessions(ctx context.Context, userID string) (int, error) {
+	n, err := r.queries.EraseVerificationSessionsByUserID(ctx, sqlc.EraseVerificationSessionsByUserIDParams{
+		UserID:   userID,
+		TenantID: tenant.ID(ctx),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to erase verification sessions: %v", err)
+	}
+	return int(n), nil
+}
+
+func (r *VerificationRepository) toDomainModel(ctx context.Context, dbSession sqlc.VerificationSession) (*models.VerificationSession, error) {
+	email, err := r.crypt.DecryptString(ctx, dbSession.UserEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt verification session: %v", err)
+	}
+
 	session := &models.VerificationSession{
 		ID:        dbSession.ID,
+		TenantID:  dbSession.TenantID,
 		UserID:    dbSession.UserID,
 		SessionID: dbSession.SessionID,
+		Provider:  dbSession.Provider,
 		Status:    dbSession.Status,
-		UserEmail: dbSession.UserEmail,
+		Level:     dbSession.Level,
+		UserEmail: email,
 		CreatedAt: dbSession.CreatedAt,
 		UpdatedAt: dbSession.UpdatedAt,
 	}
@@ -173,22 +471,67 @@ func (r *VerificationRepository) toDomainModel(dbSession sqlc.VerificationSessio
 	if dbSession.DiditSessionID.Valid {
 		session.DiditSessionID = dbSession.DiditSessionID.String
 	}
+	if dbSession.BaseSessionID.Valid {
+		session.BaseSessionID = dbSession.BaseSessionID.String
+	}
 	if dbSession.UserFirstName.Valid {
-		session.UserFirstName = dbSession.UserFirstName.String
+		session.UserFirstName, err = r.crypt.DecryptString(ctx, dbSession.UserFirstName.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt verification session: %v", err)
+		}
 	}
 	if dbSession.UserLastName.Valid {
-		session.UserLastName = dbSession.UserLastName.String
+		session.UserLastName, err = r.crypt.DecryptString(ctx, dbSession.UserLastName.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt verification session: %v", err)
+		}
+	}
+	if dbSession.UserCpf.Valid {
+		session.UserCPF, err = r.crypt.DecryptString(ctx, dbSession.UserCpf.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt verification session: %v", err)
+		}
+	}
+	if dbSession.CpfMatch.Valid {
+		session.CPFMatch = &dbSession.CpfMatch.Bool
+	}
+	if dbSession.VerificationUrl.Valid {
+		session.VerificationURL, err = r.crypt.DecryptString(ctx, dbSession.VerificationUrl.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt verification session: %v", err)
+		}
 	}
 	if dbSession.CompletedAt.Valid {
 		session.CompletedAt = &dbSession.CompletedAt.Time
 	}
+	if dbSession.ExpiresAt.Valid {
+		session.ExpiresAt = &dbSession.ExpiresAt.Time
+	}
+	if dbSession.NextReviewAt.Valid {
+		session.NextReviewAt = &dbSession.NextReviewAt.Time
+	}
+	if dbSession.RemindedAt.Valid {
+		session.RemindedAt = &dbSession.RemindedAt.Time
+	}
+	if dbSession.ReconciledAt.Valid {
+		session.ReconciledAt = &dbSession.ReconciledAt.Time
+	}
+	if dbSession.ErasedAt.Valid {
+		session.ErasedAt = &dbSession.ErasedAt.Time
+	}
 
-	return session
+	return session, nil
 }
 
-func (r *VerificationRepository) webhookEventToDomainModel(dbWebhook sqlc.WebhookEvent) (*models.WebhookEvent, error) {
+func (r *VerificationRepository) webhookEventToDomainModel(ctx context.Context, dbWebhook sqlc.WebhookEvent) (*models.WebhookEvent, error) {
 	var webhookEvent models.WebhookEvent
 
+	payload, err := r.crypt.DecryptPayload(ctx, dbWebhook.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook payload: %v", err)
+	}
+	dbWebhook.Payload = payload
+
 	// Tentar fazer parse do payload como WebhookEvent primeiro
 	if err := json.Unmarshal(dbWebhook.Payload, &webhookEvent); err != nil {
 		// Se não der, tentar fazer parse manualmente
@@ -266,3 +609,36 @@ func (r *VerificationRepository) webhookEventToDomainModel(dbWebhook sqlc.Webhoo
 
 	return &webhookEvent, nil
 }
+
+func (r *VerificationRepository) webhookEventDBToDomainModel(ctx context.Context, dbWebhook sqlc.WebhookEvent) (*models.WebhookEventDB, error) {
+	payload, err := r.crypt.DecryptPayload(ctx, dbWebhook.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook payload: %v", err)
+	}
+
+	event := &models.WebhookEventDB{
+		ID:            dbWebhook.ID,
+		TenantID:      dbWebhook.TenantID,
+		Provider:      dbWebhook.Provider,
+		EventType:     dbWebhook.EventType,
+		SessionID:     dbWebhook.SessionID,
+		Payload:       payload,
+		Processed:     dbWebhook.Processed.Valid && dbWebhook.Processed.Bool,
+		Status:        dbWebhook.Status,
+		Attempts:      int(dbWebhook.Attempts),
+		NextAttemptAt: dbWebhook.NextAttemptAt,
+		CreatedAt:     dbWebhook.CreatedAt,
+	}
+
+	if dbWebhook.LastError.Valid {
+		event.LastError = dbWebhook.LastError.String
+	}
+	if dbWebhook.ProcessedAt.Valid {
+		event.ProcessedAt = &dbWebhook.ProcessedAt.Time
+	}
+	if dbWebhook.RedactedAt.Valid {
+		event.RedactedAt = &dbWebhook.RedactedAt.Time
+	}
+
+	return event, nil
+}
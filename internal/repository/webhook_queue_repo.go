@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/database/sqlc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ClaimNextWebhookEvent locks the oldest runnable event whose session has no
+// earlier unfinished event and marks it as processing. Events stuck in
+// processing since before staleBefore are considered abandoned and reclaimed.
+// It returns nil when the queue is empty.
+func (r *VerificationRepository) ClaimNextWebhookEvent(ctx context.Context, staleBefore time.Time) (*models.WebhookEventDB, error) {
+	result, err := r.queries.ClaimNextWebhookEvent(ctx, staleBefore)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim webhook event: %v", err)
+	}
+	return r.webhookEventDBToDomainModel(ctx, result)
+}
+
+func (r *VerificationRepository) CompleteWebhookEvent(ctx context.Context, id uuid.UUID) error {
+	if err := r.queries.CompleteWebhookEvent(ctx, id); err != nil {
+		return fmt.Errorf("failed to complete webhook event: %v", err)
+	}
+	return nil
+}
+
+func (r *VerificationRepository) RetryWebhookEvent(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time, lastError string) error {
+	err := r.queries.RetryWebhookEvent(ctx, sqlc.RetryWebhookEventParams{
+		ID:            id,
+		NextAttemptAt: nextAttemptAt,
+		LastError:     pgtype.Text{String: lastError, Valid: lastError != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule webhook event retry: %v", err)
+	}
+	return nil
+}
+
+func (r *VerificationRepository) DeadLetterWebhookEvent(ctx context.Context, id uuid.UUID, lastError string) error {
+	err := r.queries.DeadLetterWebhookEvent(ctx, sqlc.DeadLetterWebhookEventParams{
+		ID:        id,
+		LastError: pgtype.Text{String: lastError, Valid: lastError != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter webhook event: %v", err)
+	}
+	return nil
+}
+
+func (r *VerificationRepository) ListDeadWebhookEvents(ctx context.Context, limit int) ([]*models.WebhookEventDB, error) {
+	results, err := r.queries.ListDeadWebhookEvents(ctx, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead webhook events: %v", err)
+	}
+
+	events := make([]*models.WebhookEventDB, len(results))
+	for i, result := range results {
+		events[i], err = r.webhookEventDBToDomainModel(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+// RequeueDeadWebhookEvent moves a dead-lettered event back to the queue with
+// a fresh attempt budget. It returns nil when the event is not dead.
+func (r *VerificationRepository) RequeueDeadWebhookEvent(ctx context.Context, id uuid.UUID) (*models.WebhookEventDB, error) {
+	result, err := r.queries.RequeueDeadWebhookEvent(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to requeue webhook event: %v", err)
+	}
+	return r.webhookEventDBToDomainModel(ctx, result)
+}
+
+func (r *VerificationRepository) CountWebhookEventsByStatus(ctx context.Context) (map[string]int64, error) {
+	results, err := r.queries.CountWebhookEventsByStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count webhook events: %v", err)
+	}
+
+	counts := make(map[string]int64, len(results))
+	for _, result := range results {
+		counts[result.Status] = result.Total
+	}
+
+	return counts, nil
+}
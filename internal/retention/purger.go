@@ -0,0 +1,195 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/media"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+)
+
+// Purge actions.
+const (
+	ActionRedact = "redact"
+	ActionDelete = "delete"
+)
+
+// Policy is the retention rule of one data class.
+type Policy struct {
+	Name      string
+	Action    string
+	Retention time.Duration
+
+	count func(ctx context.Context, before time.Time) (int, error)
+	purge func(ctx context.Context, before time.Time, batchSize int) (int, error)
+}
+
+// PolicyReport is what a policy matched and changed during a run.
+type PolicyReport struct {
+	Policy   string    `json:"policy"`
+	Action   string    `json:"action"`
+	Cutoff   time.Time `json:"cutoff"`
+	Matched  int       `json:"matched"`
+	Affected int       `json:"affected"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Report is the outcome of a purge run.
+type Report struct {
+	DryRun     bool            `json:"dry_run"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Policies   []*PolicyReport `json:"policies"`
+}
+
+// Purger applies the retention policies in batches. Data of users under legal
+// hold is never touched.
+type Purger struct {
+	repo      *repository.RetentionRepository
+	policies  []*Policy
+	batchSize int
+	interval  time.Duration
+}
+
+func NewPurger(cfg *config.Config, repo *repository.RetentionRepository, mediaService *media.Service) *Purger {
+	batchSize := cfg.RetentionBatchSize
+	if batchSize < 1 {
+		batchSize = 500
+	}
+
+	p := &Purger{
+		repo:      repo,
+		batchSize: batchSize,
+		interval:  cfg.RetentionInterval,
+	}
+
+	p.policies = []*Policy{
+		{
+			Name:      "webhook_payloads",
+			Action:    ActionRedact,
+			Retention: days(cfg.RetentionWebhookPayloadDays),
+			count:     repo.CountExpiredWebhookPayloads,
+			purge:     repo.RedactExpiredWebhookPayloads,
+		},
+		{
+			Name:      "abandoned_sessions",
+			Action:    ActionDelete,
+			Retention: days(cfg.RetentionAbandonedSessionDays),
+			count:     repo.CountAbandonedSessions,
+			purge:     repo.DeleteAbandonedSessions,
+		},
+		{
+			// Runs before decisions, which keeps sessions until their media
+			// files are deleted.
+			Name:      "media",
+			Action:    ActionDelete,
+			Retention: days(cfg.RetentionDecisionDays),
+			count:     mediaService.CountExpired,
+			purge:     mediaService.PurgeExpired,
+		},
+		{
+			Name:      "decisions",
+			Action:    ActionDelete,
+			Retention: days(cfg.RetentionDecisionDays),
+			count:     repo.CountExpiredDecisions,
+			purge:     repo.DeleteExpiredDecisions,
+		},
+	}
+
+	return p
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// Policies returns the enabled policies.
+func (p *Purger) Policies() []*Policy {
+	var enabled []*Policy
+	for _, policy := range p.policies {
+		if policy.Retention > 0 {
+			enabled = append(enabled, policy)
+		}
+	}
+	return enabled
+}
+
+// Purge runs every enabled policy. With dryRun set it only counts the rows
+// each policy would affect. Reports of real runs are stored in purge_runs.
+func (p *Purger) Purge(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{
+		DryRun:    dryRun,
+		StartedAt: time.Now().UTC(),
+		Policies:  []*PolicyReport{},
+	}
+
+	for _, policy := range p.Policies() {
+		pr := &PolicyReport{
+			Policy: policy.Name,
+			Action: policy.Action,
+			Cutoff: report.StartedAt.Add(-policy.Retention),
+		}
+		report.Policies = append(report.Policies, pr)
+
+		matched, err := policy.count(ctx, pr.Cutoff)
+		if err != nil {
+			pr.Error = err.Error()
+			continue
+		}
+		pr.Matched = matched
+		if dryRun || matched == 0 {
+			continue
+		}
+
+		for ctx.Err() == nil {
+			n, err := policy.purge(ctx, pr.Cutoff, p.batchSize)
+			if err != nil {
+				pr.Error = err.Error()
+				break
+			}
+			pr.Affected += n
+			if n < p.batchSize {
+				break
+			}
+		}
+	}
+	report.FinishedAt = time.Now().UTC()
+
+	if !dryRun {
+		if _, err := p.repo.CreatePurgeRun(ctx, report.StartedAt, report.FinishedAt, report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// Run purges on every interval until ctx is cancelled. A zero interval
+// disables the scheduled purge.
+func (p *Purger) Run(ctx context.Context) {
+	if p.interval <= 0 {
+		return
+	}
+	log.Printf("Starting retention purge every %s", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := p.Purge(ctx, false)
+			if err != nil {
+				log.Printf("Retention purge failed: %v", err)
+				continue
+			}
+			for _, pr := range report.Policies {
+				log.Printf("Retention purge %s: matched=%d %s=%d error=%q", pr.Policy, pr.Matched, pr.Action, pr.Affected, pr.Error)
+			}
+		}
+	}
+}
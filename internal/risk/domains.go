@@ -0,0 +1,63 @@
+package risk
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//go:embed disposable_domains.txt
+var builtinDisposableDomains string
+
+// DomainList is a set of email domains.
+type DomainList map[string]bool
+
+// LoadDomains returns the built-in disposable domains plus the ones of an
+// optional file in the same one-per-line format.
+func LoadDomains(path string) (DomainList, error) {
+	domains := make(DomainList)
+	domains.add(builtinDisposableDomains)
+	if path == "" {
+		return domains, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read disposable domains: %v", err)
+	}
+	domains.add(string(data))
+	return domains, nil
+}
+
+func (d DomainList) add(list string) {
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			d[line] = true
+		}
+	}
+}
+
+// Contains reports whether the domain of an email, or a parent domain, is
+// on the list.
+func (d DomainList) Contains(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for domain != "" {
+		if d[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
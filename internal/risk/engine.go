@@ -0,0 +1,155 @@
+package risk
+
+import (
+	"sync"
+
+	"github.com/FelipePn10/crispaybackend/config"
+)
+
+// Signal names a rule can test.
+const (
+	SignalLivenessScore       = "liveness_score"
+	SignalFaceMatchScore      = "face_match_score"
+	SignalAMLHits             = "aml_hits"
+	SignalIPCountryMismatch   = "ip_country_mismatch"
+	SignalDisposableEmail     = "disposable_email"
+	SignalFailedAttempts      = "failed_attempts"
+	SignalSessionsLast24h     = "sessions_last_24h"
+	SignalSanctionsMatches    = "sanctions_matches"
+	SignalDuplicateIdentities = "duplicate_identities"
+	SignalCPFMismatch         = "cpf_mismatch"
+)
+
+// Signals lists every known signal.
+var Signals = []string{
+	SignalLivenessScore,
+	SignalFaceMatchScore,
+	SignalAMLHits,
+	SignalIPCountryMismatch,
+	SignalDisposableEmail,
+	SignalFailedAttempts,
+	SignalSessionsLast24h,
+	SignalSanctionsMatches,
+	SignalDuplicateIdentities,
+	SignalCPFMismatch,
+}
+
+// Reason is a rule that fired, with the signal value that fired it.
+type Reason struct {
+	Rule        string  `json:"rule"`
+	Signal      string  `json:"signal"`
+	Value       float64 `json:"value"`
+	Weight      float64 `json:"weight"`
+	Explanation string  `json:"explanation,omitempty"`
+}
+
+// Result is the score of a decision.
+type Result struct {
+	Score        float64            `json:"score"`
+	Decision     string             `json:"decision"`
+	Reasons      []Reason           `json:"reasons"`
+	Signals      map[string]float64 `json:"signals"`
+	RulesVersion string             `json:"rules_version,omitempty"`
+}
+
+// Engine evaluates the loaded rules. Rules can be reloaded while it runs.
+type Engine struct {
+	path        string
+	domainsPath string
+	enforce     bool
+
+	mu      sync.RWMutex
+	rules   *Rules
+	domains DomainList
+}
+
+func NewEngine(cfg *config.Config) *Engine {
+	return &Engine{
+		path:        cfg.RiskRulesFile,
+		domainsPath: cfg.RiskDisposableDomainsFile,
+		enforce:     cfg.RiskMode != "shadow",
+	}
+}
+
+// Enabled reports whether a rules file is configured.
+func (e *Engine) Enabled() bool {
+	return e.path != ""
+}
+
+// Enforced reports whether decisions change session statuses, as opposed to
+// only being stored.
+func (e *Engine) Enforced() bool {
+	return e.enforce
+}
+
+// Load reads the rules file and the disposable domains. On error the
+// previous ones stay in place.
+func (e *Engine) Load() error {
+	rules, err := LoadRules(e.path)
+	if err != nil {
+		return err
+	}
+	domains, err := LoadDomains(e.domainsPath)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.rules = rules
+	e.domains = domains
+	e.mu.Unlock()
+	return nil
+}
+
+// Disposable reports whether an email belongs to a disposable provider.
+func (e *Engine) Disposable(email string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.domains.Contains(email)
+}
+
+// Rules returns the loaded rules, nil before the first load.
+func (e *Engine) Rules() *Rules {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules
+}
+
+// Evaluate scores the signals. Rules whose signal is missing do not fire. It
+// returns nil when no rules are loaded.
+func (e *Engine) Evaluate(signals map[string]float64) *Result {
+	rules := e.Rules()
+	if rules == nil {
+		return nil
+	}
+
+	result := &Result{
+		Decision:     DecisionApprove,
+		Reasons:      []Reason{},
+		Signals:      signals,
+		RulesVersion: rules.Version,
+	}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		value, ok := signals[rule.Signal]
+		if !ok || !rule.matches(value) {
+			continue
+		}
+		result.Score += rule.Weight
+		result.Reasons = append(result.Reasons, Reason{
+			Rule:        rule.Name,
+			Signal:      rule.Signal,
+			Value:       value,
+			Weight:      rule.Weight,
+			Explanation: rule.Explanation,
+		})
+	}
+
+	switch {
+	case result.Score >= rules.Thresholds.Decline:
+		result.Decision = DecisionDecline
+	case result.Score >= rules.Thresholds.Review:
+		result.Decision = DecisionReview
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+// Package risk scores terminal verification decisions with weighted rules
+// over Didit's signals and our own, read from a YAML rules file.
+package risk
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Decisions a score leads to.
+const (
+	DecisionApprove = "auto_approve"
+	DecisionReview  = "review"
+	DecisionDecline = "decline"
+)
+
+// Comparison operators of a rule.
+var operators = []string{"lt", "lte", "gt", "gte", "eq", "ne"}
+
+// Rules is the parsed rules file.
+type Rules struct {
+	// Version is stored with every score so tuning can tell rule sets apart.
+	Version    string     `yaml:"version" json:"version"`
+	Thresholds Thresholds `yaml:"thresholds" json:"thresholds"`
+	Rules      []Rule     `yaml:"rules" json:"rules"`
+}
+
+// Thresholds are the scores from which a decision is reviewed or declined.
+type Thresholds struct {
+	Review  float64 `yaml:"review" json:"review"`
+	Decline float64 `yaml:"decline" json:"decline"`
+}
+
+// Rule adds its weight to the score when the signal compares true with the
+// value. Booleans are written as true/false and compared as 1/0.
+type Rule struct {
+	Name        string  `yaml:"name" json:"name"`
+	Signal      string  `yaml:"signal" json:"signal"`
+	Op          string  `yaml:"op" json:"op"`
+	Value       any     `yaml:"value" json:"value"`
+	Weight      float64 `yaml:"weight" json:"weight"`
+	Explanation string  `yaml:"explanation" json:"explanation"`
+
+	value float64
+}
+
+// LoadRules reads and validates a rules file.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read risk rules: %v", err)
+	}
+	return ParseRules(data)
+}
+
+// ParseRules parses and validates the YAML of a rules file.
+func ParseRules(data []byte) (*Rules, error) {
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse risk rules: %v", err)
+	}
+
+	if rules.Thresholds.Review <= 0 || rules.Thresholds.Decline < rules.Thresholds.Review {
+		return nil, fmt.Errorf("risk thresholds must satisfy 0 < review <= decline")
+	}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("risk rule %d has no name", i+1)
+		}
+		if !slices.Contains(Signals, rule.Signal) {
+			return nil, fmt.Errorf("risk rule %s: unknown signal %q", rule.Name, rule.Signal)
+		}
+		if !slices.Contains(operators, rule.Op) {
+			return nil, fmt.Errorf("risk rule %s: unknown op %q", rule.Name, rule.Op)
+		}
+		switch v := rule.Value.(type) {
+		case bool:
+			if v {
+				rule.value = 1
+			}
+		case int:
+			rule.value = float64(v)
+		case int64:
+			rule.value = float64(v)
+		case uint64:
+			rule.value = float64(v)
+		case float64:
+			rule.value = v
+		default:
+			return nil, fmt.Errorf("risk rule %s: value must be a number or boolean", rule.Name)
+		}
+	}
+	return &rules, nil
+}
+
+// matches compares a signal value with the rule.
+func (r *Rule) matches(v float64) bool {
+	switch r.Op {
+	case "lt":
+		return v < r.value
+	case "lte":
+		return v <= r.value
+	case "gt":
+		return v > r.value
+	case "gte":
+		return v >= r.value
+	case "eq":
+		return v == r.value
+	case "ne":
+		return v != r.value
+	default:
+		return false
+	}
+}
@@ -0,0 +1,343 @@
+package sanctions
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Lists the screener can load.
+const (
+	ListOFAC = "ofac_sdn"
+	ListUN   = "un"
+	ListEU   = "eu"
+)
+
+// Entry is a sanctioned individual.
+type Entry struct {
+	List    string
+	ID      string
+	Name    string
+	Aliases []string
+	// DatesOfBirth are YYYY-MM-DD, or YYYY when only the year is known.
+	DatesOfBirth []string
+}
+
+// LoadFile reads a list from an XML or CSV file, chosen by extension.
+func LoadFile(list, path string) ([]*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s list: %v", list, err)
+	}
+	defer f.Close()
+
+	csvFile := strings.EqualFold(filepath.Ext(path), ".csv")
+
+	var entries []*Entry
+	switch {
+	case list == ListOFAC && csvFile:
+		entries, err = parseOFACCSV(f)
+	case list == ListOFAC:
+		entries, err = parseOFACXML(f)
+	case list == ListUN && !csvFile:
+		entries, err = parseUNXML(f)
+	case list == ListEU && csvFile:
+		entries, err = parseEUCSV(f)
+	case list == ListEU:
+		entries, err = parseEUXML(f)
+	default:
+		return nil, fmt.Errorf("unsupported format for %s list: %s", list, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s list: %v", list, err)
+	}
+	return entries, nil
+}
+
+// parseOFACXML reads the OFAC SDN list (sdn.xml).
+func parseOFACXML(r io.Reader) ([]*Entry, error) {
+	var doc struct {
+		Entries []struct {
+			UID       string `xml:"uid"`
+			FirstName string `xml:"firstName"`
+			LastName  string `xml:"lastName"`
+			Type      string `xml:"sdnType"`
+			Akas      []struct {
+				FirstName string `xml:"firstName"`
+				LastName  string `xml:"lastName"`
+			} `xml:"akaList>aka"`
+			DatesOfBirth []string `xml:"dateOfBirthList>dateOfBirthItem>dateOfBirth"`
+		} `xml:"sdnEntry"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, e := range doc.Entries {
+		if !strings.EqualFold(e.Type, "Individual") {
+			continue
+		}
+		entry := &Entry{
+			List: ListOFAC,
+			ID:   e.UID,
+			Name: joinName(e.FirstName, e.LastName),
+		}
+		for _, aka := range e.Akas {
+			entry.Aliases = append(entry.Aliases, joinName(aka.FirstName, aka.LastName))
+		}
+		for _, dob := range e.DatesOfBirth {
+			entry.DatesOfBirth = appendDate(entry.DatesOfBirth, dob)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+var ofacDOB = regexp.MustCompile(`DOB ([^;]+)`)
+
+// parseOFACCSV reads the OFAC SDN list (sdn.csv). Dates of birth are only
+// found in the remarks column.
+func parseOFACCSV(r io.Reader) ([]*Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var entries []*Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 || !strings.EqualFold(strings.TrimSpace(record[2]), "individual") {
+			continue
+		}
+
+		entry := &Entry{
+			List: ListOFAC,
+			ID:   strings.TrimSpace(record[0]),
+			Name: strings.TrimSpace(record[1]),
+		}
+		if len(record) > 11 {
+			for _, m := range ofacDOB.FindAllStringSubmatch(record[11], -1) {
+				entry.DatesOfBirth = appendDate(entry.DatesOfBirth, m[1])
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseUNXML reads the UN Security Council consolidated list.
+func parseUNXML(r io.Reader) ([]*Entry, error) {
+	var doc struct {
+		Individuals []struct {
+			DataID  string `xml:"DATAID"`
+			First   string `xml:"FIRST_NAME"`
+			Second  string `xml:"SECOND_NAME"`
+			Third   string `xml:"THIRD_NAME"`
+			Fourth  string `xml:"FOURTH_NAME"`
+			Aliases []struct {
+				Name string `xml:"ALIAS_NAME"`
+			} `xml:"INDIVIDUAL_ALIAS"`
+			DatesOfBirth []struct {
+				Date string `xml:"DATE"`
+				Year string `xml:"YEAR"`
+			} `xml:"INDIVIDUAL_DATE_OF_BIRTH"`
+		} `xml:"INDIVIDUALS>INDIVIDUAL"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, e := range doc.Individuals {
+		entry := &Entry{
+			List: ListUN,
+			ID:   e.DataID,
+			Name: joinName(e.First, e.Second, e.Third, e.Fourth),
+		}
+		for _, alias := range e.Aliases {
+			if alias.Name != "" {
+				entry.Aliases = append(entry.Aliases, alias.Name)
+			}
+		}
+		for _, dob := range e.DatesOfBirth {
+			if dob.Date != "" {
+				entry.DatesOfBirth = appendDate(entry.DatesOfBirth, dob.Date)
+			} else {
+				entry.DatesOfBirth = appendDate(entry.DatesOfBirth, dob.Year)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseEUXML reads the EU financial sanctions consolidated list.
+func parseEUXML(r io.Reader) ([]*Entry, error) {
+	var doc struct {
+		Entities []struct {
+			LogicalID string `xml:"logicalId,attr"`
+			Subject   struct {
+				Code string `xml:"code,attr"`
+			} `xml:"subjectType"`
+			Names []struct {
+				WholeName string `xml:"wholeName,attr"`
+			} `xml:"nameAlias"`
+			Births []struct {
+				Date string `xml:"birthdate,attr"`
+				Year string `xml:"year,attr"`
+			} `xml:"birthdate"`
+		} `xml:"sanctionEntity"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, e := range doc.Entities {
+		if e.Subject.Code != "person" || len(e.Names) == 0 {
+			continue
+		}
+		entry := &Entry{List: ListEU, ID: e.LogicalID}
+		for _, name := range e.Names {
+			entry.addName(name.WholeName)
+		}
+		for _, birth := range e.Births {
+			if birth.Date != "" {
+				entry.DatesOfBirth = appendDate(entry.DatesOfBirth, birth.Date)
+			} else {
+				entry.DatesOfBirth = appendDate(entry.DatesOfBirth, birth.Year)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseEUCSV reads the semicolon separated export of the EU list, which has
+// one row per name alias and birth date of each entity.
+func parseEUCSV(r io.Reader) ([]*Entry, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = i
+	}
+	field := func(record []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	if _, ok := col["Entity_LogicalId"]; !ok {
+		return nil, fmt.Errorf("missing Entity_LogicalId column")
+	}
+
+	byID := make(map[string]*Entry)
+	var entries []*Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if field(record, "Entity_SubjectType_ClassificationCode") != "person" {
+			continue
+		}
+
+		id := field(record, "Entity_LogicalId")
+		entry, ok := byID[id]
+		if !ok {
+			entry = &Entry{List: ListEU, ID: id}
+			byID[id] = entry
+			entries = append(entries, entry)
+		}
+		entry.addName(field(record, "NameAlias_WholeName"))
+		if date := field(record, "BirthDate_BirthDate"); date != "" {
+			entry.DatesOfBirth = appendDate(entry.DatesOfBirth, date)
+		} else {
+			entry.DatesOfBirth = appendDate(entry.DatesOfBirth, field(record, "BirthDate_Year"))
+		}
+	}
+
+	var named []*Entry
+	for _, entry := range entries {
+		if entry.Name != "" {
+			named = append(named, entry)
+		}
+	}
+	return named, nil
+}
+
+// addName sets the primary name, or records an alias once it is set.
+func (e *Entry) addName(name string) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+	case e.Name == "":
+		e.Name = name
+	case name != e.Name && !slices.Contains(e.Aliases, name):
+		e.Aliases = append(e.Aliases, name)
+	}
+}
+
+func joinName(parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, " ")
+}
+
+var yearOnly = regexp.MustCompile(`\b(1[89]\d\d|20\d\d)\b`)
+
+// dateLayouts are the full date formats used by the lists.
+var dateLayouts = []string{time.DateOnly, "2 Jan 2006", "02 Jan 2006", "02/01/2006", "2006/01/02"}
+
+// appendDate normalizes a list date to YYYY-MM-DD, or to YYYY for partial
+// dates such as "circa 1960" or "Jan 1960", and appends it once.
+func appendDate(dates []string, raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return dates
+	}
+
+	var date string
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			date = t.Format(time.DateOnly)
+			break
+		}
+	}
+	if date == "" {
+		date = yearOnly.FindString(raw)
+	}
+	if date == "" || slices.Contains(dates, date) {
+		return dates
+	}
+	return append(dates, date)
+}
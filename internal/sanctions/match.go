@@ -0,0 +1,119 @@
+package sanctions
+
+import (
+	"strings"
+)
+
+// Date of birth adjustments to the name score.
+const (
+	dobMatchBonus   = 0.05
+	dobMismatchCost = 0.15
+)
+
+// JaroWinkler returns the Jaro-Winkler similarity of two strings, from 0 for
+// nothing in common to 1 for equal strings.
+func JaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		lo := max(0, i-window)
+		hi := min(len(rb), i+window+1)
+		for j := lo; j < hi; j++ {
+			if matchedB[j] || ra[i] != rb[j] {
+				continue
+			}
+			matchedA[i], matchedB[j] = true, true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// NameScore compares two token lists produced by Tokens. It is the better of
+// the whole-name similarity and the token-set similarity, which tolerates
+// missing middle names and reordered tokens.
+func NameScore(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	whole := JaroWinkler(strings.Join(a, " "), strings.Join(b, " "))
+	set := (tokenCoverage(a, b) + tokenCoverage(b, a)) / 2
+	return max(whole, set)
+}
+
+// tokenCoverage averages, over the tokens of a, the best similarity to any
+// token of b.
+func tokenCoverage(a, b []string) float64 {
+	var total float64
+	for _, ta := range a {
+		best := 0.0
+		for _, tb := range b {
+			best = max(best, JaroWinkler(ta, tb))
+		}
+		total += best
+	}
+	return total / float64(len(a))
+}
+
+// dobAdjust moves a name score by the date of birth evidence. dob is
+// YYYY-MM-DD; list dates may be a full date or only a year. It returns nil
+// when either side has no date to compare.
+func dobAdjust(score float64, dob string, listDates []string) (float64, *bool) {
+	if dob == "" || len(listDates) == 0 {
+		return score, nil
+	}
+
+	matched := false
+	for _, d := range listDates {
+		if d == dob || (len(d) == 4 && strings.HasPrefix(dob, d)) {
+			matched = true
+			break
+		}
+	}
+
+	if matched {
+		return min(1, score+dobMatchBonus), &matched
+	}
+	return max(0, score-dobMismatchCost), &matched
+}
@@ -0,0 +1,56 @@
+package sanctions
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "martha", 1},
+		{"martha", "marhta", 0.961},
+		{"dwayne", "duane", 0.840},
+		{"dixon", "dicksonx", 0.813},
+		{"jones", "johnson", 0.832},
+		{"abc", "xyz", 0},
+		{"", "abc", 0},
+		{"abc", "", 0},
+		{"", "", 1},
+		{"a", "a", 1},
+		{"joão", "joao", 0.867},
+	}
+	for _, tt := range tests {
+		got := JaroWinkler(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("JaroWinkler(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+		if reverse := JaroWinkler(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+			t.Errorf("JaroWinkler(%q, %q) = %.3f is not symmetric with %.3f", tt.b, tt.a, reverse, got)
+		}
+	}
+}
+
+func TestNameScore(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"same name reordered", "SILVA, João", "João da Silva", 1, 1},
+		{"missing middle name", "João Pedro Silva", "João Silva", 0.9, 1},
+		{"different people", "Maria Oliveira", "Carlos Pereira", 0, 0.75},
+		{"empty", "", "João Silva", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NameScore(Tokens(tt.a), Tokens(tt.b))
+			if got < tt.min-1e-9 || got > tt.max+1e-9 {
+				t.Errorf("NameScore(%q, %q) = %.3f, want between %.2f and %.2f", tt.a, tt.b, got, tt.min, tt.max)
+			}
+		})
+	}
+}
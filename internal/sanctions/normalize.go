@@ -0,0 +1,105 @@
+package sanctions
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// folds maps accented Latin letters to ASCII. Portuguese names are the common
+// case (ã, ç, é...), the rest covers the names found on the lists.
+var folds = buildFolds(map[string]string{
+	"a":  "àáâãäåāăąǎ",
+	"c":  "çćĉċč",
+	"d":  "ďđð",
+	"e":  "èéêëēĕėęěə",
+	"g":  "ĝğġģ",
+	"h":  "ĥħ",
+	"i":  "ìíîïĩīĭįıǐ",
+	"j":  "ĵ",
+	"k":  "ķ",
+	"l":  "ĺļľŀł",
+	"n":  "ñńņňŉ",
+	"o":  "òóôõöøōŏőǒ",
+	"r":  "ŕŗř",
+	"s":  "śŝşšș",
+	"t":  "ţťŧț",
+	"u":  "ùúûüũūŭůűųǔ",
+	"w":  "ŵ",
+	"y":  "ýÿŷ",
+	"z":  "źżž",
+	"ss": "ß",
+	"ae": "æ",
+	"oe": "œ",
+	"th": "þ",
+})
+
+// translit romanizes Cyrillic and Greek, which appear in aliases.
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'є': "ye", 'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k",
+	'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+}
+
+// particles are connecting words that Brazilian and other Romance names use
+// inconsistently ("Maria da Silva" vs "Maria Silva").
+var particles = map[string]bool{
+	"da": true, "das": true, "de": true, "do": true, "dos": true, "e": true,
+	"di": true, "del": true, "della": true, "der": true, "van": true, "von": true,
+}
+
+func buildFolds(groups map[string]string) map[rune]string {
+	m := make(map[rune]string)
+	for ascii, letters := range groups {
+		for _, r := range letters {
+			m[r] = ascii
+		}
+	}
+	return m
+}
+
+// Tokens normalizes a name into sorted ASCII tokens: lower case, accents
+// stripped, Cyrillic and Greek transliterated, punctuation and particles
+// dropped. Sorting makes "SILVA, João" and "João Silva" equal.
+func Tokens(name string) []string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if s, ok := folds[r]; ok {
+			b.WriteString(s)
+			continue
+		}
+		if s, ok := translit[r]; ok {
+			b.WriteString(s)
+			continue
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) || r == '\'' || r == '’' {
+			// Combining marks and apostrophes join the surrounding letters.
+			continue
+		}
+		b.WriteByte(' ')
+	}
+
+	var tokens []string
+	for _, token := range strings.Fields(b.String()) {
+		if !particles[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// Normalize returns the normalized form of a name.
+func Normalize(name string) string {
+	return strings.Join(Tokens(name), " ")
+}
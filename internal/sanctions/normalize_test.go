@@ -0,0 +1,33 @@
+package sanctions
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"reordered with comma", "SILVA, João", []string{"joao", "silva"}},
+		{"particles dropped", "Maria da Silva dos Santos", []string{"maria", "santos", "silva"}},
+		{"apostrophe joins", "Sean O'Brien", []string{"obrien", "sean"}},
+		{"combining accent", "José Conceição", []string{"conceicao", "jose"}},
+		{"hyphen splits", "Müller-Lüdenscheidt", []string{"ludenscheidt", "muller"}},
+		{"multi-letter folds", "Straße Æther", []string{"aether", "strasse"}},
+		{"cyrillic", "Владимир Путин", []string{"putin", "vladimir"}},
+		{"greek", "ΑΛΕΞΗΣ", []string{"alexis"}},
+		{"digits kept", "Unit 731", []string{"731", "unit"}},
+		{"only particles", "de da", nil},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokens(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("Tokens(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Package sanctions screens verified users against local copies of the OFAC
+// SDN, UN and EU consolidated sanctions lists, alongside Didit's AML checks.
+// The list files are downloaded out of band and reloaded periodically.
+package sanctions
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+)
+
+// Match is a list entry similar enough to the screened person.
+type Match struct {
+	List         string   `json:"list"`
+	EntryID      string   `json:"entry_id"`
+	EntryName    string   `json:"entry_name"`
+	MatchedName  string   `json:"matched_name"`
+	Score        float64  `json:"score"`
+	DOBMatch     *bool    `json:"dob_match,omitempty"`
+	DatesOfBirth []string `json:"dates_of_birth,omitempty"`
+}
+
+// indexedEntry keeps the normalized tokens of every name of an entry.
+type indexedEntry struct {
+	*Entry
+	names  []string
+	tokens [][]string
+}
+
+// Screener matches names against the loaded lists.
+type Screener struct {
+	files     map[string]string
+	threshold float64
+	interval  time.Duration
+
+	mu      sync.RWMutex
+	entries []*indexedEntry
+	counts  map[string]int
+}
+
+func NewScreener(cfg *config.Config) *Screener {
+	files := make(map[string]string)
+	for list, path := range map[string]string{
+		ListOFAC: cfg.SanctionsOFACFile,
+		ListUN:   cfg.SanctionsUNFile,
+		ListEU:   cfg.SanctionsEUFile,
+	} {
+		if path != "" {
+			files[list] = path
+		}
+	}
+
+	return &Screener{
+		files:     files,
+		threshold: cfg.SanctionsMatchThreshold,
+		interval:  cfg.SanctionsReloadInterval,
+		counts:    make(map[string]int),
+	}
+}
+
+// Enabled reports whether any list file is configured.
+func (s *Screener) Enabled() bool {
+	return len(s.files) > 0
+}
+
+// Load reads every configured list. A list that fails to load keeps its
+// previous entries, so a bad download never empties the screening.
+func (s *Screener) Load() error {
+	s.mu.RLock()
+	previous := make(map[string][]*indexedEntry)
+	for _, e := range s.entries {
+		previous[e.List] = append(previous[e.List], e)
+	}
+	s.mu.RUnlock()
+
+	var firstErr error
+	var entries []*indexedEntry
+	counts := make(map[string]int)
+	for list, path := range s.files {
+		loaded, err := LoadFile(list, path)
+		if err != nil {
+			log.Printf("Keeping previous %s sanctions list: %v", list, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			entries = append(entries, previous[list]...)
+			counts[list] = len(previous[list])
+			continue
+		}
+		for _, entry := range loaded {
+			entries = append(entries, index(entry))
+		}
+		counts[list] = len(loaded)
+	}
+
+	s.mu.Lock()
+	s.entries = entries
+	s.counts = counts
+	s.mu.Unlock()
+
+	return firstErr
+}
+
+func index(entry *Entry) *indexedEntry {
+	ie := &indexedEntry{Entry: entry}
+	for _, name := range append([]string{entry.Name}, entry.Aliases...) {
+		if tokens := Tokens(name); len(tokens) > 0 {
+			ie.names = append(ie.names, name)
+			ie.tokens = append(ie.tokens, tokens)
+		}
+	}
+	return ie
+}
+
+// Counts returns the number of entries loaded per list.
+func (s *Screener) Counts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int, len(s.counts))
+	for list, n := range s.counts {
+		counts[list] = n
+	}
+	return counts
+}
+
+// Screen returns the entries whose best name score, adjusted by the date of
+// birth (YYYY-MM-DD, optional), reaches the threshold. Best matches first.
+func (s *Screener) Screen(firstName, lastName, dateOfBirth string) []Match {
+	query := Tokens(firstName + " " + lastName)
+	if len(query) == 0 {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []Match
+	for _, entry := range s.entries {
+		best, bestName := 0.0, ""
+		for i, tokens := range entry.tokens {
+			if score := NameScore(query, tokens); score > best {
+				best, bestName = score, entry.names[i]
+			}
+		}
+
+		score, dobMatch := dobAdjust(best, dateOfBirth, entry.DatesOfBirth)
+		if score < s.threshold {
+			continue
+		}
+		matches = append(matches, Match{
+			List:         entry.List,
+			EntryID:      entry.ID,
+			EntryName:    entry.Name,
+			MatchedName:  bestName,
+			Score:        score,
+			DOBMatch:     dobMatch,
+			DatesOfBirth: entry.DatesOfBirth,
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// Run reloads the lists on every interval until ctx is cancelled, picking up
+// files replaced by the out-of-band download. A zero interval disables it.
+func (s *Screener) Run(ctx context.Context) {
+	if !s.Enabled() || s.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(); err != nil {
+				log.Printf("Sanctions list reload failed: %v", err)
+				continue
+			}
+			log.Printf("Sanctions lists reloaded: %v", s.Counts())
+		}
+	}
+}
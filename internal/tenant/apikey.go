@@ -0,0 +1,32 @@
+package tenant
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Headers naming the tenant of a request: the tenant's own API key on client
+// routes, the tenant slug on admin routes. UserHeader names the end user the
+// tenant's backend acts for, trusted only along with the API key.
+const (
+	APIKeyHeader = "X-API-Key"
+	AdminHeader  = "X-Tenant"
+	UserHeader   = "X-User-ID"
+)
+
+// NewAPIKey generates a tenant API key. Only its hash is stored.
+func NewAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "crk_" + hex.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hex SHA-256 stored for an API key. Keys are random,
+// so a fast unsalted hash is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
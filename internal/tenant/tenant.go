@@ -0,0 +1,58 @@
+// Package tenant carries the tenant a request or job acts for. Every
+// verification session and webhook event belongs to a tenant, and the
+// repository scopes its queries to the one in the context.
+package tenant
+
+import (
+	"context"
+
+	"github.com/FelipePn10/crispaybackend/internal/email/service"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultID is the tenant seeded by the migration. It owns the sessions of
+// callers without an API key and uses the environment's settings.
+var DefaultID = uuid.Nil
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx acting for t. A nil t is the default
+// tenant.
+func NewContext(ctx context.Context, t *models.Tenant) context.Context {
+	if t != nil && t.ID == DefaultID {
+		t = nil
+	}
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant of ctx, or nil for the default tenant.
+func FromContext(ctx context.Context) *models.Tenant {
+	t, _ := ctx.Value(contextKey{}).(*models.Tenant)
+	return t
+}
+
+// ID returns the id of the tenant of ctx.
+func ID(ctx context.Context) uuid.UUID {
+	if t := FromContext(ctx); t != nil {
+		return t.ID
+	}
+	return DefaultID
+}
+
+// EmailBranding returns the email branding of the tenant of ctx. The default
+// tenant uses the built-in look.
+func EmailBranding(ctx context.Context) service.Branding {
+	t := FromContext(ctx)
+	if t == nil {
+		return service.Branding{}
+	}
+	return service.Branding{
+		Name:         t.Name,
+		SenderName:   t.SenderName,
+		SenderEmail:  t.SenderEmail,
+		LogoURL:      t.LogoURL,
+		PrimaryColor: t.PrimaryColor,
+		SupportURL:   t.SupportURL,
+	}
+}
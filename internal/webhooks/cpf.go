@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+)
+
+// cpfFields are the fields of the id_verification section that may carry the
+// holder's CPF: the personal number of an RG or CNH, or the document number
+// of the national identity card, which is the CPF itself.
+var cpfFields = []string{"personal_number", "document_number"}
+
+// checkCPF compares the CPF given when the session was started with the one
+// Didit read off the document. Only values passing the CPF checksum count,
+// so a CNH registration number is not mistaken for a different CPF. It is nil
+// when either side has no CPF.
+func checkCPF(session *models.VerificationSession, data models.WebhookData) *bool {
+	if session.UserCPF == "" {
+		return nil
+	}
+
+	found := false
+	for _, field := range cpfFields {
+		cpf := kyc.NormalizeCPF(DecisionString(data, "id_verification", field))
+		if !kyc.ValidCPF(cpf) {
+			continue
+		}
+		if cpf == session.UserCPF {
+			match := true
+			return &match
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	match := false
+	return &match
+}
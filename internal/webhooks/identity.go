@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"context"
+	"log"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+)
+
+// Actions taken when an approved identity is already verified under another
+// user id.
+const (
+	DuplicateFlag  = "flag"
+	DuplicateBlock = "block"
+	DuplicateOff   = "off"
+)
+
+// findDuplicates fingerprints the document Didit read for an approved session
+// and returns the latest session of every other user with the same one. The
+// fingerprint is empty when the decision lacks the document number, issuing
+// country or date of birth.
+func (p *Processor) findDuplicates(ctx context.Context, session *models.VerificationSession, data models.WebhookData) (string, []*models.VerificationSession, error) {
+	fingerprint, err := p.repo.IdentityFingerprint(ctx,
+		DecisionString(data, "id_verification", "document_number"),
+		DecisionString(data, "id_verification", "issuing_state"),
+		DecisionString(data, "id_verification", "date_of_birth"),
+	)
+	if err != nil || fingerprint == "" || p.duplicateAction == DuplicateOff {
+		return fingerprint, nil, err
+	}
+
+	duplicates, err := p.repo.ListDuplicateIdentities(ctx, fingerprint, session.UserID)
+	if err != nil {
+		return fingerprint, nil, err
+	}
+	if len(duplicates) > 0 {
+		log.Printf("Session %s shares its identity document with %d other user(s)", session.SessionID, len(duplicates))
+	}
+	return fingerprint, duplicates, nil
+}
+
+// linkDuplicates stores the fingerprint of a session and links it to the
+// sessions of the other users carrying the same identity.
+func (p *Processor) linkDuplicates(ctx context.Context, session *models.VerificationSession, fingerprint string, duplicates []*models.VerificationSession) error {
+	if fingerprint == "" {
+		return nil
+	}
+	if err := p.repo.SetSessionFingerprint(ctx, session.SessionID, fingerprint); err != nil {
+		return err
+	}
+	for _, duplicate := range duplicates {
+		if _, err := p.identity.CreateLink(ctx, session, duplicate, p.duplicateAction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
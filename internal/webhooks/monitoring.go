@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+)
+
+// Event types of Didit's ongoing AML monitoring.
+const (
+	EventAMLMonitoringHit = "aml.monitoring.hit"
+	EventAMLOngoingHit    = "aml.ongoing_monitoring.hit"
+)
+
+func isAMLMonitoringEvent(eventType string) bool {
+	return eventType == EventAMLMonitoringHit || eventType == EventAMLOngoingHit
+}
+
+// processAMLHit moves every approved session of the user to review. A hit
+// concerns the person, so no approval may keep granting a KYC level until a
+// compliance analyst looks at it.
+func (p *Processor) processAMLHit(ctx context.Context, event models.WebhookEvent, outcome *Outcome, dryRun bool) (*Outcome, error) {
+	userID := ExtractUserID(event.Data)
+	if userID == "" && event.Data.SessionID != "" {
+		session, err := p.repo.GetSessionByDiditSessionID(ctx, event.Data.SessionID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return outcome, err
+		}
+		if session != nil {
+			userID = session.UserID
+		}
+	}
+	if userID == "" {
+		log.Printf("User ID not found in AML monitoring webhook")
+		outcome.Skipped = "user id not found in webhook data"
+		return outcome, nil
+	}
+	outcome.UserID = userID
+
+	approved, err := p.repo.ListApprovedSessions(ctx, userID)
+	if err != nil {
+		return outcome, fmt.Errorf("error getting approved sessions for user: %w", err)
+	}
+	if len(approved) == 0 {
+		outcome.Skipped = "no approved sessions for user"
+		return outcome, nil
+	}
+
+	outcome.SessionID = approved[0].SessionID
+	outcome.FromStatus = approved[0].Status
+	outcome.ToStatus = "review"
+	for _, session := range approved {
+		outcome.AffectedSessions = append(outcome.AffectedSessions, session.SessionID)
+	}
+
+	if dryRun {
+		return outcome, nil
+	}
+
+	for _, session := range approved {
+		locked, err := p.repo.LockSession(ctx, session.SessionID)
+		if err != nil {
+			return outcome, fmt.Errorf("error locking session: %w", err)
+		}
+		// A transition that committed since the listing wins.
+		if locked.Status != "approved" {
+			continue
+		}
+
+		updated, err := p.repo.UpdateStatus(ctx, session.SessionID, "review")
+		if err != nil {
+			return outcome, fmt.Errorf("error updating session status: %w", err)
+		}
+
+		err = p.afterTransition(ctx, locked, updated, "", WebhookActor, models.AuditSessionTransition, map[string]any{
+			"event_type":       event.EventType,
+			"didit_session_id": event.Data.SessionID,
+			"reason":           "aml_monitoring_hit",
+		})
+		if err != nil {
+			return outcome, err
+		}
+	}
+	outcome.Applied = true
+
+	log.Printf("AML monitoring hit moved %d session(s) of user %s to review", len(approved), userID)
+	return outcome, nil
+}
+
+// DocumentExpiry reads the expiration date of the identity document from a
+// Didit decision, or returns nil when the decision carries none.
+func DocumentExpiry(data models.WebhookData) *time.Time {
+	for _, key := range []string{"expiration_date", "expiry_date", "date_of_expiry"} {
+		value := DecisionString(data, "id_verification", key)
+		if value == "" {
+			continue
+		}
+		for _, layout := range []string{time.DateOnly, time.RFC3339} {
+			if t, err := time.Parse(layout, value); err == nil {
+				t = t.UTC()
+				return &t
+			}
+		}
+	}
+
+	return nil
+}
+
+// ErrNotExpired is returned by Expire when the session is no longer an
+// approval past its expires_at.
+var ErrNotExpired = errors.New("session is not an expired approval")
+
+// Expire moves an approved session past its expires_at to expired, through
+// the same audit and outbound event path as webhooks, in one transaction.
+func (p *Processor) Expire(ctx context.Context, session *models.VerificationSession, actor string, reason string) (*models.VerificationSession, error) {
+	var updated *models.VerificationSession
+	err := p.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		tx := p.bind(repo)
+		locked, err := tx.repo.LockSession(ctx, session.SessionID)
+		if err != nil {
+			return fmt.Errorf("error locking session: %w", err)
+		}
+		// A transition or new expiry that committed since the listing wins.
+		if locked.Status != "approved" || locked.ExpiresAt == nil || locked.ExpiresAt.After(time.Now()) {
+			return ErrNotExpired
+		}
+
+		updated, err = tx.repo.UpdateStatus(ctx, session.SessionID, "expired")
+		if err != nil {
+			return fmt.Errorf("error updating session status: %w", err)
+		}
+
+		details := map[string]any{"reason": reason}
+		if locked.ExpiresAt != nil {
+			details["expires_at"] = locked.ExpiresAt
+		}
+		return tx.afterTransition(ctx, locked, updated, "", actor, models.AuditSessionTransition, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,492 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/email/service"
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/media"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/outbound"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+	"github.com/FelipePn10/crispaybackend/internal/risk"
+	"github.com/FelipePn10/crispaybackend/internal/sanctions"
+	"github.com/FelipePn10/crispaybackend/internal/tenant"
+)
+
+// Emails that a processed event may trigger.
+const (
+	EmailApproved = "approved_kyc"
+	EmailFailed   = "failed_kyc"
+)
+
+// Outcome describes what processing a webhook event did, or would do when
+// running in dry-run mode.
+type Outcome struct {
+	EventID        string `json:"event_id,omitempty"`
+	EventType      string `json:"event_type"`
+	DiditSessionID string `json:"didit_session_id"`
+	UserID         string `json:"user_id,omitempty"`
+	SessionID      string `json:"session_id,omitempty"`
+	FromStatus     string `json:"from_status,omitempty"`
+	ToStatus       string `json:"to_status,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Skipped        string `json:"skipped,omitempty"`
+	Applied        bool   `json:"applied"`
+	// AffectedSessions lists every session an AML monitoring hit moved to
+	// review.
+	AffectedSessions []string `json:"affected_sessions,omitempty"`
+	// ScreeningMatches counts the sanctions hits that held back an approval.
+	ScreeningMatches int `json:"screening_matches,omitempty"`
+	// DuplicateUsers lists the other users verified with the same identity
+	// document.
+	DuplicateUsers []string `json:"duplicate_users,omitempty"`
+	// RiskScore and RiskDecision are the risk evaluation of a terminal
+	// decision.
+	RiskScore    *float64 `json:"risk_score,omitempty"`
+	RiskDecision string   `json:"risk_decision,omitempty"`
+	// CPFMismatch is set when the CPF given at start differs from the one on
+	// the verified document.
+	CPFMismatch bool `json:"cpf_mismatch,omitempty"`
+	// MediaStored lists the verification media kept from the decision.
+	MediaStored []string `json:"media_stored,omitempty"`
+}
+
+// Processor runs provider webhook events through the status transition and
+// email pipeline. It is shared by the live webhook endpoint and the replay
+// tooling so both always behave the same way.
+type Processor struct {
+	repo      *repository.VerificationRepository
+	email     *service.EmailService
+	publisher *outbound.Dispatcher
+	audit     *repository.AuditRepository
+	screener  *sanctions.Screener
+	screening *repository.ScreeningRepository
+	identity  *repository.IdentityRepository
+	scorer    *risk.Engine
+	risks     *repository.RiskRepository
+	providers *kyc.Router
+	tenants   *repository.TenantRepository
+	media     *media.Service
+
+	// duplicateAction is taken on approvals of an identity already verified
+	// under another user id.
+	duplicateAction string
+	// reviewInterval schedules the periodic re-screening of approvals.
+	reviewInterval time.Duration
+	// silent sends no user emails, for replays of events the user was
+	// already told about.
+	silent bool
+}
+
+func NewProcessor(cfg *config.Config, repo *repository.VerificationRepository, emailService *service.EmailService, publisher *outbound.Dispatcher, audit *repository.AuditRepository, screener *sanctions.Screener, screening *repository.ScreeningRepository, identity *repository.IdentityRepository, scorer *risk.Engine, risks *repository.RiskRepository, providers *kyc.Router, tenants *repository.TenantRepository, mediaService *media.Service) *Processor {
+	return &Processor{
+		repo:            repo,
+		email:           emailService,
+		publisher:       publisher,
+		audit:           audit,
+		screener:        screener,
+		screening:       screening,
+		identity:        identity,
+		scorer:          scorer,
+		risks:           risks,
+		providers:       providers,
+		tenants:         tenants,
+		media:           mediaService,
+		duplicateAction: cfg.DuplicateIdentityAction,
+		reviewInterval:  time.Duration(cfg.KYCReviewIntervalDays) * 24 * time.Hour,
+	}
+}
+
+// WebhookActor is the audit log actor of webhook-driven transitions.
+const WebhookActor = "didit:webhook"
+
+// Process applies a webhook event. With dryRun set, it only resolves the
+// session and reports the transition and email without changing anything.
+// Otherwise the event is applied in a single transaction holding the lock
+// of the session row: the status, its side records, the audit log and the
+// outbound deliveries commit together, and emails only go out once they
+// have.
+func (p *Processor) Process(ctx context.Context, event models.WebhookEvent, dryRun bool) (*Outcome, error) {
+	outcome := &Outcome{
+		EventType:      event.EventType,
+		DiditSessionID: event.Data.SessionID,
+	}
+	if dryRun {
+		return p.process(ctx, event, outcome, true)
+	}
+
+	err := p.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		_, err := p.bind(repo).process(ctx, event, outcome, false)
+		return err
+	})
+	if err != nil {
+		outcome.Applied = false
+	}
+	return outcome, err
+}
+
+// bind returns a processor whose repositories and publisher write through
+// the transaction of repo.
+func (p *Processor) bind(repo *repository.VerificationRepository) *Processor {
+	queries := repo.Queries()
+	bound := *p
+	bound.repo = repo
+	bound.audit = repository.NewAuditRepository(queries)
+	bound.screening = repository.NewScreeningRepository(queries)
+	bound.identity = repository.NewIdentityRepository(queries)
+	bound.risks = repository.NewRiskRepository(queries)
+	bound.publisher = p.publisher.WithRepository(repository.NewSubscriptionRepository(queries))
+	return &bound
+}
+
+func (p *Processor) process(ctx context.Context, event models.WebhookEvent, outcome *Outcome, dryRun bool) (*Outcome, error) {
+	if isAMLMonitoringEvent(event.EventType) {
+		return p.processAMLHit(ctx, event, outcome, dryRun)
+	}
+
+	status, email := transitionFor(event.EventType)
+	if status == "" {
+		log.Printf("Unhandled event type: %s", event.EventType)
+		outcome.Skipped = "unhandled event type"
+		return outcome, nil
+	}
+
+	// Extract user_id from metadata or user_data
+	userID := ExtractUserID(event.Data)
+	if userID == "" {
+		log.Printf("User ID not found in webhook data")
+		outcome.Skipped = "user id not found in webhook data"
+		return outcome, nil
+	}
+	outcome.UserID = userID
+
+	sessions, err := p.repo.ListVerificationSessionsByUserID(ctx, userID)
+	if err != nil {
+		return outcome, fmt.Errorf("error getting sessions for user: %w", err)
+	}
+
+	if len(sessions) == 0 {
+		log.Printf("No sessions found for user: %s", userID)
+		outcome.Skipped = "no sessions found for user"
+		return outcome, nil
+	}
+
+	session := eventSession(sessions, event.Data.SessionID)
+	if !dryRun {
+		// Re-read under the row lock, so the transition starts from the
+		// status no other writer can change until this one commits.
+		session, err = p.repo.LockSession(ctx, session.SessionID)
+		if err != nil {
+			return outcome, fmt.Errorf("error locking session: %w", err)
+		}
+	}
+	diditStatus := status
+
+	// Sanctions hits hold an approval back for a compliance analyst, and so
+	// do a CPF not matching the document and an identity already verified
+	// under another user id, unless such duplicates are blocked outright.
+	var matches []sanctions.Match
+	var fingerprint string
+	var duplicates []*models.VerificationSession
+	var cpfMatch *bool
+	if models.IsTerminalStatus(diditStatus) {
+		cpfMatch = checkCPF(session, event.Data)
+	}
+	if status == "approved" {
+		matches = p.screen(session, event.Data)
+		if len(matches) > 0 {
+			status, email = "review", ""
+			outcome.ScreeningMatches = len(matches)
+		}
+
+		if cpfMatch != nil && !*cpfMatch {
+			status, email = "review", ""
+			outcome.CPFMismatch = true
+		}
+
+		fingerprint, duplicates, err = p.findDuplicates(ctx, session, event.Data)
+		if err != nil {
+			return outcome, err
+		}
+		if len(duplicates) > 0 {
+			if p.duplicateAction == DuplicateBlock {
+				status, email = "failed", EmailFailed
+			} else {
+				status, email = "review", ""
+			}
+			for _, duplicate := range duplicates {
+				outcome.DuplicateUsers = append(outcome.DuplicateUsers, duplicate.UserID)
+			}
+		}
+	}
+
+	// The risk score can only hold back or decline an approval; in shadow
+	// mode it is just recorded.
+	var score *risk.Result
+	if p.scorer != nil && p.scorer.Enabled() && models.IsTerminalStatus(diditStatus) {
+		score = p.scorer.Evaluate(p.riskSignals(session, sessions, event.Data, len(matches), len(duplicates), cpfMatch))
+	}
+	riskApplied := false
+	if score != nil {
+		outcome.RiskScore = &score.Score
+		outcome.RiskDecision = score.Decision
+		if p.scorer.Enforced() && status == "approved" {
+			switch score.Decision {
+			case risk.DecisionDecline:
+				status, email, riskApplied = "failed", EmailFailed, true
+			case risk.DecisionReview:
+				status, email, riskApplied = "review", "", true
+			}
+		}
+	}
+
+	if p.silent {
+		email = ""
+	}
+	outcome.SessionID = session.SessionID
+	outcome.FromStatus = session.Status
+	outcome.ToStatus = status
+	outcome.Email = email
+
+	// The same decision may arrive more than once: from the return callback
+	// or a reconciliation and then the webhook, from a webhook retry, or
+	// from a replay. A session already holding the resulting status keeps
+	// it without a second set of matches, scores, links or emails.
+	if session.Status == status {
+		outcome.Skipped = "session already " + status
+		outcome.Email = ""
+		return outcome, nil
+	}
+
+	if dryRun {
+		return outcome, nil
+	}
+
+	// Update using the didit_session_id
+	if _, err := p.repo.UpdateDiditSessionID(ctx, session.SessionID, event.Data.SessionID); err != nil {
+		return outcome, fmt.Errorf("error updating didit session data: %w", err)
+	}
+
+	updated, err := p.repo.UpdateStatus(ctx, session.SessionID, status)
+	if err != nil {
+		return outcome, fmt.Errorf("error updating session status: %w", err)
+	}
+	outcome.Applied = true
+
+	if expiresAt := DocumentExpiry(event.Data); expiresAt != nil {
+		if err := p.repo.SetSessionExpiry(ctx, session.SessionID, *expiresAt); err != nil {
+			return outcome, err
+		}
+		updated.ExpiresAt = expiresAt
+	}
+
+	details := map[string]any{
+		"event_type":       event.EventType,
+		"didit_session_id": event.Data.SessionID,
+	}
+	if len(matches) > 0 {
+		if err := p.screening.CreateMatches(ctx, session.SessionID, session.UserID, matches); err != nil {
+			return outcome, err
+		}
+		details["reason"] = "sanctions_screening"
+		details["screening_matches"] = len(matches)
+	}
+	if cpfMatch != nil {
+		if err := p.repo.SetSessionCPFMatch(ctx, session.SessionID, *cpfMatch); err != nil {
+			return outcome, err
+		}
+		updated.CPFMatch = cpfMatch
+		details["cpf_match"] = *cpfMatch
+	}
+	if outcome.CPFMismatch {
+		if _, ok := details["reason"]; !ok {
+			details["reason"] = "cpf_mismatch"
+		}
+	}
+	if err := p.linkDuplicates(ctx, session, fingerprint, duplicates); err != nil {
+		return outcome, err
+	}
+	if len(duplicates) > 0 {
+		if _, ok := details["reason"]; !ok {
+			details["reason"] = "duplicate_identity"
+		}
+		details["duplicate_user_ids"] = outcome.DuplicateUsers
+	}
+	if score != nil {
+		if _, err := p.risks.CreateScore(ctx, session, score, diditStatus, status, p.scorer.Enforced()); err != nil {
+			return outcome, err
+		}
+		if riskApplied {
+			details["reason"] = "risk_score"
+		}
+		details["risk_score"] = score.Score
+		details["risk_decision"] = score.Decision
+	}
+
+	if err := p.afterTransition(ctx, session, updated, email, WebhookActor, models.AuditSessionTransition, details); err != nil {
+		return outcome, err
+	}
+
+	// The provider's media links expire soon after the decision, so they are
+	// kept as soon as it commits. A failed download does not undo the
+	// decision; an admin can capture again from a freshly fetched decision.
+	if p.media.Enabled() && models.IsTerminalStatus(diditStatus) {
+		p.repo.AfterCommit(func() {
+			report, err := p.media.Capture(ctx, updated, event.Data.Decision)
+			if err != nil {
+				log.Printf("Failed to capture media of session %s: %v", session.SessionID, err)
+			} else {
+				outcome.MediaStored = report.Stored
+			}
+		})
+	}
+
+	log.Printf("User %s verification %s (session: %s)", session.UserID, status, session.SessionID)
+	return outcome, nil
+}
+
+// eventSession picks the session an event is about: the one opened at the
+// provider under the event's session id, or else the most recent one, as
+// fixed links only learn the provider's id from the first webhook.
+func eventSession(sessions []*models.VerificationSession, providerSessionID string) *models.VerificationSession {
+	if providerSessionID != "" {
+		for _, session := range sessions {
+			if session.DiditSessionID == providerSessionID {
+				return session
+			}
+		}
+	}
+	return sessions[0]
+}
+
+// ParseStored decodes a stored webhook event with the provider that sent it.
+func (p *Processor) ParseStored(stored *models.WebhookEventDB) (*models.WebhookEvent, error) {
+	provider, err := p.providers.Provider(stored.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return provider.ParseWebhook(stored.Payload)
+}
+
+// afterTransition audits a status change, queues its outbound deliveries
+// and schedules its periodic review alongside the status, failing the
+// transition with them. The email that goes with it and the wake-up of the
+// delivery workers wait for the commit. A status written again unchanged
+// does none of this.
+func (p *Processor) afterTransition(ctx context.Context, before *models.VerificationSession, after *models.VerificationSession, email string, actor string, action string, details map[string]any) error {
+	// Nothing happened to announce.
+	if before.Status == after.Status {
+		return nil
+	}
+
+	details["from_status"] = before.Status
+	details["to_status"] = after.Status
+	_, err := p.audit.Record(ctx, models.AuditEvent{
+		Action:        action,
+		SubjectUserID: after.UserID,
+		Actor:         actor,
+		EntityType:    "session",
+		EntityID:      after.SessionID,
+		Details:       details,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to audit status transition: %w", err)
+	}
+
+	transition := models.StatusTransition{
+		SessionID:      after.SessionID,
+		TenantID:       after.TenantID,
+		UserID:         after.UserID,
+		DiditSessionID: after.DiditSessionID,
+		PreviousStatus: before.Status,
+		Status:         after.Status,
+		OccurredAt:     after.UpdatedAt,
+	}
+	queued, err := p.publisher.Enqueue(ctx, transition)
+	if err != nil {
+		return fmt.Errorf("failed to publish status transition: %w", err)
+	}
+	if queued {
+		p.repo.AfterCommit(p.publisher.Notify)
+	}
+
+	if after.Status == "approved" && p.reviewInterval > 0 {
+		if err := p.repo.SetSessionNextReview(ctx, after.SessionID, time.Now().Add(p.reviewInterval)); err != nil {
+			return fmt.Errorf("failed to schedule review: %w", err)
+		}
+	}
+
+	// Erased sessions no longer have an address to write to.
+	if after.ErasedAt != nil {
+		return nil
+	}
+	emailUser := service.User{
+		Name:     after.UserFirstName,
+		Email:    after.UserEmail,
+		Branding: tenant.EmailBranding(ctx),
+	}
+	switch email {
+	case EmailApproved:
+		p.repo.AfterCommit(func() { p.email.SendApprovedKycEmailAsync(emailUser) })
+	case EmailFailed:
+		p.repo.AfterCommit(func() { p.email.SendFailedKycEmailAsync(emailUser) })
+	}
+	return nil
+}
+
+// transitionFor maps a Didit event type to the session status it leads to
+// and the email the user should receive.
+func transitionFor(eventType string) (status string, email string) {
+	switch eventType {
+	case "verification.completed", "verification.approved":
+		return "approved", EmailApproved
+	case "verification.failed", "verification.rejected":
+		return "failed", EmailFailed
+	case "verification.review":
+		return "review", ""
+	default:
+		return "", ""
+	}
+}
+
+// ExtractUserID extracts the user_id from the webhook data
+func ExtractUserID(data models.WebhookData) string {
+	// First, try the user_id field directly.
+	if data.UserID != "" {
+		return data.UserID
+	}
+	// Then try the metadata.
+	if data.Metadata != nil {
+		if userID, ok := data.Metadata["user_id"]; ok {
+			if str, ok := userID.(string); ok {
+				return str
+			}
+		}
+		if userID, ok := data.Metadata["internal_user_id"]; ok {
+			if str, ok := userID.(string); ok {
+				return str
+			}
+		}
+	}
+
+	// Finally try the user_data.
+	if data.UserData != nil {
+		if userID, ok := data.UserData["user_id"]; ok {
+			if str, ok := userID.(string); ok {
+				return str
+			}
+		}
+		if userID, ok := data.UserData["id"]; ok {
+			if str, ok := userID.(string); ok {
+				return str
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,163 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/config"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+)
+
+// Queue drains the webhook_events table. Events are claimed with
+// SELECT ... FOR UPDATE SKIP LOCKED so several workers (and replicas) can run
+// at once, while events of the same Didit session are still handled in the
+// order they were received.
+type Queue struct {
+	repo         *repository.VerificationRepository
+	processor    *Processor
+	workers      int
+	maxAttempts  int
+	pollInterval time.Duration
+	lockTimeout  time.Duration
+	backoff      time.Duration
+	wake         chan struct{}
+}
+
+func NewQueue(cfg *config.Config, repo *repository.VerificationRepository, processor *Processor) *Queue {
+	workers := cfg.WebhookWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	return &Queue{
+		repo:         repo,
+		processor:    processor,
+		workers:      workers,
+		maxAttempts:  cfg.WebhookMaxAttempts,
+		pollInterval: cfg.WebhookPollInterval,
+		lockTimeout:  cfg.WebhookLockTimeout,
+		backoff:      cfg.WebhookRetryBackoff,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes an idle worker so a freshly stored event is picked up without
+// waiting for the next poll.
+func (q *Queue) Notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the worker pool and blocks until ctx is cancelled.
+func (q *Queue) Run(ctx context.Context) {
+	log.Printf("Starting webhook queue with %d workers", q.workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that is runnable before going back to sleep.
+		for ctx.Err() == nil {
+			claimed, err := q.processNext(ctx)
+			if err != nil {
+				log.Printf("Webhook queue error: %v", err)
+				break
+			}
+			if !claimed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// processNext claims and processes a single event. It reports whether an
+// event was claimed.
+func (q *Queue) processNext(ctx context.Context) (bool, error) {
+	event, err := q.repo.ClaimNextWebhookEvent(ctx, time.Now().Add(-q.lockTimeout))
+	if err != nil {
+		return false, err
+	}
+	if event == nil {
+		return false, nil
+	}
+
+	// The event is applied and marked done in one transaction, so a crash
+	// in between leaves it to be claimed again rather than half applied.
+	procErr := q.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		if err := q.process(ctx, repo, event); err != nil {
+			return err
+		}
+		return repo.CompleteWebhookEvent(ctx, event.ID)
+	})
+	if procErr != nil {
+		return true, q.fail(ctx, event, procErr)
+	}
+	return true, nil
+}
+
+func (q *Queue) process(ctx context.Context, repo *repository.VerificationRepository, stored *models.WebhookEventDB) error {
+	event, err := q.processor.ParseStored(stored)
+	if err != nil {
+		return fmt.Errorf("failed to decode payload: %w", err)
+	}
+
+	ctx, err = q.processor.tenants.Context(ctx, stored.TenantID)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.processor.bind(repo).Process(ctx, *event, false)
+	return err
+}
+
+// fail schedules a retry with jittered exponential backoff, or moves the event
+// to the dead-letter state once its attempts are exhausted.
+func (q *Queue) fail(ctx context.Context, event *models.WebhookEventDB, procErr error) error {
+	if event.Attempts >= q.maxAttempts {
+		log.Printf("Webhook event %s dead-lettered after %d attempts: %v", event.ID, event.Attempts, procErr)
+		return q.repo.DeadLetterWebhookEvent(ctx, event.ID, procErr.Error())
+	}
+
+	delay := q.retryDelay(event.Attempts)
+	log.Printf("Webhook event %s failed (attempt %d), retrying in %s: %v", event.ID, event.Attempts, delay, procErr)
+	return q.repo.RetryWebhookEvent(ctx, event.ID, time.Now().Add(delay), procErr.Error())
+}
+
+func (q *Queue) retryDelay(attempt int) time.Duration {
+	const maxDelay = time.Hour
+
+	delay := q.backoff
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	// Full jitter on the upper half keeps retries of a burst from lining up.
+	return delay/2 + rand.N(delay/2+1)
+}
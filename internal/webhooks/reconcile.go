@@ -0,0 +1,127 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/kyc"
+	"github.com/FelipePn10/crispaybackend/internal/models"
+)
+
+// reconcileBatch caps the sessions checked per reconciliation run.
+const reconcileBatch = 200
+
+// ReconcileReport summarizes a reconciliation run.
+type ReconcileReport struct {
+	Checked  int        `json:"checked"`
+	Applied  int        `json:"applied"`
+	Failed   int        `json:"failed"`
+	Outcomes []*Outcome `json:"outcomes"`
+}
+
+// Reconcile asks the provider of a session for its decision and processes it
+// like a webhook. It catches up on webhooks that were lost or never sent.
+// Only sessions opened through a provider API have an id to ask about.
+func (p *Processor) Reconcile(ctx context.Context, session *models.VerificationSession) (*Outcome, error) {
+	outcome := &Outcome{
+		EventType:      "reconciliation",
+		DiditSessionID: session.DiditSessionID,
+		UserID:         session.UserID,
+		SessionID:      session.SessionID,
+	}
+	if session.DiditSessionID == "" {
+		outcome.Skipped = "session has no provider session id"
+		return outcome, nil
+	}
+
+	provider, err := p.providers.Provider(session.Provider)
+	if err != nil {
+		return outcome, err
+	}
+	event, err := provider.FetchDecision(ctx, session.DiditSessionID)
+	if errors.Is(err, kyc.ErrProviderSessionNotFound) {
+		outcome.Skipped = "provider does not know the session"
+		return outcome, p.repo.MarkSessionReconciled(ctx, session.SessionID)
+	}
+	if err != nil {
+		return outcome, err
+	}
+	if err := p.repo.MarkSessionReconciled(ctx, session.SessionID); err != nil {
+		return outcome, err
+	}
+	if event.EventType == "" {
+		outcome.Skipped = "decision not final"
+		return outcome, nil
+	}
+
+	// The event is about this session even if the provider echoes no user.
+	if ExtractUserID(event.Data) == "" {
+		event.Data.UserID = session.UserID
+	}
+	return p.Process(ctx, *event, false)
+}
+
+// ReconcilePending reconciles pending sessions of every tenant opened more
+// than olderThan ago, skipping those checked within the last olderThan.
+func (p *Processor) ReconcilePending(ctx context.Context, olderThan time.Duration) (*ReconcileReport, error) {
+	cutoff := time.Now().Add(-olderThan)
+	sessions, err := p.repo.ListSessionsToReconcile(ctx, cutoff, cutoff, reconcileBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{Outcomes: []*Outcome{}}
+	for _, session := range sessions {
+		report.Checked++
+		outcome, err := p.reconcileForTenant(ctx, session)
+		if err != nil {
+			log.Printf("Failed to reconcile session %s with %s: %v", session.SessionID, session.Provider, err)
+			outcome.Skipped = fmt.Sprintf("error: %v", err)
+			report.Failed++
+		} else if outcome.Applied {
+			report.Applied++
+		}
+		report.Outcomes = append(report.Outcomes, outcome)
+	}
+	return report, nil
+}
+
+// reconcileForTenant reconciles a session acting for its tenant.
+func (p *Processor) reconcileForTenant(ctx context.Context, session *models.VerificationSession) (*Outcome, error) {
+	ctx, err := p.tenants.Context(ctx, session.TenantID)
+	if err != nil {
+		return &Outcome{EventType: "reconciliation", UserID: session.UserID, SessionID: session.SessionID}, err
+	}
+	return p.Reconcile(ctx, session)
+}
+
+// RunReconciler reconciles pending sessions every interval until ctx is
+// done. A zero interval disables it.
+func (p *Processor) RunReconciler(ctx context.Context, interval time.Duration, olderThan time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	log.Printf("Starting session reconciliation every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := p.ReconcilePending(ctx, olderThan)
+			if err != nil {
+				log.Printf("Session reconciliation failed: %v", err)
+				continue
+			}
+			if report.Checked > 0 {
+				log.Printf("Session reconciliation: checked=%d applied=%d failed=%d", report.Checked, report.Applied, report.Failed)
+			}
+		}
+	}
+}
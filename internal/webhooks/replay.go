@@ -0,0 +1,141 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+)
+
+// ReplayRequest selects which stored webhook events to re-run. Exactly one of
+// SessionID, Since or Unprocessed must be set.
+type ReplayRequest struct {
+	SessionID   string    `json:"session_id,omitempty"`
+	Since       time.Time `json:"since,omitempty"`
+	Unprocessed bool      `json:"unprocessed,omitempty"`
+	DryRun      bool      `json:"dry_run,omitempty"`
+}
+
+// ReplayReport summarizes a replay run.
+type ReplayReport struct {
+	DryRun   bool       `json:"dry_run"`
+	Matched  int        `json:"matched"`
+	Applied  int        `json:"applied"`
+	Failed   int        `json:"failed"`
+	Outcomes []*Outcome `json:"outcomes"`
+}
+
+var ErrInvalidReplayRequest = errors.New("exactly one of session, since or unprocessed must be set")
+
+func (r ReplayRequest) validate() error {
+	selectors := 0
+	if r.SessionID != "" {
+		selectors++
+	}
+	if !r.Since.IsZero() {
+		selectors++
+	}
+	if r.Unprocessed {
+		selectors++
+	}
+	if selectors != 1 {
+		return ErrInvalidReplayRequest
+	}
+	return nil
+}
+
+// Replay re-runs stored webhook events through the current processing
+// pipeline, oldest first. Events that apply cleanly are marked processed.
+func (p *Processor) Replay(ctx context.Context, req ReplayRequest) (*ReplayReport, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	events, err := p.repo.ListWebhookEventsForReplay(ctx, repository.WebhookReplayFilter{
+		SessionID:       req.SessionID,
+		Since:           req.Since,
+		OnlyUnprocessed: req.Unprocessed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReplayReport{
+		DryRun:   req.DryRun,
+		Matched:  len(events),
+		Outcomes: make([]*Outcome, 0, len(events)),
+	}
+
+	for _, stored := range events {
+		outcome, err := p.replayOne(ctx, stored, req.DryRun)
+		if err != nil {
+			log.Printf("Replay of webhook event %s failed: %v", stored.ID, err)
+			report.Failed++
+		}
+		if outcome.Applied {
+			report.Applied++
+		}
+		report.Outcomes = append(report.Outcomes, outcome)
+	}
+
+	return report, nil
+}
+
+func (p *Processor) replayOne(ctx context.Context, stored *models.WebhookEventDB, dryRun bool) (*Outcome, error) {
+	event, err := p.ParseStored(stored)
+	if err != nil {
+		outcome := &Outcome{
+			EventID:        stored.ID.String(),
+			EventType:      stored.EventType,
+			DiditSessionID: stored.SessionID,
+			Skipped:        "invalid JSON payload",
+		}
+		return outcome, fmt.Errorf("failed to decode payload: %w", err)
+	}
+
+	// A replay applying its decision again finds the session already holding
+	// it and skips. One reaching a different decision, as after a handler
+	// fix, is applied, but the user is not emailed about an event that was
+	// already processed.
+	p = p.replaying(stored)
+
+	if dryRun {
+		outcome, err := p.Process(ctx, *event, true)
+		outcome.EventID = stored.ID.String()
+		return outcome, err
+	}
+
+	outcome := &Outcome{
+		EventID:        stored.ID.String(),
+		EventType:      stored.EventType,
+		DiditSessionID: stored.SessionID,
+	}
+	err = p.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		applied, err := p.bind(repo).Process(ctx, *event, false)
+		applied.EventID = outcome.EventID
+		outcome = applied
+		if err != nil {
+			return err
+		}
+		return repo.MarkWebhookEventProcessed(ctx, stored.ID)
+	})
+	if err != nil {
+		outcome.Applied = false
+	}
+	return outcome, err
+}
+
+// replaying returns a processor that sends no user emails when stored was
+// already processed.
+func (p *Processor) replaying(stored *models.WebhookEventDB) *Processor {
+	if !stored.Processed {
+		return p
+	}
+	quiet := *p
+	quiet.silent = true
+	return &quiet
+}
@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/repository"
+)
+
+var (
+	ErrSessionNotFound = errors.New("verification session not found")
+	ErrInvalidDecision = errors.New("decision must be approved or failed")
+)
+
+// ReviewRequest is a compliance analyst's manual decision on a session.
+type ReviewRequest struct {
+	Decision string `json:"decision" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+// Review applies a manual decision to a session. It goes through the same
+// audit, outbound event and email path as webhook-driven transitions, in one
+// transaction holding the lock of the session row.
+func (p *Processor) Review(ctx context.Context, sessionID string, req ReviewRequest, actor string) (*models.VerificationSession, error) {
+	var email string
+	switch req.Decision {
+	case "approved":
+		email = EmailApproved
+	case "failed", "rejected":
+		req.Decision = "failed"
+		email = EmailFailed
+	default:
+		return nil, ErrInvalidDecision
+	}
+
+	var session, updated *models.VerificationSession
+	err := p.repo.WithTx(ctx, func(repo *repository.VerificationRepository) error {
+		tx := p.bind(repo)
+
+		var err error
+		session, err = tx.repo.LockSession(ctx, sessionID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		updated, err = tx.repo.UpdateStatus(ctx, sessionID, req.Decision)
+		if err != nil {
+			return fmt.Errorf("error updating session status: %w", err)
+		}
+
+		// A review that confirms the current status still belongs in the log.
+		if session.Status == updated.Status {
+			_, err := tx.audit.Record(ctx, models.AuditEvent{
+				Action:        models.AuditSessionReviewed,
+				SubjectUserID: updated.UserID,
+				Actor:         actor,
+				EntityType:    "session",
+				EntityID:      updated.SessionID,
+				Details:       map[string]any{"from_status": session.Status, "to_status": updated.Status, "reason": req.Reason},
+			})
+			return err
+		}
+
+		return tx.afterTransition(ctx, session, updated, email, actor, models.AuditSessionReviewed, map[string]any{
+			"reason": req.Reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status != updated.Status {
+		log.Printf("Session %s manually set to %s by %s", sessionID, updated.Status, actor)
+	}
+	return updated, nil
+}
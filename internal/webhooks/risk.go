@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"time"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/risk"
+)
+
+// velocityWindow is the period of the sessions_last_24h signal.
+const velocityWindow = 24 * time.Hour
+
+// riskSignals gathers the risk signals of a decision: Didit's liveness, face
+// match, AML and IP checks, and from our side the email domain, the user's
+// earlier sessions, the screening done on this one and the CPF cross-check. Signals that are not
+// known are left out so their rules do not fire.
+func (p *Processor) riskSignals(session *models.VerificationSession, sessions []*models.VerificationSession, data models.WebhookData, sanctionsMatches int, duplicates int, cpfMatch *bool) map[string]float64 {
+	signals := map[string]float64{
+		risk.SignalSanctionsMatches:    float64(sanctionsMatches),
+		risk.SignalDuplicateIdentities: float64(duplicates),
+	}
+
+	if score, ok := decisionNumber(data, "liveness", "score"); ok {
+		signals[risk.SignalLivenessScore] = score
+	}
+	if score, ok := decisionNumber(data, "face_match", "score"); ok {
+		signals[risk.SignalFaceMatchScore] = score
+	}
+	if hits, ok := decisionNumber(data, "aml", "total_hits"); ok {
+		signals[risk.SignalAMLHits] = hits
+	} else if aml, ok := data.Decision["aml"].(map[string]interface{}); ok {
+		if hits, ok := aml["hits"].([]interface{}); ok {
+			signals[risk.SignalAMLHits] = float64(len(hits))
+		}
+	}
+
+	ipCountry := risk.CountryCode(DecisionString(data, "ip_analysis", "ip_country_code"))
+	documentCountry := risk.CountryCode(DecisionString(data, "id_verification", "issuing_state"))
+	if ipCountry != "" && documentCountry != "" {
+		signals[risk.SignalIPCountryMismatch] = boolSignal(ipCountry != documentCountry)
+	}
+
+	if cpfMatch != nil {
+		signals[risk.SignalCPFMismatch] = boolSignal(!*cpfMatch)
+	}
+
+	if session.UserEmail != "" {
+		signals[risk.SignalDisposableEmail] = boolSignal(p.scorer.Disposable(session.UserEmail))
+	}
+
+	failed, recent := 0, 0
+	since := time.Now().Add(-velocityWindow)
+	for _, s := range sessions {
+		if s.CreatedAt.After(since) {
+			recent++
+		}
+		if s.SessionID != session.SessionID && (s.Status == "failed" || s.Status == "rejected") {
+			failed++
+		}
+	}
+	signals[risk.SignalFailedAttempts] = float64(failed)
+	signals[risk.SignalSessionsLast24h] = float64(recent)
+
+	return signals
+}
+
+// decisionNumber reads a numeric field of a section of the Didit decision.
+func decisionNumber(data models.WebhookData, section string, key string) (float64, bool) {
+	fields, ok := data.Decision[section].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	value, ok := fields[key].(float64)
+	return value, ok
+}
+
+func boolSignal(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
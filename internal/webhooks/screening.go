@@ -0,0 +1,41 @@
+package webhooks
+
+import (
+	"log"
+
+	"github.com/FelipePn10/crispaybackend/internal/models"
+	"github.com/FelipePn10/crispaybackend/internal/sanctions"
+)
+
+// screen checks the person behind an approved session against the local
+// sanctions lists. The name comes from the session, falling back to the one
+// Didit read from the document, and the date of birth from the decision.
+func (p *Processor) screen(session *models.VerificationSession, data models.WebhookData) []sanctions.Match {
+	if p.screener == nil || !p.screener.Enabled() {
+		return nil
+	}
+
+	firstName, lastName := session.UserFirstName, session.UserLastName
+	if firstName == "" && lastName == "" {
+		firstName = DecisionString(data, "id_verification", "first_name")
+		lastName = DecisionString(data, "id_verification", "last_name")
+	}
+	dob := DecisionString(data, "id_verification", "date_of_birth")
+
+	matches := p.screener.Screen(firstName, lastName, dob)
+	if len(matches) > 0 {
+		log.Printf("Session %s matched %d sanctions list entries (best %.2f, %s %s)",
+			session.SessionID, len(matches), matches[0].Score, matches[0].List, matches[0].EntryID)
+	}
+	return matches
+}
+
+// DecisionString reads a string field of a section of the Didit decision.
+func DecisionString(data models.WebhookData, section string, key string) string {
+	fields, ok := data.Decision[section].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	value, _ := fields[key].(string)
+	return value
+}
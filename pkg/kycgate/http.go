@@ -0,0 +1,58 @@
+package kycgate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPSource reads decisions from the KYC service's
+// GET /api/verification/user/:userId/status endpoint.
+type HTTPSource struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPSource returns a Source backed by the KYC API at baseURL. A nil
+// client uses one with a 5 second timeout.
+func NewHTTPSource(baseURL string, client *http.Client) *HTTPSource {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &HTTPSource{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+func (s *HTTPSource) LatestDecision(ctx context.Context, userID string) (*Decision, error) {
+	endpoint := s.baseURL + "/api/verification/user/" + url.PathEscape(userID) + "/status"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch kyc status: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("failed to fetch kyc status: unexpected status %d", resp.StatusCode)
+	}
+
+	var decision Decision
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return nil, fmt.Errorf("failed to decode kyc status: %v", err)
+	}
+	return &decision, nil
+}
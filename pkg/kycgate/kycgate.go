@@ -0,0 +1,233 @@
+// Package kycgate restricts gin routes to users whose KYC verification was
+// approved. It is meant to be imported by every Crispay service that moves
+// money:
+//
+//	gate := kycgate.New(kycgate.NewHTTPSource(kycBaseURL, nil), kycgate.Options{})
+//	r.POST("/payments", gate.RequireKYC(kycgate.LevelBasic), createPayment)
+//
+// Decisions are cached in memory. Call Invalidate when a verification status
+// changes, e.g. from the consumer of the verification.* outbound webhooks.
+// Services serving several tenants set Options.Scope, since the same user id
+// may belong to a different person in each.
+package kycgate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Level is a KYC tier a route can require.
+type Level string
+
+const (
+	// LevelBasic checks an identity document and liveness.
+	LevelBasic Level = "basic"
+	// LevelFull adds proof of address and AML screening.
+	LevelFull Level = "full"
+	// LevelEnhanced is enhanced due diligence, required for businesses.
+	LevelEnhanced Level = "enhanced"
+)
+
+// UserIDKey is the gin context key the authentication middleware of the
+// service stores the authenticated user id under, read by default.
+const UserIDKey = "user_id"
+
+// DefaultLevels lists the tiers of the Crispay KYC service, lowest first.
+var DefaultLevels = []Level{LevelBasic, LevelFull, LevelEnhanced}
+
+// Decision is a user's effective KYC status: the approval granting the
+// highest level, or the latest terminal verification when none was approved.
+type Decision struct {
+	UserID    string    `json:"user_id"`
+	SessionID string    `json:"session_id"`
+	Status    string    `json:"status"`
+	Level     Level     `json:"level"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// Approved reports whether the decision grants at least the given level.
+func (d *Decision) Approved(level Level, levels []Level) bool {
+	if d == nil || d.Status != "approved" {
+		return false
+	}
+	have := slices.Index(levels, d.Level)
+	want := slices.Index(levels, level)
+	return have >= 0 && want >= 0 && have >= want
+}
+
+// Source looks up a user's effective decision. It returns nil when the user
+// never finished a verification.
+type Source interface {
+	LatestDecision(ctx context.Context, userID string) (*Decision, error)
+}
+
+// SourceFunc adapts a function to Source.
+type SourceFunc func(ctx context.Context, userID string) (*Decision, error)
+
+func (f SourceFunc) LatestDecision(ctx context.Context, userID string) (*Decision, error) {
+	return f(ctx, userID)
+}
+
+// Options tunes a Gate. Zero values select the defaults.
+type Options struct {
+	// CacheTTL bounds how long a decision is served from memory. Default 5m.
+	CacheTTL time.Duration
+	// Levels lists the KYC tiers from lowest to highest. Default
+	// DefaultLevels.
+	Levels []Level
+	// UserID extracts the authenticated user from the request. Default: the
+	// UserIDKey context key. It must not trust anything the client sends,
+	// like a user id header, or any caller could borrow another user's
+	// verification. Requests without a user id are rejected.
+	UserID func(c *gin.Context) string
+	// VerificationURL is where a rejected user should go to verify at the
+	// required level.
+	VerificationURL func(userID string, level Level) string
+	// Scope names the tenant a request acts for. Decisions are cached per
+	// scope and user. Default: a single scope, "".
+	Scope func(ctx context.Context) string
+}
+
+type cacheEntry struct {
+	decision *Decision
+	expires  time.Time
+}
+
+// Gate checks users against a Source with an in-memory cache.
+type Gate struct {
+	source Source
+	opts   Options
+
+	mu    sync.Mutex
+	cache map[cacheKey]cacheEntry
+}
+
+type cacheKey struct {
+	scope  string
+	userID string
+}
+
+func New(source Source, opts Options) *Gate {
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = 5 * time.Minute
+	}
+	if len(opts.Levels) == 0 {
+		opts.Levels = DefaultLevels
+	}
+	if opts.UserID == nil {
+		opts.UserID = defaultUserID
+	}
+	if opts.Scope == nil {
+		opts.Scope = func(context.Context) string { return "" }
+	}
+	return &Gate{
+		source: source,
+		opts:   opts,
+		cache:  make(map[cacheKey]cacheEntry),
+	}
+}
+
+func defaultUserID(c *gin.Context) string {
+	return c.GetString(UserIDKey)
+}
+
+// Decision returns the user's effective decision, from the cache when fresh.
+func (g *Gate) Decision(ctx context.Context, userID string) (*Decision, error) {
+	key := cacheKey{scope: g.opts.Scope(ctx), userID: userID}
+	g.mu.Lock()
+	entry, ok := g.cache[key]
+	g.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.decision, nil
+	}
+
+	decision, err := g.source.LatestDecision(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	g.cache[key] = cacheEntry{decision: decision, expires: time.Now().Add(g.opts.CacheTTL)}
+	g.mu.Unlock()
+
+	return decision, nil
+}
+
+// Invalidate drops the cached decision of a user in a scope, as returned by
+// Options.Scope.
+func (g *Gate) Invalidate(scope string, userID string) {
+	g.mu.Lock()
+	delete(g.cache, cacheKey{scope: scope, userID: userID})
+	g.mu.Unlock()
+}
+
+// ErrNotApproved is returned by Check when the user lacks the required level.
+var ErrNotApproved = errors.New("kyc verification required")
+
+// Check returns the user's decision, or ErrNotApproved when it does not grant
+// the level.
+func (g *Gate) Check(ctx context.Context, userID string, level Level) (*Decision, error) {
+	decision, err := g.Decision(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !decision.Approved(level, g.opts.Levels) {
+		return decision, ErrNotApproved
+	}
+	return decision, nil
+}
+
+// Denial is the body of the 403 returned by RequireKYC.
+type Denial struct {
+	Error           string `json:"error"`
+	Message         string `json:"message"`
+	RequiredLevel   Level  `json:"required_level"`
+	Status          string `json:"status"`
+	Level           Level  `json:"level,omitempty"`
+	VerificationURL string `json:"verification_url,omitempty"`
+}
+
+// RequireKYC aborts requests from users without an approved verification of
+// at least the given level. The decision is stored under the "kyc" context
+// key for the handlers.
+func (g *Gate) RequireKYC(level Level) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := g.opts.UserID(c)
+		if userID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		decision, err := g.Check(c.Request.Context(), userID, level)
+		if errors.Is(err, ErrNotApproved) {
+			denial := Denial{
+				Error:         "kyc_required",
+				Message:       "KYC verification required for this operation",
+				RequiredLevel: level,
+				Status:        "none",
+			}
+			if decision != nil {
+				denial.Status = decision.Status
+				denial.Level = decision.Level
+			}
+			if g.opts.VerificationURL != nil {
+				denial.VerificationURL = g.opts.VerificationURL(userID, level)
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, denial)
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "kyc status unavailable"})
+			return
+		}
+
+		c.Set("kyc", decision)
+		c.Next()
+	}
+}